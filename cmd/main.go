@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"demo01/config"
 	"demo01/internal/database"
 	"demo01/internal/handler"
@@ -26,6 +27,9 @@ func main() {
 
 	// 初始化Redis
 	util.InitRedis(cfg.RedisAddr, cfg.RedisPwd)
+	if cfg.CacheMutexEnabled {
+		util.EnableCacheMutex(util.RedisClient)
+	}
 
 	// 初始化数据库
 	if err := database.InitDatabase(db); err != nil {
@@ -35,14 +39,86 @@ func main() {
 	// 依赖注入
 	orderRepo := repository.NewOrderRepo(db, util.RedisClient)
 	inventoryRepo := repository.NewInventoryRepo(db)
-	productRepo := repository.NewProductRepo(db)
+	productRepo := repository.NewProductRepo(db, util.RedisClient)
+	skuRepo := repository.NewSKURepo(db)
+	categoryRepo := repository.NewCategoryRepo(db)
+	priceRepo := repository.NewPriceRepo(db)
+	recommendRepo := repository.NewRecommendRepo(util.RedisClient)
+	userRepo := repository.NewUserRepo(db)
+	tokenRepo := repository.NewTokenRepo(util.RedisClient)
+	cartRepo := repository.NewCartRepo(util.RedisClient)
+	addressRepo := repository.NewAddressRepo(db)
+	couponRepo := repository.NewCouponRepo(db, util.RedisClient)
+	paymentRepo := repository.NewPaymentRepo(db)
+	refundRepo := repository.NewRefundRepo(db)
+
+	// 从数据库重建布隆过滤器 失败时过滤器保持放行状态 不影响正常查询
+	if err := orderRepo.RebuildBloom(context.Background()); err != nil {
+		util.GlobalLogger.Error(context.Background(), "订单布隆过滤器重建失败", err)
+	}
+	if err := productRepo.RebuildBloom(context.Background()); err != nil {
+		util.GlobalLogger.Error(context.Background(), "商品布隆过滤器重建失败", err)
+	}
+
+	// 跨实例缓存失效广播
+	invalidator := util.NewCacheInvalidator(util.RedisClient)
 
-	orderService := service.NewOrderService(orderRepo, inventoryRepo)
-	productService := service.NewProductService(productRepo)
+	userService := service.NewUserService(userRepo)
+	authService := service.NewAuthService(userService, tokenRepo, jwtSecret(cfg), cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	addressService := service.NewAddressService(addressRepo)
+	couponService := service.NewCouponService(couponRepo, categoryRepo)
+	orderService := service.NewOrderService(orderRepo, inventoryRepo, productRepo, skuRepo, userService, addressService, couponService, invalidator)
+	productService := service.NewProductService(productRepo, skuRepo, categoryRepo, priceRepo, invalidator)
+	categoryService := service.NewCategoryService(categoryRepo, productRepo, productService)
+	recommendService := service.NewRecommendService(orderRepo, productRepo, recommendRepo, productService, cfg.RecommendWindow)
+	cartService := service.NewCartService(cartRepo, productRepo, inventoryRepo, productService, orderService)
+	paymentProvider := newPaymentProvider(cfg)
+	paymentService := service.NewPaymentService(paymentRepo, orderRepo, orderService, paymentProvider, cfg.PaymentIntentTTL)
+	refundService := service.NewRefundService(refundRepo, paymentRepo, orderRepo, skuRepo, inventoryRepo, orderService, paymentProvider)
+
+	// 创建初始管理员账号
+	if cfg.AdminPassword != "" {
+		if err := userService.EnsureAdmin(context.Background(), cfg.AdminUsername, cfg.AdminPassword); err != nil {
+			util.GlobalLogger.Error(context.Background(), "初始管理员创建失败", err)
+		}
+	}
+
+	// 订阅缓存失效频道（断线自动重连）
+	go invalidator.Start(context.Background())
+
+	// 构建商品全文索引 并定时重建以同步其他实例的修改
+	if err := productService.RebuildSearchIndex(context.Background()); err != nil {
+		util.GlobalLogger.Error(context.Background(), "商品全文索引构建失败", err)
+	}
+	go productService.StartSearchIndexRefresher(context.Background(), cfg.SearchIndexRefresh)
+
+	// 定时调价任务 多实例同时运行时每个调价只会执行一次
+	go productService.StartPriceScheduler(context.Background(), cfg.PriceScheduleInterval)
+
+	// 根据订单历史定时重建共同购买推荐 多实例部署时每个周期只有一个实例计算
+	go recommendService.StartRefresher(context.Background(), cfg.RecommendRefresh)
+
+	// 缓存预热 在启动HTTP服务之前完成 受时间预算限制
+	if cfg.WarmupEnabled {
+		service.WarmUp(context.Background(), orderService, productService, service.WarmupOptions{
+			Products: cfg.WarmupProducts,
+			Orders:   cfg.WarmupOrders,
+			Timeout:  cfg.WarmupTimeout,
+		})
+	}
 
 	orderHandler := handler.NewOrderHandler(orderService)
 	productHandler := handler.NewProductHandler(productService)
+	categoryHandler := handler.NewCategoryHandler(categoryService, productService)
+	recommendHandler := handler.NewRecommendHandler(recommendService)
+	userHandler := handler.NewUserHandler(userService, authService)
+	cartHandler := handler.NewCartHandler(cartService)
+	addressHandler := handler.NewAddressHandler(addressService)
+	couponHandler := handler.NewCouponHandler(couponService)
+	paymentHandler := handler.NewPaymentHandler(paymentService)
+	refundHandler := handler.NewRefundHandler(refundService, orderService)
 	healthHandler := handler.NewHealthHandler()
+	adminHandler := handler.NewAdminHandler(orderService, productService)
 
 	// 初始化Gin
 	r := gin.Default()
@@ -50,27 +126,116 @@ func main() {
 	// 健康检查路由
 	r.GET("/health", healthHandler.HealthCheck)
 
+	// 认证中间件 auth校验登录令牌 admin要求管理员角色 self要求访问自己的数据或为管理员 optionalAuth用于登录后可以看到更多内容的公开接口
+	auth := handler.AuthRequired(authService)
+	admin := handler.AdminRequired()
+	self := handler.SelfOrAdmin("id")
+	optionalAuth := handler.OptionalAuth(authService)
+
 	// 路由注册
+	// 认证相关路由 auth
+	{
+		r.POST("/auth/refresh", userHandler.RefreshTokenHandler)
+		r.POST("/auth/logout", userHandler.LogoutHandler)
+	}
+
 	// 订单相关路由 orders
 	{
-		r.POST("/orders", orderHandler.CreateOrderHandler)
-		r.GET("/orders", orderHandler.GetAllOrdersHandler)
-		r.GET("/orders/:id", orderHandler.GetOrderHandler)
+		r.POST("/orders", auth, orderHandler.CreateOrderHandler)
+		r.GET("/orders", auth, admin, orderHandler.GetAllOrdersHandler)
+		r.GET("/orders/:id", auth, orderHandler.GetOrderHandler)
+		r.GET("/orders/:id/tracking", auth, orderHandler.GetTrackingHandler)
+		r.POST("/orders/:id/ship", auth, admin, orderHandler.ShipOrderHandler)
+		r.POST("/orders/:id/payments", auth, paymentHandler.CreatePaymentHandler)
+		r.GET("/orders/:id/refunds", auth, refundHandler.ListRefundsHandler)
+		r.POST("/orders/:id/refunds", auth, admin, refundHandler.CreateRefundHandler)
+	}
+
+	// 支付路由 payments 回调由支付渠道调用 通过签名认证 不需要登录令牌
+	{
+		r.POST("/payments/callback", paymentHandler.PaymentCallbackHandler)
+		// 模拟支付只在开发和测试环境开放 否则任何登录用户都能把自己的订单标记为已支付
+		if cfg.PaymentProvider == service.MockPaymentProviderName && cfg.IsDevelopment() {
+			r.POST("/payments/mock/:ref/pay", auth, paymentHandler.MockPayHandler)
+		}
+	}
+
+	// 购物车路由 cart 只能操作自己的购物车
+	cartGroup := r.Group("/cart", auth)
+	{
+		cartGroup.GET("", cartHandler.GetCartHandler)
+		cartGroup.DELETE("", cartHandler.ClearCartHandler)
+		cartGroup.POST("/items", cartHandler.AddItemHandler)
+		cartGroup.PUT("/items/:item_id", cartHandler.UpdateItemHandler)
+		cartGroup.DELETE("/items/:item_id", cartHandler.RemoveItemHandler)
+		cartGroup.POST("/checkout", cartHandler.CheckoutHandler)
 	}
 
-	// 商品相关路由 products
+	// 商品相关路由 products 查询接口公开 修改接口只允许管理员
 	{
-		r.POST("/products", productHandler.CreateProductHandler)
-		r.GET("/products", productHandler.GetAllProductsHandler)
+		r.POST("/products", auth, admin, productHandler.CreateProductHandler)
+		r.GET("/products", optionalAuth, productHandler.GetAllProductsHandler)
+		r.GET("/products/search", productHandler.SearchProductsHandler)
+		r.POST("/products/import", auth, admin, productHandler.ImportProductsHandler)
+		r.GET("/products/export", auth, admin, productHandler.ExportProductsHandler)
 		r.GET("/products/:id", productHandler.GetProductHandler)
+		r.PUT("/products/:id", auth, admin, productHandler.UpdateProductHandler)
+		r.PATCH("/products/:id", auth, admin, productHandler.UpdateProductHandler)
+		r.DELETE("/products/:id", auth, admin, productHandler.DeleteProductHandler)
+		r.POST("/products/:id/status", auth, admin, productHandler.ChangeStatusHandler)
 		r.GET("/products/:id/stock", productHandler.GetStockHandler)
+		r.GET("/products/:id/skus", productHandler.ListSKUsHandler)
+		r.POST("/products/:id/skus", auth, admin, productHandler.CreateSKUHandler)
+		r.PUT("/products/:id/skus/:sku_id", auth, admin, productHandler.UpdateSKUHandler)
+		r.DELETE("/products/:id/skus/:sku_id", auth, admin, productHandler.DeleteSKUHandler)
+		r.GET("/products/:id/price-history", auth, admin, productHandler.GetPriceHistoryHandler)
+		r.GET("/products/:id/price-schedules", auth, admin, productHandler.ListPriceSchedulesHandler)
+		r.POST("/products/:id/price-schedules", auth, admin, productHandler.SchedulePriceHandler)
+		r.DELETE("/products/:id/price-schedules/:schedule_id", auth, admin, productHandler.CancelPriceScheduleHandler)
 		r.GET("/products/recommend", productHandler.RecommendProductsHandler)
-		r.GET("/products/recommend_serial", productHandler.RecommendProductsSerialHandler)
+		r.GET("/products/recommend_serial", auth, admin, productHandler.RecommendProductsSerialHandler)
+		r.GET("/products/:id/recommend", recommendHandler.ProductRecommendHandler)
 	}
 
-	// TODO:用户相关路由 users
+	// 商品分类路由 categories
 	{
+		r.POST("/categories", auth, admin, categoryHandler.CreateCategoryHandler)
+		r.GET("/categories", categoryHandler.GetCategoryTreeHandler)
+		r.GET("/categories/:id", categoryHandler.GetCategoryHandler)
+		r.PUT("/categories/:id", auth, admin, categoryHandler.UpdateCategoryHandler)
+		r.DELETE("/categories/:id", auth, admin, categoryHandler.DeleteCategoryHandler)
+		r.GET("/categories/:id/products", categoryHandler.ListCategoryProductsHandler)
+	}
 
+	// 运维管理路由 admin 全部需要管理员权限
+	adminGroup := r.Group("/admin", auth, admin)
+	{
+		adminGroup.GET("/locks", adminHandler.ListLocksHandler)
+		adminGroup.GET("/cache/stats", adminHandler.CacheStatsHandler)
+		adminGroup.DELETE("/cache/:entity", adminHandler.PurgeCacheHandler)
+		adminGroup.DELETE("/cache/:entity/:id", adminHandler.PurgeCacheHandler)
+		adminGroup.GET("/users", userHandler.ListUsersHandler)
+		adminGroup.POST("/users/:id/status", userHandler.ChangeUserStatusHandler)
+		adminGroup.POST("/coupons", couponHandler.CreateCouponHandler)
+		adminGroup.GET("/coupons", couponHandler.ListCouponsHandler)
+		adminGroup.GET("/coupons/:id", couponHandler.GetCouponHandler)
+		adminGroup.POST("/coupons/:id/status", couponHandler.ChangeCouponStatusHandler)
+	}
+
+	// 用户相关路由 users 只能访问自己的数据 管理员不受限制
+	{
+		r.POST("/users/register", userHandler.RegisterHandler)
+		r.POST("/users/login", userHandler.LoginHandler)
+		r.GET("/users/:id", auth, self, userHandler.GetUserHandler)
+		r.PUT("/users/:id", auth, self, userHandler.UpdateUserHandler)
+		r.PUT("/users/:id/password", auth, self, userHandler.ChangePasswordHandler)
+		r.GET("/users/:id/orders", auth, self, orderHandler.ListUserOrdersHandler)
+		r.GET("/users/:id/recommend", auth, self, recommendHandler.UserRecommendHandler)
+		r.GET("/users/:id/addresses", auth, self, addressHandler.ListAddressesHandler)
+		r.POST("/users/:id/addresses", auth, self, addressHandler.CreateAddressHandler)
+		r.PUT("/users/:id/addresses/:address_id", auth, self, addressHandler.UpdateAddressHandler)
+		r.DELETE("/users/:id/addresses/:address_id", auth, self, addressHandler.DeleteAddressHandler)
+		r.POST("/users/:id/addresses/:address_id/default", auth, self, addressHandler.SetDefaultAddressHandler)
 	}
 
 	// 启动服务（绑定到所有网络接口）
@@ -78,3 +243,31 @@ func main() {
 		panic("启动服务失败: " + err.Error())
 	}
 }
+
+// jwtSecret 读取JWT签名密钥 未配置时拒绝启动
+// 使用随机密钥会导致重启后令牌全部失效 且多个实例之间互不认可
+func jwtSecret(cfg *config.Config) []byte {
+	if cfg.JWTSecret == "" {
+		panic("未配置JWT_SECRET 拒绝启动")
+	}
+	return []byte(cfg.JWTSecret)
+}
+
+// newPaymentProvider 根据配置创建支付渠道 不支持的渠道拒绝启动
+func newPaymentProvider(cfg *config.Config) service.PaymentProvider {
+	switch cfg.PaymentProvider {
+	case service.MockPaymentProviderName:
+		return service.NewMockPaymentProvider(paymentCallbackSecret(cfg))
+	default:
+		panic("不支持的支付渠道: " + cfg.PaymentProvider)
+	}
+}
+
+// paymentCallbackSecret 读取支付回调签名密钥 未配置时拒绝启动
+// 使用随机密钥时重启前发起的支付回调全部验签失败
+func paymentCallbackSecret(cfg *config.Config) []byte {
+	if cfg.PaymentCallbackSecret == "" {
+		panic("未配置PAYMENT_CALLBACK_SECRET 拒绝启动")
+	}
+	return []byte(cfg.PaymentCallbackSecret)
+}
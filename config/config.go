@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config 应用配置
@@ -10,6 +12,32 @@ type Config struct {
 	RedisAddr string // Redis地址
 	RedisPwd  string // Redis密码
 	Port      string // 服务端口
+	AppEnv    string // 运行环境 development/test/production
+
+	CacheMutexEnabled bool // 是否启用跨实例缓存加载互斥锁
+
+	WarmupEnabled  bool          // 启动时是否预热缓存
+	WarmupProducts int           // 预热的热门商品数量
+	WarmupOrders   int           // 预热的最近订单数量
+	WarmupTimeout  time.Duration // 预热时间预算 超时后直接启动服务
+
+	SearchIndexRefresh time.Duration // 商品全文索引定时重建间隔
+
+	PriceScheduleInterval time.Duration // 定时调价任务的检查间隔
+
+	RecommendRefresh time.Duration // 共同购买推荐的重建间隔
+	RecommendWindow  time.Duration // 推荐统计最近多长时间的订单
+
+	AdminUsername string // 初始管理员用户名
+	AdminPassword string // 初始管理员密码 为空时不创建
+
+	JWTSecret       string        // JWT签名密钥 必须配置 多实例部署时配置为相同的值
+	AccessTokenTTL  time.Duration // 访问令牌有效期
+	RefreshTokenTTL time.Duration // 刷新令牌有效期
+
+	PaymentProvider       string        // 支付渠道 目前只有mock
+	PaymentCallbackSecret string        // 支付回调签名密钥 与支付渠道约定 必须配置
+	PaymentIntentTTL      time.Duration // 支付单有效期
 }
 
 // Load 加载配置
@@ -19,9 +47,40 @@ func Load() *Config {
 		RedisAddr: getEnv("REDIS_ADDR", "14.103.163.34:6379"),
 		RedisPwd:  getEnv("REDIS_PWD", "Azspigot1996"),
 		Port:      getEnv("PORT", "8080"),
+		AppEnv:    getEnv("APP_ENV", "production"),
+
+		CacheMutexEnabled: getEnv("CACHE_MUTEX_ENABLED", "false") == "true",
+
+		WarmupEnabled:  getEnv("WARMUP_ENABLED", "true") == "true",
+		WarmupProducts: getEnvInt("WARMUP_PRODUCTS", 100),
+		WarmupOrders:   getEnvInt("WARMUP_ORDERS", 500),
+		WarmupTimeout:  getEnvDuration("WARMUP_TIMEOUT", 10*time.Second),
+
+		SearchIndexRefresh: getEnvDuration("SEARCH_INDEX_REFRESH", 10*time.Minute),
+
+		PriceScheduleInterval: getEnvDuration("PRICE_SCHEDULE_INTERVAL", 30*time.Second),
+
+		RecommendRefresh: getEnvDuration("RECOMMEND_REFRESH", time.Hour),
+		RecommendWindow:  getEnvDuration("RECOMMEND_WINDOW", 90*24*time.Hour),
+
+		AdminUsername: getEnv("ADMIN_USERNAME", "admin"),
+		AdminPassword: getEnv("ADMIN_PASSWORD", ""),
+
+		JWTSecret:       getEnv("JWT_SECRET", ""),
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+
+		PaymentProvider:       getEnv("PAYMENT_PROVIDER", "mock"),
+		PaymentCallbackSecret: getEnv("PAYMENT_CALLBACK_SECRET", ""),
+		PaymentIntentTTL:      getEnvDuration("PAYMENT_INTENT_TTL", 30*time.Minute),
 	}
 }
 
+// IsDevelopment 是否为开发或测试环境 只在这些环境开放调试用的接口
+func (c *Config) IsDevelopment() bool {
+	return c.AppEnv == "development" || c.AppEnv == "test"
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -29,3 +88,19 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt 获取整数类型的环境变量 解析失败时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration 获取时长类型的环境变量（如 10s、500ms） 解析失败时返回默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
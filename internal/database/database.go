@@ -2,6 +2,7 @@ package database
 
 import (
 	"demo01/internal/model"
+	"errors"
 	"log"
 
 	"gorm.io/gorm"
@@ -10,15 +11,27 @@ import (
 // InitDatabase 初始化数据库
 func InitDatabase(db *gorm.DB) error {
 	// 1. 自动迁移数据库表结构
-	if err := db.AutoMigrate(&model.Order{}, &model.Inventory{}, &model.Product{}); err != nil {
+	if err := db.AutoMigrate(&model.Order{}, &model.Inventory{}, &model.Product{}, &model.SKU{}, &model.Category{},
+		&model.PriceHistory{}, &model.PriceSchedule{}, &model.User{}, &model.Address{},
+		&model.Coupon{}, &model.CouponRedemption{}, &model.Payment{}, &model.Refund{}); err != nil {
 		return err
 	}
 
-	// 2. 初始化测试数据
+	// 2. 之前未填写的SKU编码存的是空字符串 改为NULL 否则无法再创建没有编码的SKU
+	if err := db.Model(&model.SKU{}).Where("code = ?", "").Update("code", nil).Error; err != nil {
+		return err
+	}
+
+	// 3. 初始化测试数据
 	if err := initTestData(db); err != nil {
 		return err
 	}
 
+	// 4. 将商品上的字符串分类迁移到分类表
+	if err := migrateCategories(db); err != nil {
+		return err
+	}
+
 	log.Println("数据库初始化完成")
 	return nil
 }
@@ -110,3 +123,47 @@ func initTestData(db *gorm.DB) error {
 
 	return nil
 }
+
+// migrateCategories 将商品上的字符串分类迁移为分类表中的一级分类 并回填商品的分类ID
+// 只处理还没有分类ID的商品 重复执行不会产生重复分类
+func migrateCategories(db *gorm.DB) error {
+	var names []string
+	if err := db.Unscoped().Model(&model.Product{}).
+		Where("category_id = 0 AND category <> ''").
+		Distinct().Pluck("category", &names).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	log.Printf("开始迁移 %d 个商品分类...", len(names))
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			// 已存在同名一级分类时直接复用
+			var category model.Category
+			err := tx.Where("parent_id = 0 AND name = ?", name).First(&category).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				category = model.Category{Name: name, Level: 1}
+				if err := tx.Create(&category).Error; err != nil {
+					return err
+				}
+				category.Path = model.CategoryPath("", category.ID)
+				if err := tx.Model(&category).Update("path", category.Path).Error; err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+
+			result := tx.Unscoped().Model(&model.Product{}).
+				Where("category_id = 0 AND category = ?", name).
+				UpdateColumn("category_id", category.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			log.Printf("分类 %s -> ID %d, 迁移商品 %d 个", name, category.ID, result.RowsAffected)
+		}
+		return nil
+	})
+}
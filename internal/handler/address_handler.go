@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddressHandler 用户收货地址簿 路由中的:id为用户ID
+type AddressHandler struct {
+	addressService *service.AddressService
+}
+
+func NewAddressHandler(addressService *service.AddressService) *AddressHandler {
+	return &AddressHandler{addressService: addressService}
+}
+
+// AddressReq 新增或修改收货地址请求
+type AddressReq struct {
+	Receiver  string `json:"receiver" binding:"required"`
+	Phone     string `json:"phone" binding:"required"`
+	Province  string `json:"province" binding:"required"`
+	City      string `json:"city" binding:"required"`
+	District  string `json:"district"`
+	Detail    string `json:"detail" binding:"required"`
+	IsDefault bool   `json:"is_default"`
+}
+
+func (req AddressReq) toInput() service.AddressInput {
+	return service.AddressInput{
+		Receiver:  req.Receiver,
+		Phone:     req.Phone,
+		Province:  req.Province,
+		City:      req.City,
+		District:  req.District,
+		Detail:    req.Detail,
+		IsDefault: req.IsDefault,
+	}
+}
+
+// ListAddressesHandler 查询收货地址列表 默认地址排在最前面
+// GET /users/:id/addresses
+func (h *AddressHandler) ListAddressesHandler(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "用户ID格式错误")
+		return
+	}
+
+	addresses, err := h.addressService.ListAddresses(c.Request.Context(), userID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询收货地址失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "查询收货地址成功", addresses)
+}
+
+// CreateAddressHandler 新增收货地址
+// POST /users/:id/addresses
+func (h *AddressHandler) CreateAddressHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "用户ID格式错误")
+		return
+	}
+	var req AddressReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层新增地址
+	address, err := h.addressService.CreateAddress(c.Request.Context(), userID, req.toInput())
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "新增收货地址失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "新增收货地址成功", address)
+}
+
+// UpdateAddressHandler 修改收货地址
+// PUT /users/:id/addresses/:address_id
+func (h *AddressHandler) UpdateAddressHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	userID, addressID, ok := parseAddressParams(c)
+	if !ok {
+		return
+	}
+	var req AddressReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层修改地址
+	address, err := h.addressService.UpdateAddress(c.Request.Context(), userID, addressID, req.toInput())
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "修改收货地址失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "修改收货地址成功", address)
+}
+
+// SetDefaultAddressHandler 设为默认收货地址
+// POST /users/:id/addresses/:address_id/default
+func (h *AddressHandler) SetDefaultAddressHandler(c *gin.Context) {
+	userID, addressID, ok := parseAddressParams(c)
+	if !ok {
+		return
+	}
+
+	address, err := h.addressService.SetDefault(c.Request.Context(), userID, addressID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "设置默认地址失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "设置默认地址成功", address)
+}
+
+// DeleteAddressHandler 删除收货地址
+// DELETE /users/:id/addresses/:address_id
+func (h *AddressHandler) DeleteAddressHandler(c *gin.Context) {
+	userID, addressID, ok := parseAddressParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.addressService.DeleteAddress(c.Request.Context(), userID, addressID); err != nil {
+		util.ResponseUtil.BusinessError(c, "删除收货地址失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "删除收货地址成功", gin.H{"id": addressID})
+}
+
+// parseAddressParams 解析路径中的用户ID和地址ID 参数错误时已写入响应并返回false
+func parseAddressParams(c *gin.Context) (userID, addressID int, ok bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "用户ID格式错误")
+		return 0, 0, false
+	}
+	addressID, err = strconv.Atoi(c.Param("address_id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "地址ID格式错误")
+		return 0, 0, false
+	}
+	return userID, addressID, true
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 运维管理接口
+type AdminHandler struct {
+	orderService   *service.OrderService
+	productService *service.ProductService
+}
+
+func NewAdminHandler(orderService *service.OrderService, productService *service.ProductService) *AdminHandler {
+	return &AdminHandler{
+		orderService:   orderService,
+		productService: productService,
+	}
+}
+
+// ListLocksHandler 查看当前持有中的分布式锁及锁指标
+// GET /admin/locks?pattern=lock:inventory:*&limit=100
+func (h *AdminHandler) ListLocksHandler(c *gin.Context) {
+	// 1. 参数获取和默认值设置
+	pattern := c.DefaultQuery("pattern", "lock:*")
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 1000 // 限制最大返回数量
+	}
+
+	// 2. 扫描redis中的锁
+	ctx := c.Request.Context()
+	locks, err := util.ListLocks(ctx, util.RedisClient, pattern, limit)
+	if err != nil {
+		util.ResponseUtil.ServerError(c, "查询分布式锁失败: "+err.Error())
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询分布式锁成功", gin.H{
+		"locks":   locks,
+		"count":   len(locks),
+		"metrics": util.GlobalLockMetrics.Snapshot(),
+	})
+}
+
+// CacheStatsHandler 查看订单和商品各级缓存的命中/未命中/容量统计
+// GET /admin/cache/stats
+func (h *AdminHandler) CacheStatsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	orderStats, err := h.orderService.CacheStats(ctx)
+	if err != nil {
+		util.ResponseUtil.ServerError(c, "查询订单缓存统计失败: "+err.Error())
+		return
+	}
+	productStats, err := h.productService.CacheStats(ctx)
+	if err != nil {
+		util.ResponseUtil.ServerError(c, "查询商品缓存统计失败: "+err.Error())
+		return
+	}
+
+	util.ResponseUtil.Success(c, "查询缓存统计成功", gin.H{
+		"orders":   orderStats,
+		"products": productStats,
+	})
+}
+
+// PurgeCacheHandler 清除本地缓存和Redis缓存
+// DELETE /admin/cache/:entity     清空该类缓存（orders | products）
+// DELETE /admin/cache/:entity/:id 只清除单条缓存
+func (h *AdminHandler) PurgeCacheHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	entity, id := c.Param("entity"), c.Param("id")
+	ctx := c.Request.Context()
+
+	// 2. 按实体类型调用 Service 层清除缓存
+	switch entity {
+	case "orders":
+		if id != "" {
+			if err := h.orderService.EvictCache(ctx, id); err != nil {
+				util.ResponseUtil.ServerError(c, "清除订单缓存失败: "+err.Error())
+				return
+			}
+			util.ResponseUtil.Success(c, "清除订单缓存成功", gin.H{"entity": entity, "id": id})
+			return
+		}
+		deleted, err := h.orderService.PurgeCache(ctx)
+		if err != nil {
+			util.ResponseUtil.ServerError(c, "清空订单缓存失败: "+err.Error())
+			return
+		}
+		util.ResponseUtil.Success(c, "清空订单缓存成功", gin.H{"entity": entity, "deleted": deleted})
+
+	case "products":
+		if id != "" {
+			productID, err := strconv.Atoi(id)
+			if err != nil {
+				util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+				return
+			}
+			if err := h.productService.EvictCache(ctx, productID); err != nil {
+				util.ResponseUtil.ServerError(c, "清除商品缓存失败: "+err.Error())
+				return
+			}
+			util.ResponseUtil.Success(c, "清除商品缓存成功", gin.H{"entity": entity, "id": productID})
+			return
+		}
+		deleted, err := h.productService.PurgeCache(ctx)
+		if err != nil {
+			util.ResponseUtil.ServerError(c, "清空商品缓存失败: "+err.Error())
+			return
+		}
+		util.ResponseUtil.Success(c, "清空商品缓存成功", gin.H{"entity": entity, "deleted": deleted})
+
+	default:
+		util.ResponseUtil.InvalidParams(c, "不支持的缓存类型: "+entity)
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gin上下文中保存当前登录用户的key
+const (
+	ctxKeyUserID   = "auth_user_id"
+	ctxKeyUsername = "auth_username"
+	ctxKeyRole     = "auth_role"
+)
+
+// AuthRequired 校验Authorization: Bearer <access_token> 并把用户信息写入上下文
+// 只校验签名和过期时间 不查询数据库
+func AuthRequired(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			util.ResponseUtil.Unauthorized(c, "请先登录")
+			c.Abort()
+			return
+		}
+
+		claims, err := authService.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			util.ResponseUtil.BusinessError(c, "登录凭证无效", err)
+			c.Abort()
+			return
+		}
+
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// OptionalAuth 公开接口使用 携带有效令牌时写入用户信息 没有令牌或令牌无效时按未登录处理
+func OptionalAuth(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			if claims, err := authService.Authenticate(strings.TrimSpace(token)); err == nil {
+				setClaims(c, claims)
+			}
+		}
+		c.Next()
+	}
+}
+
+// setClaims 把令牌中的用户信息写入上下文
+func setClaims(c *gin.Context, claims *util.TokenClaims) {
+	c.Set(ctxKeyUserID, claims.UserID)
+	c.Set(ctxKeyUsername, claims.Username)
+	c.Set(ctxKeyRole, claims.Role)
+}
+
+// AdminRequired 只允许管理员访问 需要放在AuthRequired之后
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			util.ResponseUtil.Forbidden(c, "需要管理员权限")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SelfOrAdmin 只允许用户访问自己的资源 管理员可以访问所有用户 param为路径中用户ID参数名
+func SelfOrAdmin(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := currentUserID(c)
+		if c.Param(param) != strconv.Itoa(userID) && !isAdmin(c) {
+			util.ResponseUtil.Forbidden(c, "无权访问其他用户的数据")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUserID 当前登录用户的ID
+func currentUserID(c *gin.Context) (int, bool) {
+	userID := c.GetInt(ctxKeyUserID)
+	return userID, userID > 0
+}
+
+// isAdmin 当前登录用户是否为管理员
+func isAdmin(c *gin.Context) bool {
+	return c.GetString(ctxKeyRole) == model.UserRoleAdmin
+}
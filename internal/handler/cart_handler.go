@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"demo01/internal/service"
+	"demo01/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CartHandler 购物车 只能操作当前登录用户自己的购物车
+type CartHandler struct {
+	cartService *service.CartService
+}
+
+func NewCartHandler(cartService *service.CartService) *CartHandler {
+	return &CartHandler{cartService: cartService}
+}
+
+// AddCartItemReq 加入购物车请求
+type AddCartItemReq struct {
+	ProductID int `json:"product_id" binding:"required"`
+	SKUID     int `json:"sku_id"` // 多规格商品必须指定
+	Quantity  int `json:"quantity" binding:"required"`
+}
+
+// AddItemHandler 加入购物车
+// POST /cart/items
+func (h *CartHandler) AddItemHandler(c *gin.Context) {
+	// 1. 参数绑定和验证
+	var req AddCartItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层加入购物车
+	userID, _ := currentUserID(c)
+	item, err := h.cartService.AddItem(c.Request.Context(), userID, req.ProductID, req.SKUID, req.Quantity)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "加入购物车失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "加入购物车成功", gin.H{
+		"id":   item.ID(),
+		"item": item,
+	})
+}
+
+// UpdateCartItemReq 修改购物车商品请求 不传的字段不修改
+type UpdateCartItemReq struct {
+	Quantity *int  `json:"quantity"`
+	Selected *bool `json:"selected"`
+}
+
+// UpdateItemHandler 修改购物车商品的数量或勾选状态
+// PUT /cart/items/:item_id
+func (h *CartHandler) UpdateItemHandler(c *gin.Context) {
+	// 1. 参数绑定和验证
+	var req UpdateCartItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.Quantity == nil && req.Selected == nil {
+		util.ResponseUtil.InvalidParams(c, "没有需要修改的内容")
+		return
+	}
+
+	// 2. 调用 Service 层修改
+	userID, _ := currentUserID(c)
+	itemID := c.Param("item_id")
+	item, err := h.cartService.UpdateItem(c.Request.Context(), userID, itemID, service.CartItemUpdate{
+		Quantity: req.Quantity,
+		Selected: req.Selected,
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "修改购物车失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "修改购物车成功", gin.H{
+		"id":   itemID,
+		"item": item,
+	})
+}
+
+// RemoveItemHandler 从购物车删除商品
+// DELETE /cart/items/:item_id
+func (h *CartHandler) RemoveItemHandler(c *gin.Context) {
+	userID, _ := currentUserID(c)
+	itemID := c.Param("item_id")
+	if err := h.cartService.RemoveItem(c.Request.Context(), userID, itemID); err != nil {
+		util.ResponseUtil.BusinessError(c, "删除购物车商品失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "删除购物车商品成功", gin.H{"id": itemID})
+}
+
+// ClearCartHandler 清空购物车
+// DELETE /cart
+func (h *CartHandler) ClearCartHandler(c *gin.Context) {
+	userID, _ := currentUserID(c)
+	if err := h.cartService.ClearCart(c.Request.Context(), userID); err != nil {
+		util.ResponseUtil.BusinessError(c, "清空购物车失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "购物车已清空", nil)
+}
+
+// GetCartHandler 查看购物车 返回实时价格和库存状态
+// GET /cart
+func (h *CartHandler) GetCartHandler(c *gin.Context) {
+	userID, _ := currentUserID(c)
+	cart, err := h.cartService.GetCart(c.Request.Context(), userID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询购物车失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "查询购物车成功", cart)
+}
+
+// CheckoutReq 结算请求 item_ids为空时结算所有勾选的商品
+type CheckoutReq struct {
+	ItemIDs    []string `json:"item_ids"`
+	AddressID  int      `json:"address_id"`  // 收货地址ID 不传时使用默认地址
+	CouponCode string   `json:"coupon_code"` // 优惠券码 可选
+}
+
+// CheckoutHandler 结算购物车 创建订单成功后删除已结算的商品
+// POST /cart/checkout
+func (h *CartHandler) CheckoutHandler(c *gin.Context) {
+	// 1. 参数绑定 请求体可以为空
+	var req CheckoutReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	// 2. 调用 Service 层结算
+	userID, _ := currentUserID(c)
+	order, err := h.cartService.Checkout(c.Request.Context(), userID, req.ItemIDs, service.CreateOrderOptions{
+		AddressID:  req.AddressID,
+		CouponCode: req.CouponCode,
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "结算失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "订单创建成功", order)
+}
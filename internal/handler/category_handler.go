@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+	productService  *service.ProductService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService, productService *service.ProductService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+		productService:  productService,
+	}
+}
+
+// CreateCategoryReq 创建分类请求
+type CreateCategoryReq struct {
+	Name      string `json:"name" binding:"required"`
+	ParentID  int    `json:"parent_id"` // 0或不传表示一级分类
+	SortOrder int    `json:"sort_order"`
+}
+
+// CreateCategoryHandler 创建分类
+// POST /categories
+func (h *CategoryHandler) CreateCategoryHandler(c *gin.Context) {
+	// 1. 参数绑定和验证
+	var req CreateCategoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层创建分类
+	category, err := h.categoryService.CreateCategory(c.Request.Context(), req.Name, req.ParentID, req.SortOrder)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "创建分类失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "分类创建成功", category)
+}
+
+// GetCategoryTreeHandler 获取完整的分类树
+// GET /categories
+func (h *CategoryHandler) GetCategoryTreeHandler(c *gin.Context) {
+	tree, err := h.categoryService.GetCategoryTree(c.Request.Context())
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询分类失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "查询分类成功", gin.H{"categories": tree})
+}
+
+// GetCategoryHandler 获取分类及其子分类
+// GET /categories/:id
+func (h *CategoryHandler) GetCategoryHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "分类ID格式错误")
+		return
+	}
+
+	// 2. 调用 Service 层查询分类
+	category, err := h.categoryService.GetCategory(c.Request.Context(), categoryID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询分类失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询分类成功", category)
+}
+
+// UpdateCategoryReq 更新分类请求 只更新传入的字段
+type UpdateCategoryReq struct {
+	Name      *string `json:"name"`
+	ParentID  *int    `json:"parent_id"` // 传入时移动分类 0表示移动为一级分类
+	SortOrder *int    `json:"sort_order"`
+}
+
+// UpdateCategoryHandler 修改分类名称、排序或移动分类
+// PUT /categories/:id
+func (h *CategoryHandler) UpdateCategoryHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "分类ID格式错误")
+		return
+	}
+	var req UpdateCategoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层更新分类
+	category, err := h.categoryService.UpdateCategory(c.Request.Context(), categoryID, service.CategoryUpdate{
+		Name:      req.Name,
+		ParentID:  req.ParentID,
+		SortOrder: req.SortOrder,
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "更新分类失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "分类更新成功", category)
+}
+
+// DeleteCategoryHandler 删除分类 存在子分类或商品时不允许删除
+// DELETE /categories/:id
+func (h *CategoryHandler) DeleteCategoryHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "分类ID格式错误")
+		return
+	}
+
+	// 2. 调用 Service 层删除分类
+	if err := h.categoryService.DeleteCategory(c.Request.Context(), categoryID); err != nil {
+		util.ResponseUtil.BusinessError(c, "删除分类失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "分类删除成功", gin.H{"id": categoryID})
+}
+
+// ListCategoryProductsHandler 查询分类（包含所有子孙分类）下的商品
+// GET /categories/:id/products?page=1&page_size=10&sort=price&order=asc
+func (h *CategoryHandler) ListCategoryProductsHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "分类ID格式错误")
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100 // 限制最大分页大小
+	}
+	sortBy := c.Query("sort")
+	if sortBy != "" && sortBy != "price" && sortBy != "created_at" && sortBy != "sales" {
+		util.ResponseUtil.InvalidParams(c, "不支持的排序字段: "+sortBy)
+		return
+	}
+
+	// 2. 调用 Service 层查询商品
+	products, total, err := h.productService.SearchProducts(c.Request.Context(), model.ProductFilter{
+		CategoryID: categoryID,
+		SortBy:     sortBy,
+		Desc:       strings.EqualFold(c.Query("order"), "desc"),
+		Page:       page,
+		PageSize:   pageSize,
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询分类商品失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询分类商品成功", gin.H{
+		"category_id": categoryID,
+		"products":    products,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CouponHandler 优惠券管理（管理员）
+type CouponHandler struct {
+	couponService *service.CouponService
+}
+
+func NewCouponHandler(couponService *service.CouponService) *CouponHandler {
+	return &CouponHandler{couponService: couponService}
+}
+
+// CreateCouponReq 创建优惠券请求
+type CreateCouponReq struct {
+	Code         string    `json:"code" binding:"required"`
+	Name         string    `json:"name" binding:"required"`
+	Type         string    `json:"type" binding:"required"` // fixed / percent / threshold
+	Amount       float64   `json:"amount"`                  // 立减或满减金额
+	Percent      int       `json:"percent"`                 // 折扣券减免的百分比 如 20 表示打八折
+	MaxDiscount  float64   `json:"max_discount"`            // 折扣券最高优惠 0表示不限
+	MinSpend     float64   `json:"min_spend"`               // 使用门槛
+	ProductIDs   []int     `json:"product_ids"`             // 适用商品 与分类都为空时全场可用
+	CategoryIDs  []int     `json:"category_ids"`            // 适用分类 包含子分类
+	TotalLimit   int       `json:"total_limit"`             // 总使用次数 0表示不限
+	PerUserLimit *int      `json:"per_user_limit"`          // 每人使用次数 不传时默认1次 0表示不限
+	StartAt      time.Time `json:"start_at" binding:"required"`
+	EndAt        time.Time `json:"end_at" binding:"required"`
+}
+
+// CreateCouponHandler 创建优惠券
+// POST /admin/coupons
+func (h *CouponHandler) CreateCouponHandler(c *gin.Context) {
+	// 1. 参数绑定和验证
+	var req CreateCouponReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+	perUserLimit := 1
+	if req.PerUserLimit != nil {
+		perUserLimit = *req.PerUserLimit
+	}
+
+	// 2. 调用 Service 层创建优惠券
+	coupon := &model.Coupon{
+		Code:         req.Code,
+		Name:         req.Name,
+		Type:         req.Type,
+		Amount:       req.Amount,
+		Percent:      req.Percent,
+		MaxDiscount:  req.MaxDiscount,
+		MinSpend:     req.MinSpend,
+		ProductIDs:   req.ProductIDs,
+		CategoryIDs:  req.CategoryIDs,
+		TotalLimit:   req.TotalLimit,
+		PerUserLimit: perUserLimit,
+		StartAt:      req.StartAt,
+		EndAt:        req.EndAt,
+	}
+	if err := h.couponService.CreateCoupon(c.Request.Context(), coupon); err != nil {
+		util.ResponseUtil.BusinessError(c, "创建优惠券失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "创建优惠券成功", coupon)
+}
+
+// ListCouponsHandler 分页查询优惠券
+// GET /admin/coupons?status=active&page=1&page_size=10
+func (h *CouponHandler) ListCouponsHandler(c *gin.Context) {
+	// 1. 参数获取和默认值设置
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100 // 限制最大分页大小
+	}
+
+	// 2. 调用 Service 层查询
+	coupons, total, err := h.couponService.ListCoupons(c.Request.Context(), c.Query("status"), page, pageSize)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询优惠券列表失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询优惠券列表成功", gin.H{
+		"coupons":   coupons,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetCouponHandler 查询优惠券详情
+// GET /admin/coupons/:id
+func (h *CouponHandler) GetCouponHandler(c *gin.Context) {
+	couponID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "优惠券ID格式错误")
+		return
+	}
+
+	coupon, err := h.couponService.GetCoupon(c.Request.Context(), couponID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询优惠券失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "查询优惠券成功", coupon)
+}
+
+// ChangeCouponStatusReq 优惠券状态变更请求
+type ChangeCouponStatusReq struct {
+	Status string `json:"status" binding:"required"` // active / disabled
+}
+
+// ChangeCouponStatusHandler 启用或停用优惠券
+// POST /admin/coupons/:id/status
+func (h *CouponHandler) ChangeCouponStatusHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	couponID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "优惠券ID格式错误")
+		return
+	}
+	var req ChangeCouponStatusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层变更状态
+	coupon, err := h.couponService.ChangeStatus(c.Request.Context(), couponID, req.Status)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "变更优惠券状态失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "优惠券状态变更成功", coupon)
+}
@@ -16,9 +16,11 @@ type OrderHandler struct {
 }
 
 // CreateOrderReq 创建订单请求
+// 下单用户取自登录令牌 不再由请求指定
 type CreateOrderReq struct {
-	UserID string            `json:"user_id" binding:"required"`
-	Items  []model.OrderItem `json:"items" binding:"required"` // 可以一次传入多个商品（我想的是购物车可以批量下单
+	Items      []model.OrderItem `json:"items" binding:"required"` // 可以一次传入多个商品（我想的是购物车可以批量下单
+	AddressID  int               `json:"address_id"`               // 收货地址ID 不传时使用默认地址
+	CouponCode string            `json:"coupon_code"`              // 优惠券码 可选
 }
 
 func NewOrderHandler(orderService *service.OrderService) *OrderHandler {
@@ -43,9 +45,13 @@ func (h *OrderHandler) CreateOrderHandler(c *gin.Context) {
 	}
 
 	// 3. 调用 Service 层处理业务逻辑
-	order, err := h.orderService.CreateOrder(c.Request.Context(), req.UserID, req.Items)
+	userID, _ := currentUserID(c)
+	order, err := h.orderService.CreateOrder(c.Request.Context(), strconv.Itoa(userID), req.Items, service.CreateOrderOptions{
+		AddressID:  req.AddressID,
+		CouponCode: req.CouponCode,
+	})
 	if err != nil {
-		util.ResponseUtil.ServerError(c, "创建订单失败: "+err.Error())
+		util.ResponseUtil.BusinessError(c, "创建订单失败", err)
 		return
 	}
 
@@ -53,40 +59,107 @@ func (h *OrderHandler) CreateOrderHandler(c *gin.Context) {
 	util.ResponseUtil.Success(c, "订单创建成功", order)
 }
 
-// GetAllOrdersHandler 分页查询所有订单
+// GetAllOrdersHandler 按条件查询订单（管理员） 游标分页
+// GET /orders?user_id=12&status=paid&from=2025-01-01&to=2025-02-01&min_amount=100&max_amount=500&limit=20&cursor=xxx
+// 返回的next_cursor作为下一页的cursor参数
 func (h *OrderHandler) GetAllOrdersHandler(c *gin.Context) {
-	// 1. 参数获取和转换
-	pageStr, pageSizeStr := c.Query("page"), c.Query("page_size")
-	page, _ := strconv.Atoi(pageStr)
-	pageSize, _ := strconv.Atoi(pageSizeStr)
-
-	// 2. 参数验证和默认值设置
-	if page <= 0 {
-		page = 1
+	// 1. 参数获取和验证
+	filter, ok := parseOrderFilter(c)
+	if !ok {
+		return
 	}
-	if pageSize <= 0 {
-		pageSize = 10
+	filter.UserID = c.Query("user_id")
+
+	// 2. 调用 Service 层查询数据
+	page, err := h.orderService.SearchOrders(c.Request.Context(), filter)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询订单列表失败", err)
+		return
 	}
-	if pageSize > 100 {
-		pageSize = 100 // 限制最大分页大小
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询订单列表成功", page)
+}
+
+// ListUserOrdersHandler 查询用户的订单历史 筛选条件与订单列表相同
+// GET /users/:id/orders?status=paid&limit=20&cursor=xxx
+func (h *OrderHandler) ListUserOrdersHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	filter, ok := parseOrderFilter(c)
+	if !ok {
+		return
 	}
+	filter.UserID = c.Param("id")
 
-	// 3. 调用 Service 层查询数据
-	orders, err := h.orderService.GetAllOrders(c.Request.Context(), page, pageSize)
+	// 2. 调用 Service 层查询数据
+	page, err := h.orderService.SearchOrders(c.Request.Context(), filter)
 	if err != nil {
-		util.ResponseUtil.ServerError(c, "查询订单列表失败: "+err.Error())
+		util.ResponseUtil.BusinessError(c, "查询订单历史失败", err)
 		return
 	}
 
-	// 4. 封装并返回响应
-	util.ResponseUtil.Success(c, "查询订单列表成功", gin.H{
-		"orders":    orders,
-		"page":      page,
-		"page_size": pageSize,
-	})
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询订单历史成功", page)
 }
 
-// GetOrderHandler 查询订单接口
+// parseOrderFilter 解析订单筛选和分页参数 参数错误时已写入响应并返回false
+func parseOrderFilter(c *gin.Context) (model.OrderFilter, bool) {
+	filter := model.OrderFilter{Status: c.Query("status")}
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = parseTime(v); err != nil {
+			util.ResponseUtil.InvalidParams(c, "from时间格式错误: "+v)
+			return filter, false
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseTime(v)
+		if err != nil {
+			util.ResponseUtil.InvalidParams(c, "to时间格式错误: "+v)
+			return filter, false
+		}
+		// 只有日期时包含当天的订单 查询到第二天零点之前
+		if isDateOnly(v) {
+			filter.Before = to.AddDate(0, 0, 1)
+		} else {
+			filter.To = to
+		}
+	}
+	if v := c.Query("min_amount"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			util.ResponseUtil.InvalidParams(c, "最低金额格式错误: "+v)
+			return filter, false
+		}
+		filter.MinAmount = &amount
+	}
+	if v := c.Query("max_amount"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			util.ResponseUtil.InvalidParams(c, "最高金额格式错误: "+v)
+			return filter, false
+		}
+		filter.MaxAmount = &amount
+	}
+	if v := c.Query("cursor"); v != "" {
+		if filter.Cursor, err = model.DecodeOrderCursor(v); err != nil {
+			util.ResponseUtil.InvalidParams(c, err.Error())
+			return filter, false
+		}
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100 // 限制最大分页大小
+	}
+	return filter, true
+}
+
+// GetOrderHandler 查询订单接口 普通用户只能查询自己的订单
 func (h *OrderHandler) GetOrderHandler(c *gin.Context) {
 	// 1. 参数获取和验证
 	orderID := c.Param("id")
@@ -97,7 +170,7 @@ func (h *OrderHandler) GetOrderHandler(c *gin.Context) {
 
 	// 2. 调用 Service 层查询订单
 	order, err := h.orderService.GetOrder(c.Request.Context(), orderID)
-	if err != nil {
+	if err != nil || !canAccessOrder(c, order) {
 		util.ResponseUtil.NotFound(c, "订单不存在")
 		return
 	}
@@ -105,3 +178,47 @@ func (h *OrderHandler) GetOrderHandler(c *gin.Context) {
 	// 3. 封装并返回响应
 	util.ResponseUtil.Success(c, "查询订单成功", order)
 }
+
+// ShipOrderReq 发货请求
+type ShipOrderReq struct {
+	Carrier        string `json:"carrier" binding:"required"`         // 承运商 如 顺丰
+	TrackingNumber string `json:"tracking_number" binding:"required"` // 运单号
+}
+
+// ShipOrderHandler 订单发货（管理员） 已支付的订单才能发货
+// POST /orders/:id/ship
+func (h *OrderHandler) ShipOrderHandler(c *gin.Context) {
+	// 1. 参数绑定和验证
+	var req ShipOrderReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层发货
+	order, err := h.orderService.ShipOrder(c.Request.Context(), c.Param("id"), req.Carrier, req.TrackingNumber)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "订单发货失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "订单发货成功", order)
+}
+
+// GetTrackingHandler 查询订单的收货地址和物流信息 普通用户只能查询自己的订单
+// GET /orders/:id/tracking
+func (h *OrderHandler) GetTrackingHandler(c *gin.Context) {
+	order, err := h.orderService.GetOrder(c.Request.Context(), c.Param("id"))
+	if err != nil || !canAccessOrder(c, order) {
+		util.ResponseUtil.NotFound(c, "订单不存在")
+		return
+	}
+	util.ResponseUtil.Success(c, "查询物流信息成功", service.TrackingOf(order))
+}
+
+// canAccessOrder 当前用户能否访问订单 其他用户的订单按不存在处理 不暴露订单ID是否存在
+func canAccessOrder(c *gin.Context, order *model.Order) bool {
+	userID, _ := currentUserID(c)
+	return order.UserID == strconv.Itoa(userID) || isAdmin(c)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"demo01/internal/service"
+	"demo01/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支付回调的签名请求头
+const (
+	headerPaymentSignature = "X-Signature"
+	headerPaymentTimestamp = "X-Timestamp"
+)
+
+// PaymentHandler 订单支付
+type PaymentHandler struct {
+	paymentService *service.PaymentService
+}
+
+func NewPaymentHandler(paymentService *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{paymentService: paymentService}
+}
+
+// CreatePaymentHandler 为自己的待支付订单发起支付 返回支付页面地址
+// POST /orders/:id/payments
+func (h *PaymentHandler) CreatePaymentHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	orderID := c.Param("id")
+	if orderID == "" {
+		util.ResponseUtil.InvalidParams(c, "订单ID不能为空")
+		return
+	}
+
+	// 2. 调用 Service 层发起支付
+	userID, _ := currentUserID(c)
+	payment, err := h.paymentService.CreatePayment(c.Request.Context(), orderID, userID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "发起支付失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "发起支付成功", payment)
+}
+
+// PaymentCallbackHandler 支付渠道的异步回调 签名在请求头中 签名内容为原始请求体
+// POST /payments/callback
+func (h *PaymentHandler) PaymentCallbackHandler(c *gin.Context) {
+	// 1. 读取原始请求体 不能先绑定JSON 否则重新序列化后签名对不上
+	body, err := c.GetRawData()
+	if err != nil || len(body) == 0 {
+		util.ResponseUtil.InvalidParams(c, "回调内容不能为空")
+		return
+	}
+
+	// 2. 调用 Service 层校验签名并更新支付状态
+	payment, err := h.paymentService.HandleCallback(c.Request.Context(),
+		c.GetHeader(headerPaymentSignature), c.GetHeader(headerPaymentTimestamp), body)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "处理支付回调失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "处理支付回调成功", payment)
+}
+
+// MockPayHandler 模拟用户在支付页面完成支付 仅模拟支付渠道可用
+// POST /payments/mock/:ref/pay
+func (h *PaymentHandler) MockPayHandler(c *gin.Context) {
+	userID, _ := currentUserID(c)
+	payment, err := h.paymentService.MockPay(c.Request.Context(), c.Param("ref"), userID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "模拟支付失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "模拟支付成功", payment)
+}
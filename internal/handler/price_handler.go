@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"demo01/internal/util"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPriceHistoryHandler 查询商品价格变更记录
+// GET /products/:id/price-history?sku_id=3&from=2025-01-01&to=2025-02-01&at=2025-01-15 10:00:00
+// 传入at时同时返回该时刻的价格 用于核对订单下单时的价格 多规格商品通过sku_id查询SKU的价格
+func (h *ProductHandler) GetPriceHistoryHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	skuID := 0
+	if v := c.Query("sku_id"); v != "" {
+		if skuID, err = strconv.Atoi(v); err != nil || skuID <= 0 {
+			util.ResponseUtil.InvalidParams(c, "SKU ID格式错误")
+			return
+		}
+	}
+	var from, to time.Time
+	if v := c.Query("from"); v != "" {
+		if from, err = parseTime(v); err != nil {
+			util.ResponseUtil.InvalidParams(c, "from时间格式错误: "+v)
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = parseTime(v); err != nil {
+			util.ResponseUtil.InvalidParams(c, "to时间格式错误: "+v)
+			return
+		}
+	}
+	var at *time.Time
+	if v := c.Query("at"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			util.ResponseUtil.InvalidParams(c, "at时间格式错误: "+v)
+			return
+		}
+		at = &t
+	}
+
+	// 2. 调用 Service 层查询
+	result, err := h.productService.GetPriceHistory(c.Request.Context(), productID, skuID, from, to, at)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询价格变更记录失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询价格变更记录成功", result)
+}
+
+// SchedulePriceReq 定时调价请求
+type SchedulePriceReq struct {
+	Price       float64 `json:"price" binding:"required"`
+	EffectiveAt string  `json:"effective_at" binding:"required"` // 如 2025-06-06 00:00:00 或 RFC3339
+	Reason      string  `json:"reason"`
+}
+
+// SchedulePriceHandler 创建定时调价
+// POST /products/:id/price-schedules
+func (h *ProductHandler) SchedulePriceHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	var req SchedulePriceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+	effectiveAt, err := parseTime(req.EffectiveAt)
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "生效时间格式错误: "+req.EffectiveAt)
+		return
+	}
+
+	// 2. 调用 Service 层创建定时调价
+	schedule, err := h.productService.SchedulePriceChange(c.Request.Context(), productID, req.Price, effectiveAt,
+		operatorFromRequest(c), req.Reason)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "创建定时调价失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "定时调价创建成功", schedule)
+}
+
+// ListPriceSchedulesHandler 查询商品的定时调价
+// GET /products/:id/price-schedules
+func (h *ProductHandler) ListPriceSchedulesHandler(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+
+	schedules, err := h.productService.ListPriceSchedules(c.Request.Context(), productID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询定时调价失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "查询定时调价成功", gin.H{
+		"product_id": productID,
+		"schedules":  schedules,
+	})
+}
+
+// CancelPriceScheduleHandler 取消还未生效的定时调价
+// DELETE /products/:id/price-schedules/:schedule_id
+func (h *ProductHandler) CancelPriceScheduleHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	scheduleID, err := strconv.Atoi(c.Param("schedule_id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "定时调价ID格式错误")
+		return
+	}
+
+	// 2. 调用 Service 层取消
+	if err := h.productService.CancelPriceSchedule(c.Request.Context(), productID, scheduleID); err != nil {
+		util.ResponseUtil.BusinessError(c, "取消定时调价失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "定时调价已取消", gin.H{"product_id": productID, "schedule_id": scheduleID})
+}
+
+// 只有日期的时间格式
+const dateLayout = "2006-01-02"
+
+// 支持的时间格式 不带时区的按服务器本地时区解析
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", dateLayout}
+
+// isDateOnly 请求中的时间是否只有日期
+func isDateOnly(value string) bool {
+	_, err := time.ParseInLocation(dateLayout, value, time.Local)
+	return err == nil
+}
+
+// parseTime 解析请求中的时间
+func parseTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
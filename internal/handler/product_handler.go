@@ -4,7 +4,9 @@ import (
 	"demo01/internal/model"
 	"demo01/internal/service"
 	"demo01/internal/util"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -60,11 +62,165 @@ func (h *ProductHandler) GetProductHandler(c *gin.Context) {
 		return
 	}
 
-	// 3. 封装并返回响应
+	// 3. 封装并返回响应 版本号通过ETag返回 更新时可以放在If-Match中
+	c.Header("ETag", strconv.Quote(strconv.Itoa(product.Version)))
 	util.ResponseUtil.Success(c, "查询商品成功", product)
 }
 
-// GetAllProductsHandler 获取所有商品（分页）
+// UpdateProductReq 更新商品请求 PATCH只更新传入的字段
+type UpdateProductReq struct {
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price"`
+	Category    *string  `json:"category"`
+	CategoryID  *int     `json:"category_id"` // 传入时分类名称以分类表为准
+	Version     *int     `json:"version"`     // 也可以通过If-Match请求头传入
+}
+
+// UpdateProductHandler 更新商品信息（PUT全量 / PATCH部分）
+// 使用乐观锁 版本号来自If-Match请求头或请求体中的version
+func (h *ProductHandler) UpdateProductHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	var req UpdateProductReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+	version, ok := resolveVersion(c, req.Version)
+	if !ok {
+		util.ResponseUtil.InvalidParams(c, "缺少版本号，请通过If-Match请求头或version字段传入")
+		return
+	}
+
+	// 2. PUT为全量更新 名称和价格必填 未传的可选字段置空
+	// 分类名称和分类ID一起置空 避免名称清空后仍按旧的分类ID筛选和匹配优惠券
+	if c.Request.Method == http.MethodPut {
+		if req.Name == nil || req.Price == nil {
+			util.ResponseUtil.InvalidParams(c, "全量更新时名称和价格不能为空")
+			return
+		}
+		empty, noCategory := "", 0
+		if req.Description == nil {
+			req.Description = &empty
+		}
+		if req.Category == nil {
+			req.Category = &empty
+		}
+		if req.CategoryID == nil {
+			req.CategoryID = &noCategory
+		}
+	}
+
+	// 3. 调用 Service 层更新商品
+	product, err := h.productService.UpdateProduct(c.Request.Context(), productID, version, service.ProductUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Category:    req.Category,
+		CategoryID:  req.CategoryID,
+		Operator:    operatorFromRequest(c),
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "更新商品失败", err)
+		return
+	}
+
+	// 4. 封装并返回响应
+	c.Header("ETag", strconv.Quote(strconv.Itoa(product.Version)))
+	util.ResponseUtil.Success(c, "商品更新成功", product)
+}
+
+// ChangeStatusReq 商品状态变更请求
+type ChangeStatusReq struct {
+	Status  string `json:"status" binding:"required"` // draft / active / inactive / discontinued
+	Version *int   `json:"version"`
+}
+
+// ChangeStatusHandler 变更商品状态（上架/下架/停售）
+func (h *ProductHandler) ChangeStatusHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	var req ChangeStatusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+	version, ok := resolveVersion(c, req.Version)
+	if !ok {
+		util.ResponseUtil.InvalidParams(c, "缺少版本号，请通过If-Match请求头或version字段传入")
+		return
+	}
+
+	// 2. 调用 Service 层变更状态
+	product, err := h.productService.ChangeStatus(c.Request.Context(), productID, version, req.Status)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "变更商品状态失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	c.Header("ETag", strconv.Quote(strconv.Itoa(product.Version)))
+	util.ResponseUtil.Success(c, "商品状态变更成功", product)
+}
+
+// DeleteProductHandler 删除商品（软删除） 传入If-Match时校验版本号
+func (h *ProductHandler) DeleteProductHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	version, ok := resolveVersion(c, nil)
+	if !ok {
+		version = -1 // 不校验版本号
+	}
+
+	// 2. 调用 Service 层删除商品
+	if err := h.productService.DeleteProduct(c.Request.Context(), productID, version); err != nil {
+		util.ResponseUtil.BusinessError(c, "删除商品失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "商品删除成功", gin.H{"id": productID})
+}
+
+// resolveVersion 从If-Match请求头或请求体获取版本号 请求头优先
+// If-Match 支持 "3"、W/"3"、3 三种写法
+func resolveVersion(c *gin.Context, bodyVersion *int) (int, bool) {
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		if version, err := strconv.Atoi(tag); err == nil {
+			return version, true
+		}
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true
+	}
+	return 0, false
+}
+
+// operatorFromRequest 获取操作人 记录在价格变更等审计信息中
+// 操作人为登录用户的用户名 未经过认证中间件的请求记为anonymous
+func operatorFromRequest(c *gin.Context) string {
+	if username := c.GetString(ctxKeyUsername); username != "" {
+		return username
+	}
+	return "anonymous"
+}
+
+// GetAllProductsHandler 获取商品列表（分页 + 筛选 + 排序）
+// 支持参数: category, category_id(包含子分类), status(仅管理员), min_price, max_price, in_stock, keyword, sort(price|created_at|sales), order(asc|desc)
 func (h *ProductHandler) GetAllProductsHandler(c *gin.Context) {
 	// 1. 参数获取和转换
 	pageStr, pageSizeStr := c.Query("page"), c.Query("page_size")
@@ -82,22 +238,110 @@ func (h *ProductHandler) GetAllProductsHandler(c *gin.Context) {
 		pageSize = 100 // 限制最大分页大小
 	}
 
+	filter := model.ProductFilter{
+		Category: c.Query("category"),
+		Status:   model.ProductStatusActive,
+		InStock:  c.Query("in_stock") == "true" || c.Query("in_stock") == "1",
+		Keyword:  strings.TrimSpace(c.Query("keyword")),
+		SortBy:   c.Query("sort"),
+		Desc:     strings.EqualFold(c.Query("order"), "desc"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	// 只有管理员可以查看草稿、下架、停售的商品
+	if status := c.Query("status"); status != "" && isAdmin(c) {
+		filter.Status = status
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		id, err := strconv.Atoi(categoryID)
+		if err != nil || id <= 0 {
+			util.ResponseUtil.InvalidParams(c, "分类ID格式错误")
+			return
+		}
+		filter.CategoryID = id
+	}
+	if sortBy := filter.SortBy; sortBy != "" && sortBy != "price" && sortBy != "created_at" && sortBy != "sales" {
+		util.ResponseUtil.InvalidParams(c, "不支持的排序字段: "+sortBy)
+		return
+	}
+	var ok bool
+	if filter.MinPrice, ok = parsePriceQuery(c, "min_price"); !ok {
+		util.ResponseUtil.InvalidParams(c, "最低价格格式错误")
+		return
+	}
+	if filter.MaxPrice, ok = parsePriceQuery(c, "max_price"); !ok {
+		util.ResponseUtil.InvalidParams(c, "最高价格格式错误")
+		return
+	}
+
 	// 3. 调用 Service 层查询数据
 	ctx := c.Request.Context()
-	products, err := h.productService.GetAllProducts(ctx, page, pageSize)
+	products, total, err := h.productService.SearchProducts(ctx, filter)
 	if err != nil {
-		util.ResponseUtil.ServerError(c, "获取商品列表失败: "+err.Error())
+		util.ResponseUtil.BusinessError(c, "获取商品列表失败", err)
 		return
 	}
 
 	// 4. 封装并返回响应
 	util.ResponseUtil.Success(c, "查询商品列表成功", gin.H{
 		"products":  products,
+		"total":     total,
 		"page":      page,
 		"page_size": pageSize,
 	})
 }
 
+// SearchProductsHandler 全文检索商品 按相关度排序并返回高亮
+// GET /products/search?q=苹果手机&page=1&page_size=10
+func (h *ProductHandler) SearchProductsHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		util.ResponseUtil.InvalidParams(c, "搜索关键词不能为空")
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100 // 限制最大分页大小
+	}
+
+	// 2. 调用 Service 层检索
+	results, total, err := h.productService.FullTextSearch(c.Request.Context(), query, page, pageSize)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "搜索商品失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "搜索商品成功", gin.H{
+		"query":     query,
+		"results":   results,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// parsePriceQuery 解析价格查询参数 未传时返回nil
+func parsePriceQuery(c *gin.Context, key string) (*float64, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, true
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price < 0 {
+		return nil, false
+	}
+	return &price, true
+}
+
 // GetStockHandler 获取库存
 func (h *ProductHandler) GetStockHandler(c *gin.Context) {
 	// 1. 参数获取和验证
@@ -124,7 +368,10 @@ func (h *ProductHandler) GetStockHandler(c *gin.Context) {
 }
 
 // RecommendProductsHandler 猜你喜欢商品推荐接口
+// GET /products/recommend?ids=1,2,3
+// 按ids顺序返回可购买的商品详情 查不到或不可购买的ID在missing中返回 不传ids时返回热销商品
 func (h *ProductHandler) RecommendProductsHandler(c *gin.Context) {
+	// 1. 参数获取和验证
 	idsStr := c.Query("ids")
 	var ids []int
 	if idsStr != "" {
@@ -139,16 +386,19 @@ func (h *ProductHandler) RecommendProductsHandler(c *gin.Context) {
 		}
 	}
 
+	// 2. 调用 Service 层批量查询
 	ctx := c.Request.Context()
-	products, err := h.productService.RecommendProducts(ctx, ids)
+	result, err := h.productService.RecommendProducts(ctx, ids)
 	if err != nil {
-		util.ResponseUtil.ServerError(c, "推荐商品失败: "+err.Error())
+		util.ResponseUtil.BusinessError(c, "推荐商品失败", err)
 		return
 	}
 
-	util.ResponseUtil.Success(c, "推荐商品成功 (WaitGroup并发)", gin.H{
-		"mode":     "waitgroup",
-		"products": products,
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "推荐商品成功", gin.H{
+		"mode":     "batch",
+		"products": result.Products,
+		"missing":  result.Missing,
 	})
 }
 
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导入文件大小上限
+const productImportMaxBytes = 100 << 20
+
+// ImportProductsHandler 批量导入商品 按external_sku新增或更新
+// POST /products/import?format=csv|jsonl
+// 请求体可以直接是文件内容 也可以是multipart表单中名为file的文件
+// 未指定format时根据Content-Type或文件扩展名判断
+func (h *ProductHandler) ImportProductsHandler(c *gin.Context) {
+	// 1. 获取文件流和格式 不把整个文件读入内存
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, productImportMaxBytes)
+	format := strings.ToLower(c.Query("format"))
+
+	var body io.Reader = c.Request.Body
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		// 使用MultipartReader逐个读取表单part 不像FormFile那样先把文件落盘或读入内存
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			util.ResponseUtil.InvalidParams(c, "请求格式错误: "+err.Error())
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				util.ResponseUtil.InvalidParams(c, "请上传名为file的文件")
+				return
+			}
+			if part.FormName() == "file" {
+				defer part.Close()
+				body = part
+				if format == "" {
+					format = importFormatFromExt(part.FileName())
+				}
+				break
+			}
+			part.Close()
+		}
+	} else if format == "" {
+		format = importFormatFromMediaType(mediaType)
+	}
+	if format == "" {
+		util.ResponseUtil.InvalidParams(c, "无法识别文件格式，请通过format参数指定csv或jsonl")
+		return
+	}
+
+	// 2. 调用 Service 层逐行导入
+	report, err := h.productService.ImportProducts(c.Request.Context(), body, format, operatorFromRequest(c))
+	if err != nil {
+		if report == nil {
+			util.ResponseUtil.BusinessError(c, "导入商品失败", err)
+			return
+		}
+		// 中途失败时之前的批次已经写入 一并返回报告
+		c.JSON(http.StatusOK, util.Response{
+			Code:    util.CodeError,
+			Message: "导入商品中断: " + err.Error(),
+			Data:    report,
+		})
+		return
+	}
+
+	// 3. 封装并返回导入报告
+	util.ResponseUtil.Success(c, "商品导入完成", report)
+}
+
+// ExportProductsHandler 流式导出商品目录和当前库存
+// GET /products/export?format=csv|jsonl&status=active
+func (h *ProductHandler) ExportProductsHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	format := strings.ToLower(c.DefaultQuery("format", service.ProductFileCSV))
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case service.ProductFileCSV:
+	case service.ProductFileJSONL:
+		contentType = "application/x-ndjson; charset=utf-8"
+	default:
+		util.ResponseUtil.InvalidParams(c, "不支持的文件格式: "+format)
+		return
+	}
+	// 开始输出后就不能再返回错误响应 参数必须在这之前校验完
+	status := c.Query("status")
+	if status != "" && !model.IsValidProductStatus(status) {
+		util.ResponseUtil.InvalidParams(c, "商品状态无效: "+status)
+		return
+	}
+
+	// 2. 设置下载响应头 之后的内容边查询边写出
+	filename := fmt.Sprintf("products_%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// 3. 调用 Service 层导出 响应头已经发出 出错时只能记录日志并中断输出
+	ctx := c.Request.Context()
+	if err := h.productService.ExportProducts(ctx, c.Writer, format, status); err != nil {
+		util.GlobalLogger.Error(ctx, "导出商品失败", err,
+			util.Field{Key: "format", Value: format},
+		)
+		c.Abort()
+	}
+}
+
+// importFormatFromMediaType 根据Content-Type判断导入文件格式
+func importFormatFromMediaType(mediaType string) string {
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return service.ProductFileCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return service.ProductFileJSONL
+	}
+	return ""
+}
+
+// importFormatFromExt 根据文件扩展名判断导入文件格式
+func importFormatFromExt(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return service.ProductFileCSV
+	case ".jsonl", ".ndjson":
+		return service.ProductFileJSONL
+	}
+	return ""
+}
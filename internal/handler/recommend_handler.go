@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 推荐数量 默认10个 最多50个
+const (
+	defaultRecommendLimit = 10
+	maxRecommendLimit     = 50
+)
+
+type RecommendHandler struct {
+	recommendService *service.RecommendService
+}
+
+func NewRecommendHandler(recommendService *service.RecommendService) *RecommendHandler {
+	return &RecommendHandler{recommendService: recommendService}
+}
+
+// ProductRecommendHandler 经常一起购买的商品
+// GET /products/:id/recommend?limit=10
+func (h *RecommendHandler) ProductRecommendHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+
+	// 2. 调用 Service 层查询推荐
+	recs, err := h.recommendService.RecommendForProduct(c.Request.Context(), productID, recommendLimit(c))
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询商品推荐失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询商品推荐成功", gin.H{
+		"product_id":      productID,
+		"recommendations": recs,
+	})
+}
+
+// UserRecommendHandler 根据用户购买记录推荐商品 没有购买记录时返回热销商品
+// GET /users/:id/recommend?limit=10
+func (h *RecommendHandler) UserRecommendHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	userID := c.Param("id")
+	if userID == "" {
+		util.ResponseUtil.InvalidParams(c, "用户ID不能为空")
+		return
+	}
+
+	// 2. 调用 Service 层查询推荐
+	recs, err := h.recommendService.RecommendForUser(c.Request.Context(), userID, recommendLimit(c))
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询用户推荐失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询用户推荐成功", gin.H{
+		"user_id":         userID,
+		"recommendations": recs,
+	})
+}
+
+// recommendLimit 读取推荐数量参数 超出范围时使用默认值或上限
+func recommendLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultRecommendLimit
+	}
+	if limit > maxRecommendLimit {
+		return maxRecommendLimit
+	}
+	return limit
+}
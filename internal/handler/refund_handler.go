@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefundHandler 订单退款
+type RefundHandler struct {
+	refundService *service.RefundService
+	orderService  *service.OrderService
+}
+
+func NewRefundHandler(refundService *service.RefundService, orderService *service.OrderService) *RefundHandler {
+	return &RefundHandler{refundService: refundService, orderService: orderService}
+}
+
+// CreateRefundReq 退款请求 lines为空时全额退款
+type CreateRefundReq struct {
+	Lines  []model.RefundLineRequest `json:"lines"`  // 按订单商品行部分退款 line为商品行下标
+	Reason string                    `json:"reason"` // 退款原因
+}
+
+// CreateRefundHandler 订单退款（管理员） 已支付、已发货、已完成的订单可以退款
+// POST /orders/:id/refunds
+func (h *RefundHandler) CreateRefundHandler(c *gin.Context) {
+	// 1. 参数绑定和验证 请求体为空时全额退款 分块传输的空请求体没有ContentLength 以读到EOF判断
+	var req CreateRefundReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层退款
+	operatorID, _ := currentUserID(c)
+	refund, err := h.refundService.CreateRefund(c.Request.Context(), c.Param("id"), operatorID, req.Lines, req.Reason)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "订单退款失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "订单退款成功", refund)
+}
+
+// ListRefundsHandler 查询订单的退款记录 普通用户只能查询自己的订单
+// GET /orders/:id/refunds
+func (h *RefundHandler) ListRefundsHandler(c *gin.Context) {
+	order, err := h.orderService.GetOrder(c.Request.Context(), c.Param("id"))
+	if err != nil || !canAccessOrder(c, order) {
+		util.ResponseUtil.NotFound(c, "订单不存在")
+		return
+	}
+	refunds, err := h.refundService.ListRefunds(c.Request.Context(), order.ID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询退款记录失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "查询退款记录成功", refunds)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SKUReq 创建/更新SKU请求 更新时只修改传入的字段
+type SKUReq struct {
+	Code       *string              `json:"code"`
+	Attributes []model.SKUAttribute `json:"attributes"` // 如 [{"name":"颜色","value":"黑色"},{"name":"容量","value":"128GB"}]
+	Price      *float64             `json:"price"`
+	Stock      *int                 `json:"stock"`
+	Version    *int                 `json:"version"` // 更新时使用 也可以通过If-Match请求头传入
+}
+
+// ListSKUsHandler 查询商品下的所有SKU
+// GET /products/:id/skus
+func (h *ProductHandler) ListSKUsHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+
+	// 2. 调用 Service 层查询
+	skus, err := h.productService.ListSKUs(c.Request.Context(), productID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询SKU失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询SKU成功", gin.H{
+		"product_id": productID,
+		"skus":       skus,
+		"variants":   model.BuildVariantMatrix(skus),
+	})
+}
+
+// CreateSKUHandler 为商品新增SKU
+// POST /products/:id/skus
+func (h *ProductHandler) CreateSKUHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	var req SKUReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.Price == nil {
+		util.ResponseUtil.InvalidParams(c, "SKU价格不能为空")
+		return
+	}
+
+	sku := &model.SKU{Code: model.NormalizeSKUCode(req.Code), Attributes: req.Attributes, Price: *req.Price}
+	if req.Stock != nil {
+		sku.Stock = *req.Stock
+	}
+
+	// 2. 调用 Service 层创建SKU
+	if err := h.productService.CreateSKU(c.Request.Context(), productID, sku); err != nil {
+		util.ResponseUtil.BusinessError(c, "创建SKU失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "SKU创建成功", sku)
+}
+
+// UpdateSKUHandler 更新SKU的编码、规格、价格或库存（乐观锁）
+// PUT /products/:id/skus/:sku_id
+func (h *ProductHandler) UpdateSKUHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	skuID, err := strconv.Atoi(c.Param("sku_id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "SKU ID格式错误")
+		return
+	}
+	var req SKUReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+	version, ok := resolveVersion(c, req.Version)
+	if !ok {
+		util.ResponseUtil.InvalidParams(c, "缺少版本号，请通过If-Match请求头或version字段传入")
+		return
+	}
+
+	// 2. 调用 Service 层更新SKU
+	sku, err := h.productService.UpdateSKU(c.Request.Context(), productID, skuID, version, service.SKUUpdate{
+		Code:       req.Code,
+		Attributes: req.Attributes,
+		Price:      req.Price,
+		Stock:      req.Stock,
+		Operator:   operatorFromRequest(c),
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "更新SKU失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	c.Header("ETag", strconv.Quote(strconv.Itoa(sku.Version)))
+	util.ResponseUtil.Success(c, "SKU更新成功", sku)
+}
+
+// DeleteSKUHandler 删除SKU
+// DELETE /products/:id/skus/:sku_id
+func (h *ProductHandler) DeleteSKUHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "商品ID格式错误")
+		return
+	}
+	skuID, err := strconv.Atoi(c.Param("sku_id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "SKU ID格式错误")
+		return
+	}
+
+	// 2. 调用 Service 层删除SKU
+	if err := h.productService.DeleteSKU(c.Request.Context(), productID, skuID); err != nil {
+		util.ResponseUtil.BusinessError(c, "删除SKU失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "SKU删除成功", gin.H{"product_id": productID, "sku_id": skuID})
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	userService *service.UserService
+	authService *service.AuthService
+}
+
+func NewUserHandler(userService *service.UserService, authService *service.AuthService) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+		authService: authService,
+	}
+}
+
+// RegisterReq 注册请求
+type RegisterReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+}
+
+// RegisterHandler 用户注册
+// POST /users/register
+func (h *UserHandler) RegisterHandler(c *gin.Context) {
+	// 1. 参数绑定和验证
+	var req RegisterReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层注册
+	user, err := h.userService.Register(c.Request.Context(), service.UserRegistration{
+		Username: req.Username,
+		Password: req.Password,
+		Nickname: req.Nickname,
+		Email:    req.Email,
+		Phone:    req.Phone,
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "注册失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "注册成功", user)
+}
+
+// LoginReq 登录请求
+type LoginReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginHandler 用户登录 返回访问令牌和刷新令牌
+// POST /users/login
+func (h *UserHandler) LoginHandler(c *gin.Context) {
+	// 1. 参数绑定和验证
+	var req LoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层校验用户名和密码并签发令牌
+	tokens, err := h.authService.Login(c.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "登录失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "登录成功", tokens)
+}
+
+// RefreshTokenReq 刷新令牌请求
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshTokenHandler 使用刷新令牌换取新的令牌 旧的刷新令牌随即失效
+// POST /auth/refresh
+func (h *UserHandler) RefreshTokenHandler(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "刷新令牌失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "刷新令牌成功", tokens)
+}
+
+// LogoutHandler 退出登录 吊销刷新令牌
+// POST /auth/logout
+func (h *UserHandler) LogoutHandler(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		util.ResponseUtil.BusinessError(c, "退出登录失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "已退出登录", nil)
+}
+
+// GetUserHandler 查询用户资料
+// GET /users/:id
+func (h *UserHandler) GetUserHandler(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "用户ID格式错误")
+		return
+	}
+
+	user, err := h.userService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询用户失败", err)
+		return
+	}
+	util.ResponseUtil.Success(c, "查询用户成功", user)
+}
+
+// UpdateUserReq 修改资料请求 不传的字段不修改
+type UpdateUserReq struct {
+	Nickname *string `json:"nickname"`
+	Email    *string `json:"email"`
+	Phone    *string `json:"phone"`
+}
+
+// UpdateUserHandler 修改用户资料
+// PUT /users/:id
+func (h *UserHandler) UpdateUserHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "用户ID格式错误")
+		return
+	}
+	var req UpdateUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层修改资料
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userID, service.UserUpdate{
+		Nickname: req.Nickname,
+		Email:    req.Email,
+		Phone:    req.Phone,
+	})
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "修改用户资料失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "用户资料修改成功", user)
+}
+
+// ChangePasswordReq 修改密码请求
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangePasswordHandler 修改密码
+// PUT /users/:id/password
+func (h *UserHandler) ChangePasswordHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "用户ID格式错误")
+		return
+	}
+	var req ChangePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层修改密码
+	if err := h.userService.ChangePassword(c.Request.Context(), userID, req.OldPassword, req.NewPassword); err != nil {
+		util.ResponseUtil.BusinessError(c, "修改密码失败", err)
+		return
+	}
+	// 修改密码后其他设备需要重新登录
+	h.revokeTokens(c, userID)
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "密码修改成功", gin.H{"user_id": userID})
+}
+
+// ListUsersHandler 管理员分页查询用户
+// GET /admin/users?status=active&role=customer&keyword=tom&page=1&page_size=10
+func (h *UserHandler) ListUsersHandler(c *gin.Context) {
+	// 1. 参数获取和默认值设置
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100 // 限制最大分页大小
+	}
+	filter := model.UserFilter{
+		Role:     c.Query("role"),
+		Status:   c.Query("status"),
+		Keyword:  c.Query("keyword"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	// 2. 调用 Service 层查询
+	users, total, err := h.userService.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "查询用户列表失败", err)
+		return
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "查询用户列表成功", gin.H{
+		"users":     users,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ChangeUserStatusReq 用户状态变更请求
+type ChangeUserStatusReq struct {
+	Status string `json:"status" binding:"required"` // active / disabled
+}
+
+// ChangeUserStatusHandler 管理员启用或禁用用户
+// POST /admin/users/:id/status
+func (h *UserHandler) ChangeUserStatusHandler(c *gin.Context) {
+	// 1. 参数获取和验证
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ResponseUtil.InvalidParams(c, "用户ID格式错误")
+		return
+	}
+	var req ChangeUserStatusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.ResponseUtil.InvalidParams(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 2. 调用 Service 层变更状态
+	user, err := h.userService.ChangeStatus(c.Request.Context(), userID, req.Status)
+	if err != nil {
+		util.ResponseUtil.BusinessError(c, "变更用户状态失败", err)
+		return
+	}
+	// 禁用后无法再刷新令牌 已签发的访问令牌在短时间内过期
+	if !user.IsActive() {
+		h.revokeTokens(c, userID)
+	}
+
+	// 3. 封装并返回响应
+	util.ResponseUtil.Success(c, "用户状态变更成功", user)
+}
+
+// revokeTokens 吊销用户的所有刷新令牌 失败只记录日志 不影响已完成的修改
+func (h *UserHandler) revokeTokens(c *gin.Context, userID int) {
+	ctx := c.Request.Context()
+	if err := h.authService.RevokeUserTokens(ctx, userID); err != nil {
+		util.GlobalLogger.Error(ctx, "吊销用户令牌失败", err,
+			util.Field{Key: "user_id", Value: userID},
+		)
+	}
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxAddressesPerUser 每个用户最多保存的收货地址数量
+const MaxAddressesPerUser = 20
+
+// 手机号 11位数字 以1开头
+var mobilePattern = regexp.MustCompile(`^1\d{10}$`)
+
+// Address 用户收货地址 每个用户最多一个默认地址
+type Address struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int       `json:"user_id" gorm:"not null;index:idx_addresses_user_id;comment:所属用户ID"`
+	Receiver  string    `json:"receiver" gorm:"size:32;not null;comment:收货人"`
+	Phone     string    `json:"phone" gorm:"size:20;not null;comment:收货人手机号"`
+	Province  string    `json:"province" gorm:"size:32;not null;comment:省"`
+	City      string    `json:"city" gorm:"size:32;not null;comment:市"`
+	District  string    `json:"district" gorm:"size:32;comment:区县"`
+	Detail    string    `json:"detail" gorm:"size:200;not null;comment:详细地址"`
+	IsDefault bool      `json:"is_default" gorm:"not null;default:false;comment:是否为默认地址"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Address) TableName() string {
+	return "user_addresses"
+}
+
+// ShippingAddress 下单时的收货地址快照 地址簿修改或删除后订单上的地址不变
+type ShippingAddress struct {
+	Receiver string `json:"receiver"`
+	Phone    string `json:"phone"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+	District string `json:"district,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+// Snapshot 生成收货地址快照
+func (a *Address) Snapshot() *ShippingAddress {
+	return &ShippingAddress{
+		Receiver: a.Receiver,
+		Phone:    a.Phone,
+		Province: a.Province,
+		City:     a.City,
+		District: a.District,
+		Detail:   a.Detail,
+	}
+}
+
+// Normalize 去掉各字段首尾的空白
+func (a *Address) Normalize() {
+	a.Receiver = strings.TrimSpace(a.Receiver)
+	a.Phone = strings.TrimSpace(a.Phone)
+	a.Province = strings.TrimSpace(a.Province)
+	a.City = strings.TrimSpace(a.City)
+	a.District = strings.TrimSpace(a.District)
+	a.Detail = strings.TrimSpace(a.Detail)
+}
+
+// Validate 校验收货地址 调用前先Normalize
+func (a *Address) Validate() error {
+	if a.Receiver == "" || len([]rune(a.Receiver)) > 32 {
+		return errors.New("收货人不能为空且不超过32个字符")
+	}
+	if !mobilePattern.MatchString(a.Phone) {
+		return errors.New("收货人手机号格式错误")
+	}
+	if a.Province == "" || a.City == "" {
+		return errors.New("省市不能为空")
+	}
+	if len([]rune(a.Province)) > 32 || len([]rune(a.City)) > 32 || len([]rune(a.District)) > 32 {
+		return errors.New("省市区不能超过32个字符")
+	}
+	if a.Detail == "" || len([]rune(a.Detail)) > 200 {
+		return errors.New("详细地址不能为空且不超过200个字符")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 购物车限制
+const (
+	MaxCartLines        = 100 // 每个购物车最多的商品行数
+	MaxCartItemQuantity = 999 // 每行最多购买数量
+)
+
+// 购物车错误
+var (
+	ErrInvalidCartItemID    = errors.New("购物车商品ID格式错误")
+	ErrCartFull             = fmt.Errorf("购物车最多只能添加%d种商品", MaxCartLines)
+	ErrCartQuantityExceeded = fmt.Errorf("商品数量必须在1到%d之间", MaxCartItemQuantity)
+)
+
+// CartItem 购物车中的一行 只保存商品和数量 价格和库存在查看购物车时实时读取
+type CartItem struct {
+	ProductID int       `json:"product_id"`
+	SKUID     int       `json:"sku_id,omitempty"`
+	Quantity  int       `json:"quantity"`
+	Selected  bool      `json:"selected"` // 结算时默认只提交勾选的商品
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// ID 购物车行ID 同一商品的同一规格只占一行
+// 格式: {product_id}-{sku_id} 无规格商品的sku_id为0
+func (i *CartItem) ID() string {
+	return CartItemID(i.ProductID, i.SKUID)
+}
+
+// CartItemID 根据商品和SKU生成购物车行ID
+func CartItemID(productID, skuID int) string {
+	return fmt.Sprintf("%d-%d", productID, skuID)
+}
+
+// ParseCartItemID 解析购物车行ID
+func ParseCartItemID(id string) (productID, skuID int, err error) {
+	productPart, skuPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, ErrInvalidCartItemID
+	}
+	productID, err = strconv.Atoi(productPart)
+	if err != nil || productID <= 0 {
+		return 0, 0, ErrInvalidCartItemID
+	}
+	skuID, err = strconv.Atoi(skuPart)
+	if err != nil || skuID < 0 {
+		return 0, 0, ErrInvalidCartItemID
+	}
+	return productID, skuID, nil
+}
+
+// ValidateCartQuantity 校验购物车商品数量
+func ValidateCartQuantity(quantity int) error {
+	if quantity <= 0 || quantity > MaxCartItemQuantity {
+		return ErrCartQuantityExceeded
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// MaxCategoryLevel 分类最大层级 一级分类的Level为1
+const MaxCategoryLevel = 5
+
+// Category 商品分类 使用物化路径保存层级关系
+// Path为从根分类到自身的ID路径 如 /1/3/ 查询子孙分类只需要 path LIKE '/1/3/%' 不需要递归查询
+type Category struct {
+	ID        int         `json:"id" gorm:"primaryKey;autoIncrement"`
+	ParentID  int         `json:"parent_id" gorm:"not null;default:0;index:idx_categories_parent_id;comment:父分类ID 0表示一级分类"`
+	Name      string      `json:"name" gorm:"size:50;not null;comment:分类名称"`
+	Path      string      `json:"path" gorm:"size:255;not null;default:'';index:idx_categories_path;comment:物化路径"`
+	Level     int         `json:"level" gorm:"not null;default:1;comment:层级"`
+	SortOrder int         `json:"sort_order" gorm:"not null;default:0;comment:同级排序 越小越靠前"`
+	CreatedAt time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+	Children  []*Category `json:"children,omitempty" gorm:"-"`
+}
+
+// TableName 指定表名
+func (Category) TableName() string {
+	return "categories"
+}
+
+// CategoryPath 根据父分类路径生成子分类的物化路径
+func CategoryPath(parentPath string, id int) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + strconv.Itoa(id) + "/"
+}
+
+// BuildCategoryTree 将分类列表组装为树 子分类保持输入顺序
+// categories需要按 level, sort_order, id 排序 父分类不在列表中的分类作为根节点返回
+func BuildCategoryTree(categories []Category) []*Category {
+	nodes := make(map[int]*Category, len(categories))
+	roots := make([]*Category, 0)
+	for i := range categories {
+		node := categories[i]
+		node.Children = nil
+		nodes[node.ID] = &node
+	}
+	for i := range categories {
+		node := nodes[categories[i].ID]
+		if parent, ok := nodes[node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,252 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 优惠券类型
+const (
+	CouponTypeFixed     = "fixed"     // 立减 直接减Amount元
+	CouponTypePercent   = "percent"   // 折扣 减免Percent% 可以用MaxDiscount限制最高优惠
+	CouponTypeThreshold = "threshold" // 满减 满MinSpend元减Amount元
+)
+
+// 优惠券状态
+const (
+	CouponStatusActive   = "active"   // 可以使用
+	CouponStatusDisabled = "disabled" // 已停用
+)
+
+// 优惠券码 4-32位大写字母、数字、下划线或中划线
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{4,32}$`)
+
+var (
+	ErrCouponDisabled         = errors.New("优惠券已停用")
+	ErrCouponNotStarted       = errors.New("优惠券未到使用时间")
+	ErrCouponExpired          = errors.New("优惠券已过期")
+	ErrCouponNotApplicable    = errors.New("订单中没有可以使用该优惠券的商品")
+	ErrCouponThresholdNotMet  = errors.New("订单金额未达到优惠券的使用门槛")
+	ErrCouponSoldOut          = errors.New("优惠券已被领完")
+	ErrCouponUserLimitReached = errors.New("已达到该优惠券的使用次数上限")
+)
+
+// Coupon 优惠券 ProductIDs和CategoryIDs都为空时全场可用 否则只对范围内的商品生效
+type Coupon struct {
+	ID           int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code         string    `json:"code" gorm:"size:32;not null;uniqueIndex:idx_coupons_code;comment:优惠券码"`
+	Name         string    `json:"name" gorm:"size:64;not null;comment:优惠券名称"`
+	Type         string    `json:"type" gorm:"size:20;not null;comment:优惠类型"`
+	Amount       float64   `json:"amount" gorm:"type:decimal(10,2);not null;default:0;comment:立减或满减金额"`
+	Percent      int       `json:"percent" gorm:"not null;default:0;comment:折扣比例 减免的百分比"`
+	MaxDiscount  float64   `json:"max_discount" gorm:"type:decimal(10,2);not null;default:0;comment:折扣券最高优惠 0表示不限"`
+	MinSpend     float64   `json:"min_spend" gorm:"type:decimal(10,2);not null;default:0;comment:使用门槛 按范围内商品金额计算"`
+	ProductIDs   []int     `json:"product_ids,omitempty" gorm:"type:text;serializer:json;comment:适用商品"`
+	CategoryIDs  []int     `json:"category_ids,omitempty" gorm:"type:text;serializer:json;comment:适用分类 包含子分类"`
+	TotalLimit   int       `json:"total_limit" gorm:"not null;default:0;comment:总使用次数 0表示不限"`
+	PerUserLimit int       `json:"per_user_limit" gorm:"not null;default:1;comment:每个用户可使用次数 0表示不限"`
+	StartAt      time.Time `json:"start_at" gorm:"not null;comment:生效时间"`
+	EndAt        time.Time `json:"end_at" gorm:"not null;comment:失效时间"`
+	Status       string    `json:"status" gorm:"size:20;not null;default:'active';comment:状态"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Coupon) TableName() string {
+	return "coupons"
+}
+
+// CouponRedemption 优惠券使用记录 与订单在同一事务中写入
+type CouponRedemption struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CouponID  int       `json:"coupon_id" gorm:"not null;index:idx_coupon_redemptions_coupon_user,priority:1"`
+	UserID    int       `json:"user_id" gorm:"not null;index:idx_coupon_redemptions_coupon_user,priority:2"`
+	OrderID   string    `json:"order_id" gorm:"type:varchar(32);not null;uniqueIndex:idx_coupon_redemptions_order_id"`
+	Amount    float64   `json:"amount" gorm:"type:decimal(10,2);not null;comment:优惠金额"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (CouponRedemption) TableName() string {
+	return "coupon_redemptions"
+}
+
+// OrderDiscount 订单的优惠明细 下单时计算后保存在订单上
+type OrderDiscount struct {
+	CouponID       int            `json:"coupon_id"`
+	CouponCode     string         `json:"coupon_code"`
+	CouponName     string         `json:"coupon_name"`
+	Type           string         `json:"type"`
+	OriginalAmount float64        `json:"original_amount"` // 优惠前的订单金额
+	EligibleAmount float64        `json:"eligible_amount"` // 参与优惠的商品金额
+	Amount         float64        `json:"amount"`          // 优惠金额
+	Lines          []LineDiscount `json:"lines"`           // 优惠分摊到每个订单项的金额
+}
+
+// LineDiscount 分摊到订单项的优惠 退款时按分摊后的金额退
+type LineDiscount struct {
+	ProductID int     `json:"product_id"`
+	SKUID     int     `json:"sku_id,omitempty"`
+	Subtotal  float64 `json:"subtotal"`
+	Amount    float64 `json:"amount"`
+}
+
+// IsValidCouponType 判断是否为合法的优惠券类型
+func IsValidCouponType(couponType string) bool {
+	switch couponType {
+	case CouponTypeFixed, CouponTypePercent, CouponTypeThreshold:
+		return true
+	}
+	return false
+}
+
+// NormalizeCouponCode 优惠券码不区分大小写 统一转为大写
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate 校验优惠券配置
+func (c *Coupon) Validate() error {
+	if !couponCodePattern.MatchString(c.Code) {
+		return errors.New("优惠券码必须是4-32位字母、数字、下划线或中划线")
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("优惠券名称不能为空")
+	}
+	switch c.Type {
+	case CouponTypeFixed:
+		if c.Amount <= 0 {
+			return errors.New("立减金额必须大于0")
+		}
+	case CouponTypePercent:
+		if c.Percent <= 0 || c.Percent >= 100 {
+			return errors.New("折扣比例必须在1到99之间")
+		}
+		if c.MaxDiscount < 0 {
+			return errors.New("最高优惠不能小于0")
+		}
+	case CouponTypeThreshold:
+		if c.Amount <= 0 || c.MinSpend <= c.Amount {
+			return errors.New("满减券的门槛必须大于减免金额 且减免金额大于0")
+		}
+	default:
+		return errors.New("无效的优惠券类型: " + c.Type)
+	}
+	if c.MinSpend < 0 || c.TotalLimit < 0 || c.PerUserLimit < 0 {
+		return errors.New("门槛和使用次数不能小于0")
+	}
+	if !c.EndAt.After(c.StartAt) {
+		return errors.New("失效时间必须晚于生效时间")
+	}
+	return nil
+}
+
+// CheckUsable 检查优惠券在指定时间是否可以使用
+func (c *Coupon) CheckUsable(now time.Time) error {
+	switch {
+	case c.Status != CouponStatusActive:
+		return ErrCouponDisabled
+	case now.Before(c.StartAt):
+		return ErrCouponNotStarted
+	case !now.Before(c.EndAt):
+		return ErrCouponExpired
+	}
+	return nil
+}
+
+// InScope 判断商品是否在优惠券的适用范围内
+// categoryPath为商品所属分类的物化路径 指定了父分类时子分类的商品也适用
+func (c *Coupon) InScope(productID int, categoryPath string) bool {
+	if len(c.ProductIDs) == 0 && len(c.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range c.ProductIDs {
+		if id == productID {
+			return true
+		}
+	}
+	for _, id := range c.CategoryIDs {
+		if strings.Contains(categoryPath, "/"+strconv.Itoa(id)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Calculate 计算订单的优惠金额 eligible[i]表示items[i]是否在适用范围内
+// 优惠按金额比例分摊到范围内的订单项 写入每个订单项的Discount 分摊的尾差计入最后一项
+func (c *Coupon) Calculate(items []OrderItem, eligible []bool) (*OrderDiscount, error) {
+	discount := &OrderDiscount{
+		CouponID:   c.ID,
+		CouponCode: c.Code,
+		CouponName: c.Name,
+		Type:       c.Type,
+	}
+	last := -1
+	for i, item := range items {
+		subtotal := item.Price * float64(item.Quantity)
+		discount.OriginalAmount += subtotal
+		if eligible[i] {
+			discount.EligibleAmount += subtotal
+			last = i
+		}
+	}
+	discount.OriginalAmount = RoundAmount(discount.OriginalAmount)
+	discount.EligibleAmount = RoundAmount(discount.EligibleAmount)
+	if last < 0 || discount.EligibleAmount <= 0 {
+		return nil, ErrCouponNotApplicable
+	}
+	if discount.EligibleAmount < c.MinSpend {
+		return nil, ErrCouponThresholdNotMet
+	}
+
+	// 1. 计算优惠金额 不超过参与优惠的商品金额
+	var amount float64
+	switch c.Type {
+	case CouponTypeFixed, CouponTypeThreshold:
+		amount = c.Amount
+	case CouponTypePercent:
+		amount = discount.EligibleAmount * float64(c.Percent) / 100
+		if c.MaxDiscount > 0 && amount > c.MaxDiscount {
+			amount = c.MaxDiscount
+		}
+	}
+	amount = RoundAmount(math.Min(amount, discount.EligibleAmount))
+	discount.Amount = amount
+
+	// 2. 按金额比例分摊到订单项
+	remaining := amount
+	for i := range items {
+		if !eligible[i] {
+			items[i].Discount = 0
+			continue
+		}
+		subtotal := RoundAmount(items[i].Price * float64(items[i].Quantity))
+		share := remaining
+		if i != last {
+			share = RoundAmount(amount * subtotal / discount.EligibleAmount)
+			if share > remaining {
+				share = remaining
+			}
+		}
+		remaining = RoundAmount(remaining - share)
+		items[i].Discount = share
+		discount.Lines = append(discount.Lines, LineDiscount{
+			ProductID: items[i].ProductID,
+			SKUID:     items[i].SKUID,
+			Subtotal:  subtotal,
+			Amount:    share,
+		})
+	}
+	return discount, nil
+}
+
+// RoundAmount 金额保留两位小数
+func RoundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
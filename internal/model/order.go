@@ -1,17 +1,129 @@
 // internal/model/order.go
 package model
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 订单状态
+const (
+	OrderStatusPending   = "pending"   // 待支付
+	OrderStatusPaid      = "paid"      // 已支付
+	OrderStatusShipped   = "shipped"   // 已发货
+	OrderStatusCompleted = "completed" // 已完成
+	OrderStatusCancelled = "cancelled" // 已取消
+	OrderStatusRefunded  = "refunded"  // 已全额退款
+)
+
+// orderStatusTransitions 订单状态允许的流转
+var orderStatusTransitions = map[string][]string{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusShipped:   {OrderStatusCompleted, OrderStatusRefunded},
+	OrderStatusCompleted: {OrderStatusRefunded},
+	OrderStatusCancelled: {},
+	OrderStatusRefunded:  {},
+}
+
+// PurchasedOrderStatuses 已支付且没有全额退款的订单状态 统计购买行为时只计入这些订单
+// 未支付的订单任何人都可以随意创建 计入后可以操纵推荐结果
+var PurchasedOrderStatuses = []string{OrderStatusPaid, OrderStatusShipped, OrderStatusCompleted}
+
+// IsPurchasedOrderStatus 订单是否算作实际购买
+func IsPurchasedOrderStatus(status string) bool {
+	for _, s := range PurchasedOrderStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidOrderStatus 判断是否为合法的订单状态
+func IsValidOrderStatus(status string) bool {
+	_, ok := orderStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionOrderStatus 判断订单状态能否从from流转到to
+func CanTransitionOrderStatus(from, to string) bool {
+	for _, next := range orderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
 
 // Order 订单模型
+// 索引说明: 订单列表按(created_at, id)倒序分页 按用户和状态筛选时分别走idx_orders_user_created和idx_orders_status_created
 type Order struct {
 	ID          string    `gorm:"type:varchar(32);primaryKey" json:"id"`
-	UserID      string    `gorm:"type:varchar(32)" json:"user_id"`
+	UserID      string    `gorm:"type:varchar(32);index:idx_orders_user_created,priority:1" json:"user_id"`
 	Items       string    `gorm:"type:text" json:"items"` // 存储JSON格式的商品列表
 	TotalAmount float64   `json:"total_amount"`
-	Status      string    `gorm:"size:20;default:'pending'" json:"status"` // pending, paid, shipped, completed, cancelled
-	CreatedAt   time.Time `json:"created_at"`
+	Status      string    `gorm:"size:20;default:'pending';index:idx_orders_status_created,priority:1" json:"status"` // pending, paid, shipped, completed, cancelled, refunded
+	CreatedAt   time.Time `gorm:"index:idx_orders_user_created,priority:2;index:idx_orders_status_created,priority:2;index:idx_created_at" json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// 收货、支付和物流信息 收货地址为下单时的快照 支付时间在支付成功回调时写入 物流信息在发货时写入
+	ShippingAddress *ShippingAddress `gorm:"type:text;serializer:json" json:"shipping_address,omitempty"`
+	Carrier         string           `gorm:"size:32" json:"carrier,omitempty"`
+	TrackingNumber  string           `gorm:"size:64" json:"tracking_number,omitempty"`
+	ShippedAt       *time.Time       `json:"shipped_at,omitempty"`
+	PaidAt          *time.Time       `json:"paid_at,omitempty"`
+
+	// 优惠信息 TotalAmount为优惠后的应付金额
+	CouponCode     string         `gorm:"size:32" json:"coupon_code,omitempty"`
+	DiscountAmount float64        `gorm:"type:decimal(10,2);not null;default:0" json:"discount_amount"`
+	Discount       *OrderDiscount `gorm:"type:text;serializer:json" json:"discount,omitempty"`
+
+	// 已退款金额 部分退款时订单状态不变 退完支付金额后变为refunded
+	RefundedAmount float64 `gorm:"type:decimal(10,2);not null;default:0" json:"refunded_amount"`
+}
+
+// OrderFilter 订单筛选条件 零值表示不限制
+type OrderFilter struct {
+	UserID    string
+	Status    string
+	From      time.Time // 创建时间下限（含）
+	To        time.Time // 创建时间上限（含）
+	Before    time.Time // 创建时间上限（不含） 按日期筛选时为第二天零点
+	MinAmount *float64
+	MaxAmount *float64
+	Cursor    *OrderCursor // 上一页最后一个订单的位置 为空时查询第一页
+	Limit     int
+}
+
+// OrderCursor 订单列表的游标 订单按(created_at, id)倒序排列
+// 下一页从游标之后开始查询 不使用OFFSET 翻到很深的页也只扫描一页的数据
+type OrderCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("分页游标无效")
+
+// EncodeOrderCursor 将订单位置编码为游标字符串
+func EncodeOrderCursor(order *Order) string {
+	data, _ := json.Marshal(OrderCursor{CreatedAt: order.CreatedAt, ID: order.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeOrderCursor 解析游标字符串
+func DecodeOrderCursor(cursor string) (*OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c OrderCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
 }
 
 // Inventory 库存模型
@@ -24,6 +136,15 @@ type Inventory struct {
 // OrderItem 订单商品项
 type OrderItem struct {
 	ProductID int     `json:"product_id"`
+	SKUID     int     `json:"sku_id,omitempty"`   // 多规格商品必须指定SKU 价格以SKU为准
+	SKUName   string  `json:"sku_name,omitempty"` // 下单时的规格快照 如 黑色/128GB 防止SKU修改或删除后无法追溯
 	Quantity  int     `json:"quantity"`
 	Price     float64 `json:"price"`
+	Discount  float64 `json:"discount,omitempty"` // 分摊到该项的优惠金额
+}
+
+// ValidateQuantity 校验订单项的购买数量 与购物车使用相同的上限
+// 数量为0或负数时扣库存的条件恒成立 会变成增加库存并压低订单金额
+func (i *OrderItem) ValidateQuantity() error {
+	return ValidateCartQuantity(i.Quantity)
 }
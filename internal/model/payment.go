@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// 支付状态
+const (
+	PaymentStatusPending   = "pending"   // 已创建 等待用户支付
+	PaymentStatusSucceeded = "succeeded" // 支付成功
+	PaymentStatusFailed    = "failed"    // 支付失败
+)
+
+// Payment 支付单 一个订单可以有多次支付尝试 最多一次成功
+type Payment struct {
+	ID          string     `json:"id" gorm:"type:varchar(32);primaryKey"`
+	OrderID     string     `json:"order_id" gorm:"type:varchar(32);not null;index:idx_payments_order_id"`
+	UserID      int        `json:"user_id" gorm:"not null;comment:付款用户ID"`
+	Amount      float64    `json:"amount" gorm:"type:decimal(10,2);not null;comment:支付金额"`
+	Provider    string     `json:"provider" gorm:"size:20;not null;comment:支付渠道"`
+	ProviderRef string     `json:"provider_ref" gorm:"size:64;not null;uniqueIndex:idx_payments_provider_ref;comment:支付渠道的交易号"`
+	Status      string     `json:"status" gorm:"size:20;not null;default:'pending';comment:支付状态"`
+	PayURL      string     `json:"pay_url,omitempty" gorm:"size:255;comment:支付页面地址"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"comment:支付单过期时间"`
+	PaidAt      *time.Time `json:"paid_at,omitempty" gorm:"comment:支付成功时间"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Payment) TableName() string {
+	return "payments"
+}
+
+// IsReusable 未支付且未过期的支付单可以直接返回给用户继续支付
+func (p *Payment) IsReusable(now time.Time) bool {
+	return p.Status == PaymentStatusPending && now.Before(p.ExpiresAt)
+}
+
+// PaymentEvent 支付渠道回调通知的内容
+type PaymentEvent struct {
+	ProviderRef string    `json:"provider_ref"`
+	Status      string    `json:"status"` // succeeded / failed
+	Amount      float64   `json:"amount"`
+	PaidAt      time.Time `json:"paid_at"`
+}
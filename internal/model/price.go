@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// 价格变更来源
+const (
+	PriceSourceManual   = "manual"   // 后台手动修改
+	PriceSourceImport   = "import"   // 批量导入
+	PriceSourceSchedule = "schedule" // 定时调价
+)
+
+// 定时调价状态
+const (
+	PriceScheduleStatusPending   = "pending"   // 等待生效
+	PriceScheduleStatusApplied   = "applied"   // 已生效
+	PriceScheduleStatusCancelled = "cancelled" // 已取消
+	PriceScheduleStatusFailed    = "failed"    // 生效失败 如商品已被删除
+)
+
+// PriceHistory 商品价格变更记录 只追加不修改 用于核对订单下单时的价格
+// 多规格商品按SKU定价 SKU的价格变更同样记录 以SKUID区分
+type PriceHistory struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID int       `json:"product_id" gorm:"not null;index:idx_price_histories_product_time,priority:1;comment:商品ID"`
+	SKUID     int       `json:"sku_id,omitempty" gorm:"not null;default:0;comment:SKU ID 0表示商品本身的价格"`
+	OldPrice  float64   `json:"old_price" gorm:"type:decimal(10,2);not null;comment:变更前价格"`
+	NewPrice  float64   `json:"new_price" gorm:"type:decimal(10,2);not null;comment:变更后价格"`
+	Source    string    `json:"source" gorm:"size:20;not null;comment:变更来源 manual/import/schedule"`
+	ChangedBy string    `json:"changed_by" gorm:"size:64;comment:操作人"`
+	Reason    string    `json:"reason,omitempty" gorm:"size:255;comment:变更原因"`
+	ChangedAt time.Time `json:"changed_at" gorm:"not null;index:idx_price_histories_product_time,priority:2;comment:变更时间"`
+}
+
+// TableName 指定表名
+func (PriceHistory) TableName() string {
+	return "price_histories"
+}
+
+// PriceSchedule 定时调价 到达生效时间后由后台任务修改商品价格
+type PriceSchedule struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID   int        `json:"product_id" gorm:"not null;index:idx_price_schedules_product_id;comment:商品ID"`
+	Price       float64    `json:"price" gorm:"type:decimal(10,2);not null;comment:生效后的价格"`
+	EffectiveAt time.Time  `json:"effective_at" gorm:"not null;index:idx_price_schedules_status_time,priority:2;comment:生效时间"`
+	Status      string     `json:"status" gorm:"size:20;not null;default:'pending';index:idx_price_schedules_status_time,priority:1;comment:状态"`
+	CreatedBy   string     `json:"created_by" gorm:"size:64;comment:创建人"`
+	Reason      string     `json:"reason,omitempty" gorm:"size:255;comment:调价原因"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty" gorm:"comment:实际生效时间"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (PriceSchedule) TableName() string {
+	return "price_schedules"
+}
+
+// PriceAt 根据价格变更记录计算某一时刻的价格
+// histories需要按变更时间升序排列 current为商品当前价格
+// 时刻之前有变更时取最后一次变更后的价格 否则取第一次变更前的价格 没有任何变更时就是当前价格
+func PriceAt(histories []PriceHistory, at time.Time, current float64) float64 {
+	price := current
+	for i := len(histories) - 1; i >= 0; i-- {
+		if !histories[i].ChangedAt.After(at) {
+			return histories[i].NewPrice
+		}
+		price = histories[i].OldPrice
+	}
+	return price
+}
@@ -2,23 +2,115 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// 商品状态
+const (
+	ProductStatusDraft        = "draft"        // 草稿 未上架
+	ProductStatusActive       = "active"       // 上架中 可以展示和下单
+	ProductStatusInactive     = "inactive"     // 已下架 可以重新上架
+	ProductStatusDiscontinued = "discontinued" // 已停售 终态
 )
 
+// productStatusTransitions 商品状态允许的流转
+var productStatusTransitions = map[string][]string{
+	ProductStatusDraft:        {ProductStatusActive, ProductStatusDiscontinued},
+	ProductStatusActive:       {ProductStatusInactive, ProductStatusDiscontinued},
+	ProductStatusInactive:     {ProductStatusActive, ProductStatusDiscontinued},
+	ProductStatusDiscontinued: {},
+}
+
 // Product 商品模型
+// 索引说明: 列表默认只查上架商品 按分类筛选时走(category,status)联合索引 价格/销量/创建时间用于排序
 type Product struct {
-	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name        string    `json:"name" gorm:"size:100;not null;comment:商品名称"`
-	Description string    `json:"description" gorm:"size:500;comment:商品描述"`
-	Price       float64   `json:"price" gorm:"type:decimal(10,2);not null;comment:商品价格"`
-	Stock       int       `json:"stock" gorm:"not null;default:0;comment:库存数量"`
-	Category    string    `json:"category" gorm:"size:50;comment:商品分类"`
-	Status      string    `json:"status" gorm:"size:20;default:'active';comment:商品状态"`
-	Version     int       `json:"version" gorm:"default:0;comment:乐观锁版本号"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID          int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	ExternalSKU *string        `json:"external_sku,omitempty" gorm:"size:64;uniqueIndex:idx_products_external_sku;comment:外部系统商品编码 批量导入时按此字段更新"`
+	Name        string         `json:"name" gorm:"size:100;not null;comment:商品名称"`
+	Description string         `json:"description" gorm:"size:500;comment:商品描述"`
+	Price       float64        `json:"price" gorm:"type:decimal(10,2);not null;index:idx_products_price;comment:商品价格"`
+	Stock       int            `json:"stock" gorm:"not null;default:0;comment:库存数量"`
+	Sales       int            `json:"sales" gorm:"not null;default:0;index:idx_products_sales;comment:销量"`
+	CategoryID  int            `json:"category_id" gorm:"not null;default:0;index:idx_products_category_id;comment:分类ID"`
+	Category    string         `json:"category" gorm:"size:50;index:idx_products_category_status,priority:1;comment:商品分类名称 与分类ID同步"`
+	Status      string         `json:"status" gorm:"size:20;default:'active';index:idx_products_status;index:idx_products_category_status,priority:2;comment:商品状态"`
+	Version     int            `json:"version" gorm:"default:0;comment:乐观锁版本号"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"autoCreateTime;index:idx_products_created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index;comment:软删除时间"`
+
+	SKUs     []SKU          `json:"skus,omitempty" gorm:"foreignKey:ProductID"` // 规格商品的SKU列表 只在查询商品详情时加载
+	Variants *VariantMatrix `json:"variants,omitempty" gorm:"-"`                // 根据SKU生成的规格矩阵
 }
 
 // TableName 指定表名
 func (Product) TableName() string {
 	return "products"
 }
+
+// ProductFilter 商品筛选条件
+type ProductFilter struct {
+	Category    string   // 分类名称
+	CategoryID  int      // 分类ID 包含所有子孙分类的商品
+	CategoryIDs []int    // 由CategoryID展开后的分类ID列表 由service层填充
+	Status      string   // 状态 为空时只查上架中的商品
+	MinPrice    *float64 // 最低价格
+	MaxPrice    *float64 // 最高价格
+	InStock     bool     // 只查有库存的商品
+	Keyword     string   // 关键词 匹配名称和描述
+	SortBy      string   // 排序字段 price / created_at / sales 为空时按ID排序
+	Desc        bool     // 是否倒序
+	Page        int
+	PageSize    int
+}
+
+// IsValidProductStatus 判断是否为合法的商品状态
+func IsValidProductStatus(status string) bool {
+	_, ok := productStatusTransitions[status]
+	return ok
+}
+
+// ResolveImportStatus 批量导入时决定商品状态 current为空表示新商品
+// requested为空时新商品默认上架 已有商品保持原状态 已有商品修改状态时同样要符合状态流转规则
+func ResolveImportStatus(current, requested string) (string, bool) {
+	switch {
+	case requested == "" && current == "":
+		return ProductStatusActive, true
+	case requested == "":
+		return current, true
+	case current == "" || current == requested:
+		return requested, true
+	}
+	return requested, CanTransitionProductStatus(current, requested)
+}
+
+// CanTransitionProductStatus 判断商品状态能否从from流转到to
+func CanTransitionProductStatus(from, to string) bool {
+	for _, next := range productStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSKUs 是否为多规格商品 多规格商品下单时必须指定SKU
+func (p *Product) HasSKUs() bool {
+	return len(p.SKUs) > 0
+}
+
+// FindSKU 在商品的SKU中查找指定ID
+func (p *Product) FindSKU(skuID int) *SKU {
+	for i := range p.SKUs {
+		if p.SKUs[i].ID == skuID {
+			return &p.SKUs[i]
+		}
+	}
+	return nil
+}
+
+// IsPurchasable 商品是否可以下单
+func (p *Product) IsPurchasable() bool {
+	return p.Status == ProductStatusActive
+}
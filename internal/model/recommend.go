@@ -0,0 +1,134 @@
+package model
+
+import "sort"
+
+// 订单中商品种类超过该数量时不统计共同购买 避免批发类订单产生大量无意义的组合
+const MaxCoPurchaseItemsPerOrder = 50
+
+// ScoredProduct 带推荐分数的商品ID
+type ScoredProduct struct {
+	ProductID int     `json:"product_id"`
+	Score     float64 `json:"score"`
+}
+
+// CoPurchaseCounter 统计商品两两出现在同一订单中的次数 以及每个商品的销量
+// 不是并发安全的 由重建任务单独使用
+type CoPurchaseCounter struct {
+	pairs map[int]map[int]int // 商品ID -> 同单购买的商品ID -> 同单次数
+	sales map[int]int         // 商品ID -> 累计购买数量
+}
+
+// NewCoPurchaseCounter 创建共同购买计数器
+func NewCoPurchaseCounter() *CoPurchaseCounter {
+	return &CoPurchaseCounter{
+		pairs: make(map[int]map[int]int),
+		sales: make(map[int]int),
+	}
+}
+
+// CountOrder 统计一个订单 只计入已支付的订单 返回是否计入
+func (c *CoPurchaseCounter) CountOrder(status string, items []OrderItem) bool {
+	if !IsPurchasedOrderStatus(status) {
+		return false
+	}
+	c.AddOrder(items)
+	return true
+}
+
+// AddOrder 统计一个订单 同一商品的多个SKU或多行只按一次计入共同购买
+func (c *CoPurchaseCounter) AddOrder(items []OrderItem) {
+	seen := make(map[int]bool, len(items))
+	products := make([]int, 0, len(items))
+	for _, item := range items {
+		if item.ProductID <= 0 {
+			continue
+		}
+		c.sales[item.ProductID] += item.Quantity
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			products = append(products, item.ProductID)
+		}
+	}
+	if len(products) > MaxCoPurchaseItemsPerOrder {
+		return
+	}
+
+	for i, a := range products {
+		for _, b := range products[i+1:] {
+			c.incr(a, b)
+			c.incr(b, a)
+		}
+	}
+}
+
+func (c *CoPurchaseCounter) incr(a, b int) {
+	related, ok := c.pairs[a]
+	if !ok {
+		related = make(map[int]int)
+		c.pairs[a] = related
+	}
+	related[b]++
+}
+
+// Products 有共同购买记录的商品ID
+func (c *CoPurchaseCounter) Products() []int {
+	ids := make([]int, 0, len(c.pairs))
+	for id := range c.pairs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Top 与指定商品同单购买次数最多的limit个商品 次数相同时按商品ID升序
+func (c *CoPurchaseCounter) Top(productID, limit int) []ScoredProduct {
+	related := c.pairs[productID]
+	result := make([]ScoredProduct, 0, len(related))
+	for id, count := range related {
+		result = append(result, ScoredProduct{ProductID: id, Score: float64(count)})
+	}
+	return topScored(result, limit)
+}
+
+// BestSellers 购买数量最多的limit个商品
+func (c *CoPurchaseCounter) BestSellers(limit int) []ScoredProduct {
+	result := make([]ScoredProduct, 0, len(c.sales))
+	for id, quantity := range c.sales {
+		if quantity > 0 {
+			result = append(result, ScoredProduct{ProductID: id, Score: float64(quantity)})
+		}
+	}
+	return topScored(result, limit)
+}
+
+// MergeScoredProducts 合并多个推荐列表 同一商品的分数累加 跳过exclude中的商品
+// 用于根据用户买过的多个商品汇总推荐
+func MergeScoredProducts(lists [][]ScoredProduct, exclude map[int]bool, limit int) []ScoredProduct {
+	scores := make(map[int]float64)
+	for _, list := range lists {
+		for _, sp := range list {
+			if !exclude[sp.ProductID] {
+				scores[sp.ProductID] += sp.Score
+			}
+		}
+	}
+	result := make([]ScoredProduct, 0, len(scores))
+	for id, score := range scores {
+		result = append(result, ScoredProduct{ProductID: id, Score: score})
+	}
+	return topScored(result, limit)
+}
+
+// topScored 按分数降序排序并截取前limit个 limit<=0时不截取
+func topScored(list []ScoredProduct, limit int) []ScoredProduct {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].ProductID < list[j].ProductID
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
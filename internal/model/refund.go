@@ -0,0 +1,158 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// 退款状态
+const (
+	RefundStatusPending   = "pending"   // 已创建 等待支付渠道处理
+	RefundStatusSucceeded = "succeeded" // 退款成功 库存已退回
+	RefundStatusFailed    = "failed"    // 退款失败 不计入已退金额
+)
+
+var (
+	ErrRefundNothing        = errors.New("没有可以退款的金额")
+	ErrRefundLineInvalid    = errors.New("订单商品行不存在")
+	ErrRefundLineDuplicated = errors.New("同一商品行不能重复退款")
+	ErrRefundQuantity       = errors.New("退款数量超过可退数量")
+	ErrRefundAmount         = errors.New("退款金额超过该商品行的可退金额")
+	ErrRefundExceedsPaid    = errors.New("退款总额超过实际支付金额")
+)
+
+// Refund 退款单 一个订单可以多次部分退款 所有未失败的退款合计不超过支付金额
+type Refund struct {
+	ID          string       `json:"id" gorm:"type:varchar(32);primaryKey"`
+	OrderID     string       `json:"order_id" gorm:"type:varchar(32);not null;index:idx_refunds_order_id"`
+	PaymentID   string       `json:"payment_id" gorm:"type:varchar(32);not null;comment:原支付单ID"`
+	Amount      float64      `json:"amount" gorm:"type:decimal(10,2);not null;comment:退款金额"`
+	Lines       []RefundLine `json:"lines" gorm:"type:text;serializer:json;comment:退款的商品行"`
+	Reason      string       `json:"reason,omitempty" gorm:"size:255;comment:退款原因"`
+	Status      string       `json:"status" gorm:"size:20;not null;default:'pending';comment:退款状态"`
+	ProviderRef string       `json:"provider_ref,omitempty" gorm:"size:64;comment:支付渠道的退款交易号"`
+	OperatorID  int          `json:"operator_id" gorm:"comment:发起退款的管理员ID"`
+	RefundedAt  *time.Time   `json:"refunded_at,omitempty" gorm:"comment:退款成功时间"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Refund) TableName() string {
+	return "refunds"
+}
+
+// RefundLine 退款单中的一个商品行 Quantity为退回库存的数量 只退差价时为0
+type RefundLine struct {
+	Line      int     `json:"line"` // 订单商品行的下标 从0开始
+	ProductID int     `json:"product_id"`
+	SKUID     int     `json:"sku_id,omitempty"`
+	Quantity  int     `json:"quantity"`
+	Amount    float64 `json:"amount"`
+}
+
+// RefundLineRequest 申请退款的商品行
+// 只传数量时按该行实付金额折算 只传金额时为退差价 不退库存
+type RefundLineRequest struct {
+	Line     int     `json:"line"`
+	Quantity int     `json:"quantity"`
+	Amount   float64 `json:"amount"`
+}
+
+// RefundableOrderStatuses 可以退款的订单状态
+var RefundableOrderStatuses = []string{OrderStatusPaid, OrderStatusShipped, OrderStatusCompleted}
+
+// IsRefundableOrderStatus 已支付、已发货、已完成的订单可以退款
+func IsRefundableOrderStatus(status string) bool {
+	for _, s := range RefundableOrderStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LinePaidAmount 订单商品行的实付金额 即小计减去分摊的优惠
+func LinePaidAmount(item OrderItem) float64 {
+	return RoundAmount(item.Price*float64(item.Quantity) - item.Discount)
+}
+
+// PlanRefund 根据订单商品和之前的退款计算本次退款的商品行和金额
+// previous为之前所有未失败的退款 requested为空时退还剩余的全部金额和数量
+func PlanRefund(items []OrderItem, previous []Refund, requested []RefundLineRequest, paid float64) ([]RefundLine, float64, error) {
+	// 1. 统计每一行已退的数量和金额
+	refundedQty := make([]int, len(items))
+	refundedAmount := make([]float64, len(items))
+	refundedTotal := 0.0
+	for _, refund := range previous {
+		refundedTotal += refund.Amount
+		for _, line := range refund.Lines {
+			if line.Line >= 0 && line.Line < len(items) {
+				refundedQty[line.Line] += line.Quantity
+				refundedAmount[line.Line] += line.Amount
+			}
+		}
+	}
+
+	// 2. 全额退款 退还每一行剩余的数量和金额
+	var lines []RefundLine
+	if len(requested) == 0 {
+		for i, item := range items {
+			qty := item.Quantity - refundedQty[i]
+			amount := RoundAmount(LinePaidAmount(item) - refundedAmount[i])
+			if qty <= 0 && amount <= 0 {
+				continue
+			}
+			lines = append(lines, RefundLine{Line: i, ProductID: item.ProductID, SKUID: item.SKUID, Quantity: max(qty, 0), Amount: max(amount, 0)})
+		}
+	}
+
+	// 3. 部分退款 逐行校验可退数量和金额
+	seen := make(map[int]bool, len(requested))
+	for _, req := range requested {
+		if req.Line < 0 || req.Line >= len(items) {
+			return nil, 0, ErrRefundLineInvalid
+		}
+		if seen[req.Line] {
+			return nil, 0, ErrRefundLineDuplicated
+		}
+		seen[req.Line] = true
+		if req.Quantity < 0 || req.Amount < 0 || (req.Quantity == 0 && req.Amount == 0) {
+			return nil, 0, ErrRefundNothing
+		}
+
+		item := items[req.Line]
+		linePaid := LinePaidAmount(item)
+		remainingQty := item.Quantity - refundedQty[req.Line]
+		remainingAmount := RoundAmount(linePaid - refundedAmount[req.Line])
+		if req.Quantity > remainingQty {
+			return nil, 0, ErrRefundQuantity
+		}
+		amount := RoundAmount(req.Amount)
+		if amount == 0 {
+			// 按数量折算 退完最后一件时退还剩余金额 避免分摊的尾差
+			amount = RoundAmount(linePaid * float64(req.Quantity) / float64(item.Quantity))
+			if req.Quantity == remainingQty || amount > remainingAmount {
+				amount = remainingAmount
+			}
+		}
+		if amount > remainingAmount {
+			return nil, 0, ErrRefundAmount
+		}
+		lines = append(lines, RefundLine{Line: req.Line, ProductID: item.ProductID, SKUID: item.SKUID, Quantity: req.Quantity, Amount: amount})
+	}
+
+	// 4. 校验退款总额不超过支付金额
+	total := 0.0
+	for _, line := range lines {
+		total += line.Amount
+	}
+	total = RoundAmount(total)
+	if total <= 0 {
+		return nil, 0, ErrRefundNothing
+	}
+	if RoundAmount(refundedTotal+total) > RoundAmount(paid) {
+		return nil, 0, ErrRefundExceedsPaid
+	}
+	return lines, total, nil
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSKURequired      = errors.New("请选择商品规格")
+	ErrSKUNotInProduct  = errors.New("SKU不属于该商品")
+	ErrProductHasNoSKUs = errors.New("商品没有规格")
+)
+
+// SKU 商品的可售规格 如 iPhone 15 黑色/128GB
+// 每个SKU有独立的价格和库存 同一商品下所有SKU的规格维度必须一致
+type SKU struct {
+	ID         int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID  int            `json:"product_id" gorm:"not null;index:idx_skus_product_id;comment:所属商品ID"`
+	Code       *string        `json:"code" gorm:"size:64;uniqueIndex:idx_skus_code;comment:商家SKU编码 未填写时为NULL"`
+	Attributes []SKUAttribute `json:"attributes" gorm:"type:text;serializer:json;comment:规格属性"`
+	Price      float64        `json:"price" gorm:"type:decimal(10,2);not null;comment:SKU价格"`
+	Stock      int            `json:"stock" gorm:"not null;default:0;comment:SKU库存"`
+	Version    int            `json:"version" gorm:"default:0;comment:乐观锁版本号"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (SKU) TableName() string {
+	return "product_skus"
+}
+
+// SKUAttribute 规格属性 使用有序列表而不是map 保证"颜色/容量"的展示顺序
+type SKUAttribute struct {
+	Name  string `json:"name"`  // 规格维度 如 颜色
+	Value string `json:"value"` // 规格值 如 黑色
+}
+
+// VariantKey 规格组合的唯一标识 按属性顺序用"/"拼接规格值 如 黑色/128GB
+func (s *SKU) VariantKey() string {
+	values := make([]string, len(s.Attributes))
+	for i, attr := range s.Attributes {
+		values[i] = attr.Value
+	}
+	return strings.Join(values, "/")
+}
+
+// dimensionNames 规格维度名称 用于校验同一商品下的SKU维度是否一致
+func (s *SKU) dimensionNames() string {
+	names := make([]string, len(s.Attributes))
+	for i, attr := range s.Attributes {
+		names[i] = attr.Name
+	}
+	return strings.Join(names, "/")
+}
+
+// ValidateSKUs 校验同一商品下的SKU 价格、库存合法 规格维度一致且组合不重复
+func ValidateSKUs(skus []SKU) error {
+	if len(skus) == 0 {
+		return nil
+	}
+	dimensions := skus[0].dimensionNames()
+	seen := make(map[string]bool, len(skus))
+	for i := range skus {
+		sku := &skus[i]
+		if sku.Price <= 0 {
+			return errors.New("SKU价格必须大于0")
+		}
+		if sku.Stock < 0 {
+			return errors.New("SKU库存不能为负数")
+		}
+		if len(sku.Attributes) == 0 {
+			return errors.New("SKU规格属性不能为空")
+		}
+		names := make(map[string]bool, len(sku.Attributes))
+		for _, attr := range sku.Attributes {
+			if attr.Name == "" || attr.Value == "" {
+				return errors.New("SKU规格名称和规格值不能为空")
+			}
+			if names[attr.Name] {
+				return fmt.Errorf("SKU规格维度重复: %s", attr.Name)
+			}
+			names[attr.Name] = true
+		}
+		if sku.dimensionNames() != dimensions {
+			return fmt.Errorf("SKU规格维度不一致: %s 与 %s", sku.dimensionNames(), dimensions)
+		}
+		key := sku.VariantKey()
+		if seen[key] {
+			return fmt.Errorf("SKU规格组合重复: %s", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// VariantDimension 规格维度及其所有可选值
+type VariantDimension struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// VariantCell 规格矩阵中的一个组合
+type VariantCell struct {
+	SKUID     int     `json:"sku_id"`
+	Price     float64 `json:"price"`
+	Stock     int     `json:"stock"`
+	Available bool    `json:"available"`
+}
+
+// VariantMatrix 规格矩阵 前端根据维度渲染选择器 再用选中值拼出的key查找对应SKU
+type VariantMatrix struct {
+	Dimensions []VariantDimension     `json:"dimensions"`
+	Variants   map[string]VariantCell `json:"variants"` // key为VariantKey 如 黑色/128GB
+}
+
+// BuildVariantMatrix 根据SKU列表生成规格矩阵 维度和规格值按首次出现的顺序排列
+func BuildVariantMatrix(skus []SKU) *VariantMatrix {
+	if len(skus) == 0 {
+		return nil
+	}
+
+	matrix := &VariantMatrix{Variants: make(map[string]VariantCell, len(skus))}
+	dimIndex := make(map[string]int)
+	seenValue := make(map[string]bool)
+	for i := range skus {
+		sku := &skus[i]
+		for _, attr := range sku.Attributes {
+			idx, ok := dimIndex[attr.Name]
+			if !ok {
+				idx = len(matrix.Dimensions)
+				dimIndex[attr.Name] = idx
+				matrix.Dimensions = append(matrix.Dimensions, VariantDimension{Name: attr.Name})
+			}
+			if valueKey := attr.Name + "=" + attr.Value; !seenValue[valueKey] {
+				seenValue[valueKey] = true
+				matrix.Dimensions[idx].Values = append(matrix.Dimensions[idx].Values, attr.Value)
+			}
+		}
+		matrix.Variants[sku.VariantKey()] = VariantCell{
+			SKUID:     sku.ID,
+			Price:     sku.Price,
+			Stock:     sku.Stock,
+			Available: sku.Stock > 0,
+		}
+	}
+	return matrix
+}
+
+// NormalizeSKUCode 去掉编码首尾空白 空编码返回nil
+// 编码上有唯一索引 未填写的编码必须存为NULL 存空字符串时第二个没有编码的SKU会违反唯一约束
+func NormalizeSKUCode(code *string) *string {
+	if code == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*code)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// PriceOrderItem 以商品的当前价格为订单项定价 不信任请求中的价格
+// 多规格商品必须指定所属的SKU 价格和规格快照以SKU为准
+func (p *Product) PriceOrderItem(item *OrderItem) error {
+	if !p.HasSKUs() {
+		if item.SKUID != 0 {
+			return ErrProductHasNoSKUs
+		}
+		item.Price = p.Price
+		item.SKUName = ""
+		return nil
+	}
+	if item.SKUID == 0 {
+		return ErrSKURequired
+	}
+	sku := p.FindSKU(item.SKUID)
+	if sku == nil {
+		return ErrSKUNotInProduct
+	}
+	item.Price = sku.Price
+	item.SKUName = sku.VariantKey()
+	return nil
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"time"
+)
+
+// 用户角色
+const (
+	UserRoleCustomer = "customer" // 普通用户
+	UserRoleAdmin    = "admin"    // 管理员 可以管理商品和用户
+)
+
+// 用户状态
+const (
+	UserStatusActive   = "active"   // 正常
+	UserStatusDisabled = "disabled" // 已禁用 不能登录和下单
+)
+
+// 密码长度限制 bcrypt只使用前72个字节
+const (
+	MinPasswordLength = 6
+	MaxPasswordLength = 72
+)
+
+// 用户名 3-32位字母、数字或下划线
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+// User 用户模型
+type User struct {
+	ID           int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Username     string     `json:"username" gorm:"size:32;not null;uniqueIndex:idx_users_username;comment:登录用户名"`
+	PasswordHash string     `json:"-" gorm:"size:100;not null;comment:bcrypt密码哈希"`
+	Nickname     string     `json:"nickname" gorm:"size:64;comment:昵称"`
+	Email        string     `json:"email,omitempty" gorm:"size:128;comment:邮箱"`
+	Phone        string     `json:"phone,omitempty" gorm:"size:20;comment:手机号"`
+	Role         string     `json:"role" gorm:"size:20;not null;default:'customer';comment:角色"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:'active';index:idx_users_status;comment:状态"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty" gorm:"comment:最后登录时间"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (User) TableName() string {
+	return "users"
+}
+
+// UserFilter 用户筛选条件
+type UserFilter struct {
+	Role     string // 角色 为空时不限制
+	Status   string // 状态 为空时不限制
+	Keyword  string // 关键词 匹配用户名、昵称、邮箱和手机号
+	Page     int
+	PageSize int
+}
+
+// IsActive 用户是否可以登录和下单
+func (u *User) IsActive() bool {
+	return u.Status == UserStatusActive
+}
+
+// IsAdmin 是否为管理员
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// IsValidUserStatus 判断是否为合法的用户状态
+func IsValidUserStatus(status string) bool {
+	return status == UserStatusActive || status == UserStatusDisabled
+}
+
+// IsValidUserRole 判断是否为合法的用户角色
+func IsValidUserRole(role string) bool {
+	return role == UserRoleCustomer || role == UserRoleAdmin
+}
+
+// ValidateUsername 校验用户名格式
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("用户名必须是3-32位字母、数字或下划线")
+	}
+	return nil
+}
+
+// ValidatePassword 校验密码长度
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return errors.New("密码长度必须在6-72个字符之间")
+	}
+	return nil
+}
+
+// ValidateEmail 校验邮箱格式 空字符串表示不填
+func ValidateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("邮箱格式错误")
+	}
+	return nil
+}
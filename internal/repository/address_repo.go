@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// 收货地址相关操作 所有查询都带上用户ID 防止操作其他用户的地址
+type AddressRepo struct {
+	db *gorm.DB
+}
+
+func NewAddressRepo(db *gorm.DB) *AddressRepo {
+	return &AddressRepo{db: db}
+}
+
+// Create 新增收货地址 设为默认时在同一事务中取消原来的默认地址
+func (r *AddressRepo) Create(ctx context.Context, address *model.Address) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, address.UserID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(address).Error
+	})
+}
+
+// GetByID 查询用户的某个收货地址
+func (r *AddressRepo) GetByID(ctx context.Context, userID, id int) (*model.Address, error) {
+	var address model.Address
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&address).Error
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// GetDefault 查询用户的默认收货地址
+func (r *AddressRepo) GetDefault(ctx context.Context, userID int) (*model.Address, error) {
+	var address model.Address
+	err := r.db.WithContext(ctx).Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// ListByUser 查询用户的所有收货地址 默认地址排在最前面
+func (r *AddressRepo) ListByUser(ctx context.Context, userID int) ([]model.Address, error) {
+	var addresses []model.Address
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("is_default DESC, updated_at DESC, id DESC").Find(&addresses).Error
+	return addresses, err
+}
+
+// CountByUser 统计用户的收货地址数量
+func (r *AddressRepo) CountByUser(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Address{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// Update 更新收货地址的全部字段 设为默认时在同一事务中取消原来的默认地址
+func (r *AddressRepo) Update(ctx context.Context, address *model.Address) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, address.UserID); err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&model.Address{}).
+			Where("id = ? AND user_id = ?", address.ID, address.UserID).
+			Select("receiver", "phone", "province", "city", "district", "detail", "is_default").
+			Updates(address)
+		return result.Error
+	})
+}
+
+// SetDefault 设置默认收货地址
+func (r *AddressRepo) SetDefault(ctx context.Context, userID, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultAddress(tx, userID); err != nil {
+			return err
+		}
+		result := tx.Model(&model.Address{}).Where("id = ? AND user_id = ?", id, userID).Update("is_default", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Delete 删除收货地址 删除的是默认地址时把最近修改的地址设为默认
+func (r *AddressRepo) Delete(ctx context.Context, userID, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var address model.Address
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&address).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			return nil
+		}
+
+		var next model.Address
+		err := tx.Where("user_id = ?", userID).Order("updated_at DESC, id DESC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+// clearDefaultAddress 取消用户的默认地址
+func clearDefaultAddress(tx *gorm.DB, userID int) error {
+	return tx.Model(&model.Address{}).
+		Where("user_id = ? AND is_default = ?", userID, true).
+		Update("is_default", false).Error
+}
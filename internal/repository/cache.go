@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCachedNotFound 缓存中记录了"数据不存在" 调用方无需再查数据库
+var ErrCachedNotFound = errors.New("缓存记录数据不存在")
+
+// 空值缓存 防止不存在的ID反复穿透到数据库
+// 过期时间较短 避免数据创建后长时间查不到
+const (
+	notFoundPlaceholder   = "null"
+	notFoundCacheTTL      = 30 * time.Second
+	notFoundCacheJitter   = 10 * time.Second
+	bloomRebuildBatchSize = 1000
+)
+
+// 布隆过滤器的redis key
+const (
+	orderBloomKey   = "bloom:order"
+	productBloomKey = "bloom:product"
+)
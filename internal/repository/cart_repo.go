@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CartTTL 购物车的过期时间 每次修改购物车后重新计算
+const CartTTL = 30 * 24 * time.Hour
+
+// CartRepo 购物车 每个用户一个Redis hash field为购物车行ID value为商品行JSON
+type CartRepo struct {
+	redisClient *redis.Client
+}
+
+func NewCartRepo(redisClient *redis.Client) *CartRepo {
+	return &CartRepo{redisClient: redisClient}
+}
+
+// cartKey 用户购物车的key
+// 格式: cart:{user_id}
+func cartKey(userID int) string {
+	return fmt.Sprintf("cart:%d", userID)
+}
+
+// GetAll 查询购物车中的所有商品 按加入时间排序
+func (r *CartRepo) GetAll(ctx context.Context, userID int) ([]model.CartItem, error) {
+	values, err := r.redisClient.HGetAll(ctx, cartKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.CartItem, 0, len(values))
+	for field, value := range values {
+		var item model.CartItem
+		if err := json.Unmarshal([]byte(value), &item); err != nil {
+			// 损坏的数据直接跳过 不影响其他商品
+			r.redisClient.HDel(ctx, cartKey(userID), field)
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].AddedAt.Equal(items[j].AddedAt) {
+			return items[i].ID() < items[j].ID()
+		}
+		return items[i].AddedAt.Before(items[j].AddedAt)
+	})
+	return items, nil
+}
+
+// Get 查询购物车中的一行 不存在时返回nil
+func (r *CartRepo) Get(ctx context.Context, userID int, itemID string) (*model.CartItem, error) {
+	value, err := r.redisClient.HGet(ctx, cartKey(userID), itemID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var item model.CartItem
+	if err := json.Unmarshal([]byte(value), &item); err != nil {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Save 写入购物车中的一行 并刷新购物车的过期时间
+func (r *CartRepo) Save(ctx context.Context, userID int, item *model.CartItem) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	key := cartKey(userID)
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, item.ID(), value)
+		pipe.Expire(ctx, key, CartTTL)
+		return nil
+	})
+	return err
+}
+
+// AddItem 原子地把商品加入购物车 并刷新购物车的过期时间
+// 已在购物车中的累加数量并重新勾选 累加后超过maxQuantity返回ErrCartQuantityExceeded
+// 新商品行数达到maxLines时返回ErrCartFull 返回写入后的购物车行
+func (r *CartRepo) AddItem(ctx context.Context, userID int, item *model.CartItem, maxLines, maxQuantity int) (*model.CartItem, error) {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	// 读取、累加和写入在一个脚本中完成 并发加购不会丢失数量 损坏的旧数据按新商品处理
+	script := `
+		local item
+		local current = redis.call("hget", KEYS[1], ARGV[1])
+		if current then
+			local ok, decoded = pcall(cjson.decode, current)
+			if ok then
+				item = decoded
+			end
+		end
+		if item then
+			item.quantity = item.quantity + tonumber(ARGV[3])
+			if item.quantity > tonumber(ARGV[5]) then
+				return -2
+			end
+			item.selected = true
+		else
+			if not current and redis.call("hlen", KEYS[1]) >= tonumber(ARGV[4]) then
+				return -1
+			end
+			item = cjson.decode(ARGV[2])
+		end
+		local encoded = cjson.encode(item)
+		redis.call("hset", KEYS[1], ARGV[1], encoded)
+		redis.call("expire", KEYS[1], ARGV[6])
+		return encoded
+	`
+	result, err := r.redisClient.Eval(ctx, script, []string{cartKey(userID)},
+		item.ID(), value, item.Quantity, maxLines, maxQuantity, int(CartTTL.Seconds())).Result()
+	if err != nil {
+		return nil, err
+	}
+	switch result {
+	case int64(cartAddFull):
+		return nil, model.ErrCartFull
+	case int64(cartAddQuantityExceeded):
+		return nil, model.ErrCartQuantityExceeded
+	}
+
+	encoded, _ := result.(string)
+	var saved model.CartItem
+	if err := json.Unmarshal([]byte(encoded), &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// AddItem脚本的返回值
+const (
+	cartAddFull             = -1
+	cartAddQuantityExceeded = -2
+)
+
+// Remove 删除购物车中的商品 返回实际删除的行数
+func (r *CartRepo) Remove(ctx context.Context, userID int, itemIDs ...string) (int64, error) {
+	if len(itemIDs) == 0 {
+		return 0, nil
+	}
+	return r.redisClient.HDel(ctx, cartKey(userID), itemIDs...).Result()
+}
+
+// Clear 清空购物车
+func (r *CartRepo) Clear(ctx context.Context, userID int) error {
+	return r.redisClient.Del(ctx, cartKey(userID)).Err()
+}
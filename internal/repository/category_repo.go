@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var ErrCategoryCycle = errors.New("不能将分类移动到自身或其子分类下")
+
+// 分类相关操作
+type CategoryRepo struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepo(db *gorm.DB) *CategoryRepo {
+	return &CategoryRepo{db: db}
+}
+
+// Create 创建分类 先插入拿到ID 再根据父分类生成物化路径
+func (r *CategoryRepo) Create(ctx context.Context, category *model.Category, parent *model.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		category.ParentID, category.Level = 0, 1
+		parentPath := ""
+		if parent != nil {
+			category.ParentID, category.Level = parent.ID, parent.Level+1
+			parentPath = parent.Path
+		}
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		category.Path = model.CategoryPath(parentPath, category.ID)
+		return tx.Model(category).Update("path", category.Path).Error
+	})
+}
+
+// GetByID 根据ID查询分类
+func (r *CategoryRepo) GetByID(ctx context.Context, id int) (*model.Category, error) {
+	var category model.Category
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// List 查询所有分类 按层级和同级排序返回 可以直接组装为树
+func (r *CategoryRepo) List(ctx context.Context) ([]model.Category, error) {
+	var categories []model.Category
+	err := r.db.WithContext(ctx).Order("level, sort_order, id").Find(&categories).Error
+	return categories, err
+}
+
+// ListSubtree 查询分类及其所有子孙分类
+func (r *CategoryRepo) ListSubtree(ctx context.Context, category *model.Category) ([]model.Category, error) {
+	var categories []model.Category
+	err := r.db.WithContext(ctx).Where("path LIKE ?", escapeLike(category.Path)+"%").
+		Order("level, sort_order, id").Find(&categories).Error
+	return categories, err
+}
+
+// SubtreeIDs 查询分类及其所有子孙分类的ID
+func (r *CategoryRepo) SubtreeIDs(ctx context.Context, category *model.Category) ([]int, error) {
+	var ids []int
+	err := r.db.WithContext(ctx).Model(&model.Category{}).
+		Where("path LIKE ?", escapeLike(category.Path)+"%").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// MaxSubtreeLevel 子树中最深的层级 移动分类时用于校验层级上限
+func (r *CategoryRepo) MaxSubtreeLevel(ctx context.Context, category *model.Category) (int, error) {
+	var level int
+	err := r.db.WithContext(ctx).Model(&model.Category{}).
+		Where("path LIKE ?", escapeLike(category.Path)+"%").
+		Select("COALESCE(MAX(level), 0)").Scan(&level).Error
+	return level, err
+}
+
+// Update 更新分类名称、排序等基础字段
+func (r *CategoryRepo) Update(ctx context.Context, id int, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&model.Category{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Move 将分类（连同子孙分类）移动到新的父分类下 newParent为nil表示移动为一级分类
+// 在一个事务中批量替换整棵子树的路径前缀并调整层级
+func (r *CategoryRepo) Move(ctx context.Context, category *model.Category, newParent *model.Category) error {
+	parentID, parentPath, parentLevel := 0, "", 0
+	if newParent != nil {
+		if len(newParent.Path) >= len(category.Path) && newParent.Path[:len(category.Path)] == category.Path {
+			return ErrCategoryCycle
+		}
+		parentID, parentPath, parentLevel = newParent.ID, newParent.Path, newParent.Level
+	}
+
+	oldPath := category.Path
+	newPath := model.CategoryPath(parentPath, category.ID)
+	levelDelta := parentLevel + 1 - category.Level
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Category{}).Where("id = ?", category.ID).
+			Update("parent_id", parentID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Category{}).Where("path LIKE ?", escapeLike(oldPath)+"%").
+			Updates(map[string]interface{}{
+				"path":  gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", newPath, len(oldPath)+1),
+				"level": gorm.Expr("level + ?", levelDelta),
+			}).Error
+	})
+}
+
+// Delete 删除分类
+func (r *CategoryRepo) Delete(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Category{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountChildren 统计直接子分类数量
+func (r *CategoryRepo) CountChildren(ctx context.Context, id int) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// GetPaths 批量查询分类的物化路径 不存在的分类不在结果中
+func (r *CategoryRepo) GetPaths(ctx context.Context, ids []int) (map[int]string, error) {
+	paths := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return paths, nil
+	}
+	var categories []model.Category
+	if err := r.db.WithContext(ctx).Select("id", "path").Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		paths[category.ID] = category.Path
+	}
+	return paths, nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var ErrCouponCodeTaken = errors.New("优惠券码已存在")
+
+// 预占优惠券使用次数的结果
+const (
+	couponReserveOK        = 1
+	couponReserveSoldOut   = -1
+	couponReserveUserLimit = -2
+)
+
+// CouponRepo 优惠券 配置和使用记录保存在MySQL 使用次数计数保存在Redis
+// 计数在下单前通过Lua脚本原子地检查并增加 并发下单时不会超过总量和每人限制
+type CouponRepo struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+func NewCouponRepo(db *gorm.DB, redisClient *redis.Client) *CouponRepo {
+	return &CouponRepo{db: db, redisClient: redisClient}
+}
+
+// couponCounterKey 优惠券使用次数计数 hash中total为总次数 user:{id}为每个用户的次数
+// 格式: coupon:redemptions:{coupon_id}
+func couponCounterKey(couponID int) string {
+	return fmt.Sprintf("coupon:redemptions:%d", couponID)
+}
+
+// couponUserField 用户使用次数在计数hash中的field
+func couponUserField(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// Create 创建优惠券 券码重复时返回ErrCouponCodeTaken
+func (r *CouponRepo) Create(ctx context.Context, coupon *model.Coupon) error {
+	err := r.db.WithContext(ctx).Create(coupon).Error
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return ErrCouponCodeTaken
+	}
+	return err
+}
+
+// GetByID 根据ID查询优惠券
+func (r *CouponRepo) GetByID(ctx context.Context, id int) (*model.Coupon, error) {
+	var coupon model.Coupon
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// GetByCode 根据券码查询优惠券
+func (r *CouponRepo) GetByCode(ctx context.Context, code string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// List 分页查询优惠券 按创建时间倒序
+func (r *CouponRepo) List(ctx context.Context, status string, page, pageSize int) ([]model.Coupon, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Coupon{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var coupons []model.Coupon
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&coupons).Error
+	return coupons, total, err
+}
+
+// UpdateStatus 更新优惠券状态
+func (r *CouponRepo) UpdateStatus(ctx context.Context, id int, status string) error {
+	result := r.db.WithContext(ctx).Model(&model.Coupon{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&model.Coupon{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// CreateRedemptionWithTx 在下单事务中写入优惠券使用记录
+func (r *CouponRepo) CreateRedemptionWithTx(tx *gorm.DB, redemption *model.CouponRedemption) error {
+	return tx.Create(redemption).Error
+}
+
+// Reserve 预占一次优惠券使用次数 超过总量返回ErrCouponSoldOut 超过每人限制返回ErrCouponUserLimitReached
+// limit为0表示不限制 计数不存在时先从数据库的使用记录恢复
+func (r *CouponRepo) Reserve(ctx context.Context, couponID, userID, totalLimit, perUserLimit int) error {
+	if err := r.ensureCounter(ctx, couponID); err != nil {
+		return err
+	}
+
+	script := `
+		local total = tonumber(redis.call("hget", KEYS[1], "total") or "0")
+		local used = tonumber(redis.call("hget", KEYS[1], ARGV[1]) or "0")
+		if tonumber(ARGV[2]) > 0 and total >= tonumber(ARGV[2]) then
+			return -1
+		end
+		if tonumber(ARGV[3]) > 0 and used >= tonumber(ARGV[3]) then
+			return -2
+		end
+		redis.call("hincrby", KEYS[1], "total", 1)
+		redis.call("hincrby", KEYS[1], ARGV[1], 1)
+		return 1
+	`
+	result, err := r.redisClient.Eval(ctx, script, []string{couponCounterKey(couponID)},
+		couponUserField(userID), totalLimit, perUserLimit).Int()
+	if err != nil {
+		return err
+	}
+	switch result {
+	case couponReserveSoldOut:
+		return model.ErrCouponSoldOut
+	case couponReserveUserLimit:
+		return model.ErrCouponUserLimitReached
+	}
+	return nil
+}
+
+// Release 归还预占的使用次数 下单失败时调用 计数不会减到0以下
+func (r *CouponRepo) Release(ctx context.Context, couponID, userID int) error {
+	script := `
+		for _, field in ipairs({"total", ARGV[1]}) do
+			if tonumber(redis.call("hget", KEYS[1], field) or "0") > 0 then
+				redis.call("hincrby", KEYS[1], field, -1)
+			end
+		end
+		return 1
+	`
+	return r.redisClient.Eval(ctx, script, []string{couponCounterKey(couponID)}, couponUserField(userID)).Err()
+}
+
+// ensureCounter 计数不存在时（首次使用或Redis数据丢失）从使用记录恢复
+// 多个实例同时恢复时只有第一个写入生效
+func (r *CouponRepo) ensureCounter(ctx context.Context, couponID int) error {
+	key := couponCounterKey(couponID)
+	exists, err := r.redisClient.Exists(ctx, key).Result()
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	var rows []struct {
+		UserID int
+		Count  int
+	}
+	err = r.db.WithContext(ctx).Model(&model.CouponRedemption{}).
+		Select("user_id, COUNT(*) AS count").Where("coupon_id = ?", couponID).
+		Group("user_id").Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	args := make([]interface{}, 0, len(rows)*2+2)
+	for _, row := range rows {
+		total += row.Count
+		args = append(args, couponUserField(row.UserID), row.Count)
+	}
+	args = append(args, "total", total)
+
+	script := `
+		if redis.call("exists", KEYS[1]) == 1 then
+			return 0
+		end
+		redis.call("hset", KEYS[1], unpack(ARGV))
+		return 1
+	`
+	return r.redisClient.Eval(ctx, script, []string{key}, args...).Err()
+}
@@ -38,10 +38,22 @@ func (r *InventoryRepo) DecreaseStockWithDistributedLock(ctx context.Context, pr
 	lock := util.NewDistributedLock(util.RedisClient, lockKey, 10*time.Second)
 
 	// 使用WithLock方法，自动处理锁的获取和释放
-	return lock.WithLock(ctx, func() error {
+	err := lock.WithLock(ctx, func() error {
 		// 在锁保护下再次检查库存并扣减
 		return r.DecreaseStockWithTx(r.db, productID, quantity)
 	})
+	if err != nil {
+		return err
+	}
+
+	// 3. 库存变更后清除商品的Redis缓存
+	if err := util.DelProductCache(ctx, productID); err != nil {
+		util.GlobalLogger.Warn(ctx, "商品缓存删除失败",
+			util.Field{Key: "product_id", Value: productID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	return nil
 }
 
 // 核心操作 执行商品扣减
@@ -132,3 +144,35 @@ func (r *InventoryRepo) DecreaseStockWithTx(tx *gorm.DB, productID int, quantity
 
 	return result.Error
 }
+
+// IncreaseStockWithTx 在外部事务中退回库存 没有库存记录时返回gorm.ErrRecordNotFound
+func (r *InventoryRepo) IncreaseStockWithTx(tx *gorm.DB, productID int, quantity int) error {
+	result := tx.Model(&model.Inventory{}).Where("product_id = ?", productID).
+		Updates(map[string]interface{}{
+			"stock":   gorm.Expr("stock + ?", quantity),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetStocks 批量查询商品库存 没有库存记录的商品不在结果中
+func (r *InventoryRepo) GetStocks(ctx context.Context, productIDs []int) (map[int]int, error) {
+	stocks := make(map[int]int, len(productIDs))
+	if len(productIDs) == 0 {
+		return stocks, nil
+	}
+	var inventories []model.Inventory
+	if err := r.db.WithContext(ctx).Where("product_id IN ?", productIDs).Find(&inventories).Error; err != nil {
+		return nil, err
+	}
+	for _, inventory := range inventories {
+		stocks[inventory.ProductID] = inventory.Stock
+	}
+	return stocks, nil
+}
@@ -3,24 +3,31 @@ package repository
 import (
 	"context"
 	"demo01/internal/model"
+	"demo01/internal/util"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+// ErrOrderStatusChanged 订单状态已被其他请求修改
+var ErrOrderStatusChanged = errors.New("订单状态已变更")
+
 // 订单相关操作
 
 type OrderRepo struct {
 	db          *gorm.DB
 	redisClient *redis.Client
+	bloom       *util.BloomFilter // 已存在订单ID的布隆过滤器
 }
 
 func NewOrderRepo(db *gorm.DB, redisClient *redis.Client) *OrderRepo {
 	return &OrderRepo{
 		db:          db,
 		redisClient: redisClient,
+		bloom:       util.NewBloomFilter(redisClient, orderBloomKey, util.DefaultBloomBits, util.DefaultBloomHashes),
 	}
 }
 
@@ -34,15 +41,64 @@ func (r *OrderRepo) CreateWithTx(tx *gorm.DB, order *model.Order) error {
 	return tx.Create(order).Error
 }
 
-// GetAll 分页查询所有订单
-func (r *OrderRepo) GetAll(ctx context.Context, page, pageSize int) ([]model.Order, error) {
-	// 参数验证
-	if page <= 0 || pageSize <= 0 {
-		return nil, gorm.ErrInvalidData
+// Search 按条件查询订单 按(created_at, id)倒序 游标分页
+// 多查一条用于判断是否还有下一页 由调用方截取
+func (r *OrderRepo) Search(ctx context.Context, filter model.OrderFilter) ([]model.Order, error) {
+	query := r.db.WithContext(ctx).Model(&model.Order{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at <= ?", filter.To)
+	}
+	if !filter.Before.IsZero() {
+		query = query.Where("created_at < ?", filter.Before)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("total_amount >= ?", *filter.MinAmount)
 	}
+	if filter.MaxAmount != nil {
+		query = query.Where("total_amount <= ?", *filter.MaxAmount)
+	}
+	if c := filter.Cursor; c != nil {
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", c.CreatedAt, c.CreatedAt, c.ID)
+	}
+
+	var orders []model.Order
+	err := query.Order("created_at DESC, id DESC").Limit(filter.Limit + 1).Find(&orders).Error
+	return orders, err
+}
 
+// GetRecent 查询最近创建的订单
+func (r *OrderRepo) GetRecent(ctx context.Context, limit int) ([]model.Order, error) {
 	var orders []model.Order
-	err := r.db.WithContext(ctx).Offset((page - 1) * pageSize).Limit(pageSize).Find(&orders).Error
+	err := r.db.WithContext(ctx).Order("created_at DESC").Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+// FindSinceInBatches 分批遍历某一时间之后创建的已支付订单 只加载商品列表和状态
+// 未支付、已取消和已全额退款的订单不算购买
+func (r *OrderRepo) FindSinceInBatches(ctx context.Context, since time.Time, batchSize int, fn func(orders []model.Order) error) error {
+	var batch []model.Order
+	return r.db.WithContext(ctx).Select("id", "items", "status").
+		Where("created_at >= ? AND status IN ?", since, model.PurchasedOrderStatuses).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// GetRecentByUser 查询用户最近的已支付订单
+func (r *OrderRepo) GetRecentByUser(ctx context.Context, userID string, limit int) ([]model.Order, error) {
+	var orders []model.Order
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status IN ?", userID, model.PurchasedOrderStatuses).
+		Order("created_at DESC").Limit(limit).Find(&orders).Error
 	return orders, err
 }
 
@@ -67,10 +123,13 @@ func (r *OrderRepo) GetFromCache(ctx context.Context, orderID string) (*model.Or
 		return nil, redis.Nil
 	}
 
-	orderJSON, err := r.redisClient.Get(ctx, "order:"+orderID).Result()
+	orderJSON, err := r.redisClient.Get(ctx, util.OrderCacheKey(orderID)).Result()
 	if err != nil {
 		return nil, err
 	}
+	if orderJSON == notFoundPlaceholder {
+		return nil, ErrCachedNotFound
+	}
 
 	var order model.Order
 	if err := json.Unmarshal([]byte(orderJSON), &order); err != nil {
@@ -91,10 +150,118 @@ func (r *OrderRepo) SetToCache(ctx context.Context, orderID string, order *model
 		return err
 	}
 
-	return r.redisClient.Set(ctx, "order:"+orderID, orderJSON, time.Hour).Err()
+	return r.redisClient.Set(ctx, util.OrderCacheKey(orderID), orderJSON, time.Hour).Err()
+}
+
+// DelFromCache 删除Redis中的订单缓存
+func (r *OrderRepo) DelFromCache(ctx context.Context, orderID string) error {
+	if r.redisClient == nil {
+		return nil
+	}
+	return r.redisClient.Del(ctx, util.OrderCacheKey(orderID)).Err()
+}
+
+// SetNotFoundToCache 缓存订单不存在的结果（空值缓存）
+func (r *OrderRepo) SetNotFoundToCache(ctx context.Context, orderID string) error {
+	if r.redisClient == nil {
+		return nil
+	}
+	ttl := util.TTLWithJitter(notFoundCacheTTL, notFoundCacheJitter)
+	return r.redisClient.Set(ctx, util.OrderCacheKey(orderID), notFoundPlaceholder, ttl).Err()
+}
+
+// MightExist 通过布隆过滤器判断订单是否可能存在
+func (r *OrderRepo) MightExist(ctx context.Context, orderID string) bool {
+	return r.bloom.MightContain(ctx, orderID)
+}
+
+// AddToBloom 新订单创建后加入布隆过滤器
+func (r *OrderRepo) AddToBloom(ctx context.Context, orderID string) error {
+	return r.bloom.Add(ctx, orderID)
+}
+
+// RebuildBloom 从数据库分批加载所有订单ID重建布隆过滤器
+func (r *OrderRepo) RebuildBloom(ctx context.Context) error {
+	var batch []model.Order
+	err := r.db.WithContext(ctx).Model(&model.Order{}).Select("id").
+		FindInBatches(&batch, bloomRebuildBatchSize, func(tx *gorm.DB, _ int) error {
+			ids := make([]string, len(batch))
+			for i := range batch {
+				ids[i] = batch[i].ID
+			}
+			return r.bloom.Add(ctx, ids...)
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	r.bloom.MarkReady()
+	return nil
 }
 
 // GetDB 获取数据库连接（用于事务）
 func (r *OrderRepo) GetDB() *gorm.DB {
 	return r.db
 }
+
+// CountCache 统计Redis中的订单缓存数量（包含空值缓存）
+func (r *OrderRepo) CountCache(ctx context.Context) (int, error) {
+	if r.redisClient == nil {
+		return 0, nil
+	}
+	return util.ScanCount(ctx, r.redisClient, "order:*")
+}
+
+// PurgeCache 清空Redis中的所有订单缓存
+func (r *OrderRepo) PurgeCache(ctx context.Context) (int64, error) {
+	if r.redisClient == nil {
+		return 0, nil
+	}
+	return util.ScanDelete(ctx, r.redisClient, "order:*")
+}
+
+// TransitionStatus 将订单状态从from改为to 同时更新其他字段
+// 以当前状态作为更新条件 并发修改时只有一个请求成功 其他请求返回ErrOrderStatusChanged
+func (r *OrderRepo) TransitionStatus(ctx context.Context, id, from, to string, updates map[string]interface{}) error {
+	return r.TransitionStatusWithTx(r.db.WithContext(ctx), id, from, to, updates)
+}
+
+// TransitionStatusWithTx 在外部事务中修改订单状态
+func (r *OrderRepo) TransitionStatusWithTx(tx *gorm.DB, id, from, to string, updates map[string]interface{}) error {
+	values := map[string]interface{}{"status": to, "updated_at": time.Now()}
+	for k, v := range updates {
+		values[k] = v
+	}
+	result := tx.Model(&model.Order{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderStatusChanged
+	}
+	return nil
+}
+
+// AddRefundedAmountWithTx 在外部事务中累加订单的已退款金额 全额退款时同时把订单状态改为已退款
+// 只更新可以退款状态的订单 订单已被取消或已全额退款时返回ErrOrderStatusChanged
+func (r *OrderRepo) AddRefundedAmountWithTx(tx *gorm.DB, id string, amount float64, fullyRefunded bool) error {
+	values := map[string]interface{}{
+		"refunded_amount": gorm.Expr("refunded_amount + ?", amount),
+		"updated_at":      time.Now(),
+	}
+	if fullyRefunded {
+		values["status"] = model.OrderStatusRefunded
+	}
+	result := tx.Model(&model.Order{}).
+		Where("id = ? AND status IN ?", id, model.RefundableOrderStatuses).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderStatusChanged
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 支付单相关操作
+type PaymentRepo struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepo(db *gorm.DB) *PaymentRepo {
+	return &PaymentRepo{db: db}
+}
+
+// Create 创建支付单
+func (r *PaymentRepo) Create(ctx context.Context, payment *model.Payment) error {
+	return r.db.WithContext(ctx).Create(payment).Error
+}
+
+// GetByProviderRef 根据支付渠道的交易号查询支付单
+func (r *PaymentRepo) GetByProviderRef(ctx context.Context, providerRef string) (*model.Payment, error) {
+	var payment model.Payment
+	if err := r.db.WithContext(ctx).Where("provider_ref = ?", providerRef).First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetLatestByOrder 查询订单最近创建的支付单
+func (r *PaymentRepo) GetLatestByOrder(ctx context.Context, orderID string) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).
+		Order("created_at DESC").First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetSucceededByOrder 查询订单支付成功的支付单
+func (r *PaymentRepo) GetSucceededByOrder(ctx context.Context, orderID string) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.WithContext(ctx).Where("order_id = ? AND status = ?", orderID, model.PaymentStatusSucceeded).
+		First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// MarkSucceededWithTx 在事务中将待支付的支付单标记为成功 已处理过的支付单返回false
+func (r *PaymentRepo) MarkSucceededWithTx(tx *gorm.DB, id string, paidAt time.Time) (bool, error) {
+	result := tx.Model(&model.Payment{}).
+		Where("id = ? AND status = ?", id, model.PaymentStatusPending).
+		Updates(map[string]interface{}{
+			"status":  model.PaymentStatusSucceeded,
+			"paid_at": paidAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkFailed 将待支付的支付单标记为失败
+func (r *PaymentRepo) MarkFailed(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&model.Payment{}).
+		Where("id = ? AND status = ?", id, model.PaymentStatusPending).
+		Update("status", model.PaymentStatusFailed).Error
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *PaymentRepo) GetDB() *gorm.DB {
+	return r.db
+}
+
+// GetSucceededByOrderForUpdateWithTx 在事务中查询并锁定订单支付成功的支付单
+// 同一订单的退款在这一行上串行执行 避免并发退款超过支付金额
+func (r *PaymentRepo) GetSucceededByOrderForUpdateWithTx(tx *gorm.DB, orderID string) (*model.Payment, error) {
+	var payment model.Payment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, model.PaymentStatusSucceeded).
+		First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
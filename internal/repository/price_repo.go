@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 价格变更记录和定时调价相关操作
+type PriceRepo struct {
+	db *gorm.DB
+}
+
+func NewPriceRepo(db *gorm.DB) *PriceRepo {
+	return &PriceRepo{db: db}
+}
+
+// CreateHistoryWithTx 在外部事务中写入价格变更记录 与价格修改同时提交
+func (r *PriceRepo) CreateHistoryWithTx(tx *gorm.DB, histories ...model.PriceHistory) error {
+	if len(histories) == 0 {
+		return nil
+	}
+	return tx.Create(&histories).Error
+}
+
+// ListHistory 查询商品或SKU的价格变更记录 按变更时间升序 skuID为0时查询商品本身的价格 from/to为零值时不限制
+func (r *PriceRepo) ListHistory(ctx context.Context, productID, skuID int, from, to time.Time) ([]model.PriceHistory, error) {
+	query := r.db.WithContext(ctx).Where("product_id = ? AND sku_id = ?", productID, skuID)
+	if !from.IsZero() {
+		query = query.Where("changed_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("changed_at <= ?", to)
+	}
+	var histories []model.PriceHistory
+	err := query.Order("changed_at, id").Find(&histories).Error
+	return histories, err
+}
+
+// CreateSchedule 创建定时调价
+func (r *PriceRepo) CreateSchedule(ctx context.Context, schedule *model.PriceSchedule) error {
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+// ListSchedules 查询商品的定时调价 按生效时间升序
+func (r *PriceRepo) ListSchedules(ctx context.Context, productID int) ([]model.PriceSchedule, error) {
+	var schedules []model.PriceSchedule
+	err := r.db.WithContext(ctx).Where("product_id = ?", productID).
+		Order("effective_at, id").Find(&schedules).Error
+	return schedules, err
+}
+
+// CancelSchedule 取消等待生效的定时调价
+func (r *PriceRepo) CancelSchedule(ctx context.Context, productID, scheduleID int) error {
+	result := r.db.WithContext(ctx).Model(&model.PriceSchedule{}).
+		Where("id = ? AND product_id = ? AND status = ?", scheduleID, productID, model.PriceScheduleStatusPending).
+		Update("status", model.PriceScheduleStatusCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DueSchedules 查询已到生效时间但还没有执行的定时调价
+func (r *PriceRepo) DueSchedules(ctx context.Context, now time.Time, limit int) ([]model.PriceSchedule, error) {
+	var schedules []model.PriceSchedule
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND effective_at <= ?", model.PriceScheduleStatusPending, now).
+		Order("effective_at, id").Limit(limit).Find(&schedules).Error
+	return schedules, err
+}
+
+// ClaimScheduleWithTx 在事务中将定时调价标记为已生效
+// 多个实例同时执行时只有一个能更新成功 返回false表示已被其他实例处理
+func (r *PriceRepo) ClaimScheduleWithTx(tx *gorm.DB, scheduleID int, now time.Time) (bool, error) {
+	result := tx.Model(&model.PriceSchedule{}).
+		Where("id = ? AND status = ?", scheduleID, model.PriceScheduleStatusPending).
+		Updates(map[string]interface{}{
+			"status":     model.PriceScheduleStatusApplied,
+			"applied_at": now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// MarkScheduleFailed 标记定时调价执行失败
+func (r *PriceRepo) MarkScheduleFailed(ctx context.Context, scheduleID int) error {
+	return r.db.WithContext(ctx).Model(&model.PriceSchedule{}).
+		Where("id = ? AND status = ?", scheduleID, model.PriceScheduleStatusPending).
+		Update("status", model.PriceScheduleStatusFailed).Error
+}
@@ -3,46 +3,268 @@ package repository
 import (
 	"context"
 	"demo01/internal/model"
+	"demo01/internal/util"
+	"encoding/json"
 	"errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
 	ErrInsufficientStock = errors.New("库存不足")
+	ErrVersionConflict   = errors.New("数据已被修改，请刷新后重试")
+)
+
+// 商品缓存过期时间 基础30分钟 + 最多5分钟随机抖动
+const (
+	productCacheTTL    = 30 * time.Minute
+	productCacheJitter = 5 * time.Minute
 )
 
 type ProductRepo struct {
-	db *gorm.DB
+	db          *gorm.DB
+	redisClient *redis.Client
+	bloom       *util.BloomFilter // 已存在商品ID的布隆过滤器
 }
 
-func NewProductRepo(db *gorm.DB) *ProductRepo {
-	return &ProductRepo{db: db}
+func NewProductRepo(db *gorm.DB, redisClient *redis.Client) *ProductRepo {
+	return &ProductRepo{
+		db:          db,
+		redisClient: redisClient,
+		bloom:       util.NewBloomFilter(redisClient, productBloomKey, util.DefaultBloomBits, util.DefaultBloomHashes),
+	}
 }
 
-// Create 创建商品
+// Create 创建商品 成功后加入布隆过滤器
 func (r *ProductRepo) Create(ctx context.Context, product *model.Product) error {
-	return r.db.WithContext(ctx).Create(product).Error
+	if err := r.db.WithContext(ctx).Create(product).Error; err != nil {
+		return err
+	}
+	if err := r.bloom.Add(ctx, strconv.Itoa(product.ID)); err != nil {
+		// 商品已经创建成功 布隆过滤器写入失败只记录日志
+		util.GlobalLogger.Warn(ctx, "商品ID写入布隆过滤器失败",
+			util.Field{Key: "product_id", Value: product.ID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	return nil
 }
 
-// GetByID 根据ID获取商品
+// GetByID 根据ID获取商品 同时加载SKU并生成规格矩阵
 func (r *ProductRepo) GetByID(ctx context.Context, id int) (*model.Product, error) {
 	var product model.Product
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&product).Error
+	err := r.db.WithContext(ctx).Preload("SKUs", orderSKUs).Where("id = ?", id).First(&product).Error
 	if err != nil {
 		return nil, err
 	}
+	product.Variants = model.BuildVariantMatrix(product.SKUs)
 	return &product, nil
 }
 
-// GetAll 获取所有商品（分页）
+// orderSKUs SKU按ID排序 保证规格矩阵中规格值的顺序稳定
+func orderSKUs(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// GetAll 获取所有上架中的商品（分页） 草稿、下架、停售的商品不在列表中展示
 func (r *ProductRepo) GetAll(ctx context.Context, page, pageSize int) ([]model.Product, error) {
 	var products []model.Product
 	offset := (page - 1) * pageSize
-	err := r.db.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&products).Error
+	err := r.db.WithContext(ctx).Where("status = ?", model.ProductStatusActive).
+		Offset(offset).Limit(pageSize).Find(&products).Error
+	return products, err
+}
+
+// productSortColumns 允许排序的字段 防止SQL注入
+var productSortColumns = map[string]string{
+	"price":      "price",
+	"created_at": "created_at",
+	"sales":      "sales",
+}
+
+// Search 按条件筛选商品（分页） 同时返回符合条件的总数
+func (r *ProductRepo) Search(ctx context.Context, filter model.ProductFilter) ([]model.Product, int64, error) {
+	// 1. 组装筛选条件
+	status := filter.Status
+	if status == "" {
+		status = model.ProductStatusActive
+	}
+	query := r.db.WithContext(ctx).Model(&model.Product{}).Where("status = ?", status)
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.InStock {
+		// 按下单时实际扣减的库存筛选 多规格商品看SKU库存 其他商品看库存表
+		query = query.Where(`(EXISTS (SELECT 1 FROM product_skus WHERE product_skus.product_id = products.id AND product_skus.stock > 0)
+			OR (NOT EXISTS (SELECT 1 FROM product_skus WHERE product_skus.product_id = products.id)
+				AND EXISTS (SELECT 1 FROM inventories WHERE inventories.product_id = products.id AND inventories.stock > 0)))`)
+	}
+	if filter.Keyword != "" {
+		// LIKE前后模糊匹配无法使用索引 数据量大时应使用全文索引
+		like := "%" + escapeLike(filter.Keyword) + "%"
+		query = query.Where("(name LIKE ? OR description LIKE ?)", like, like)
+	}
+
+	// 2. 查询总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 3. 排序和分页
+	orderBy := "id"
+	if column, ok := productSortColumns[filter.SortBy]; ok {
+		orderBy = column
+	}
+	if filter.Desc {
+		orderBy += " DESC"
+	}
+	// 排序字段相同时按ID排序 保证分页结果稳定
+	if orderBy != "id" && orderBy != "id DESC" {
+		orderBy += ", id"
+	}
+
+	var products []model.Product
+	err := query.Order(orderBy).
+		Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize).
+		Find(&products).Error
+	return products, total, err
+}
+
+// escapeLike 转义LIKE中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// CountByCategory 统计分类下（不含子分类）的商品数量
+func (r *ProductRepo) CountByCategory(ctx context.Context, categoryID int) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Product{}).Where("category_id = ?", categoryID).Count(&count).Error
+	return count, err
+}
+
+// SyncCategoryName 分类改名后同步商品上冗余的分类名称 返回受影响的商品数量
+// 只修改冗余字段 不增加版本号
+func (r *ProductRepo) SyncCategoryName(ctx context.Context, categoryID int, name string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.Product{}).Where("category_id = ?", categoryID).
+		UpdateColumn("category", name)
+	return result.RowsAffected, result.Error
+}
+
+// IncreaseSalesWithTx 在外部事务中增加商品销量
+func (r *ProductRepo) IncreaseSalesWithTx(tx *gorm.DB, productID, quantity int) error {
+	return tx.Model(&model.Product{}).Where("id = ?", productID).
+		UpdateColumn("sales", gorm.Expr("sales + ?", quantity)).Error
+}
+
+// Update 更新商品信息（乐观锁）
+// 只有版本号匹配时才更新 同时版本号+1
+func (r *ProductRepo) Update(ctx context.Context, productID, version int, updates map[string]interface{}) error {
+	return r.UpdateWithTx(r.db.WithContext(ctx), productID, version, updates)
+}
+
+// UpdateWithTx 在外部事务中更新商品信息 version小于0时不校验版本号
+func (r *ProductRepo) UpdateWithTx(tx *gorm.DB, productID, version int, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	query := tx.Model(&model.Product{}).Where("id = ?", productID)
+	if version >= 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.notFoundOrConflict(tx, productID)
+	}
+	return nil
+}
+
+// GetPriceForUpdateWithTx 在事务中查询商品当前价格并加行锁 保证记录的变更前价格准确
+func (r *ProductRepo) GetPriceForUpdateWithTx(tx *gorm.DB, productID int) (float64, error) {
+	var product model.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "price").
+		Where("id = ?", productID).First(&product).Error
+	return product.Price, err
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *ProductRepo) GetDB() *gorm.DB {
+	return r.db
+}
+
+// UpdateStatus 更新商品状态（乐观锁）
+func (r *ProductRepo) UpdateStatus(ctx context.Context, productID, version int, status string) error {
+	return r.Update(ctx, productID, version, map[string]interface{}{"status": status})
+}
+
+// Delete 软删除商品 version小于0时不校验版本号
+func (r *ProductRepo) Delete(ctx context.Context, productID, version int) error {
+	query := r.db.WithContext(ctx).Where("id = ?", productID)
+	if version >= 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Delete(&model.Product{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.notFoundOrConflict(r.db.WithContext(ctx), productID)
+	}
+	return nil
+}
+
+// notFoundOrConflict 乐观锁更新失败时区分是商品不存在还是版本冲突
+func (r *ProductRepo) notFoundOrConflict(db *gorm.DB, productID int) error {
+	var count int64
+	if err := db.Model(&model.Product{}).Where("id = ?", productID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrVersionConflict
+}
+
+// GetActiveIDs 获取上架中的商品ID 按最近更新时间排序
+func (r *ProductRepo) GetActiveIDs(ctx context.Context, limit int) ([]int, error) {
+	var ids []int
+	err := r.db.WithContext(ctx).Model(&model.Product{}).Where("status = ?", model.ProductStatusActive).
+		Order("updated_at DESC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetBestSellers 获取销量最高的上架商品
+func (r *ProductRepo) GetBestSellers(ctx context.Context, limit int) ([]model.Product, error) {
+	var products []model.Product
+	err := r.db.WithContext(ctx).Where("status = ?", model.ProductStatusActive).
+		Order("sales DESC, id").Limit(limit).Find(&products).Error
 	return products, err
 }
 
+// FindActiveInBatches 分批遍历所有上架中的商品
+func (r *ProductRepo) FindActiveInBatches(ctx context.Context, batchSize int, fn func(products []model.Product) error) error {
+	var batch []model.Product
+	return r.db.WithContext(ctx).Where("status = ?", model.ProductStatusActive).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
 // UpdateStock 更新库存（乐观锁）
 func (r *ProductRepo) UpdateStock(ctx context.Context, productID, quantity, version int) error {
 	result := r.db.WithContext(ctx).Model(&model.Product{}).
@@ -73,12 +295,238 @@ func (r *ProductRepo) GetStock(ctx context.Context, productID int) (int, error)
 	return product.Stock, nil
 }
 
-// GetProductsByIDs 根据一组商品ID批量查询商品详情
+// GetProductsByIDs 根据一组商品ID批量查询商品详情（包含SKU）
 func (r *ProductRepo) GetProductsByIDs(ctx context.Context, ids []int) ([]model.Product, error) {
 	if len(ids) == 0 {
 		return []model.Product{}, nil
 	}
 	var products []model.Product
-	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&products).Error
+	err := r.db.WithContext(ctx).Preload("SKUs", orderSKUs).Where("id IN ?", ids).Find(&products).Error
+	for i := range products {
+		products[i].Variants = model.BuildVariantMatrix(products[i].SKUs)
+	}
 	return products, err
 }
+
+// GetFromCache 从Redis缓存获取商品
+func (r *ProductRepo) GetFromCache(ctx context.Context, productID int) (*model.Product, error) {
+	if r.redisClient == nil {
+		return nil, redis.Nil
+	}
+
+	productJSON, err := r.redisClient.Get(ctx, util.ProductCacheKey(productID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if productJSON == notFoundPlaceholder {
+		return nil, ErrCachedNotFound
+	}
+
+	var product model.Product
+	if err := json.Unmarshal([]byte(productJSON), &product); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// SetToCache 将商品写入Redis缓存
+func (r *ProductRepo) SetToCache(ctx context.Context, productID int, product *model.Product) error {
+	if r.redisClient == nil {
+		return nil
+	}
+
+	productJSON, err := json.Marshal(product)
+	if err != nil {
+		return err
+	}
+
+	ttl := util.TTLWithJitter(productCacheTTL, productCacheJitter)
+	return r.redisClient.Set(ctx, util.ProductCacheKey(productID), productJSON, ttl).Err()
+}
+
+// DelFromCache 删除Redis中的商品缓存
+func (r *ProductRepo) DelFromCache(ctx context.Context, productID int) error {
+	if r.redisClient == nil {
+		return nil
+	}
+	return r.redisClient.Del(ctx, util.ProductCacheKey(productID)).Err()
+}
+
+// SetNotFoundToCache 缓存商品不存在的结果（空值缓存）
+func (r *ProductRepo) SetNotFoundToCache(ctx context.Context, productID int) error {
+	if r.redisClient == nil {
+		return nil
+	}
+	ttl := util.TTLWithJitter(notFoundCacheTTL, notFoundCacheJitter)
+	return r.redisClient.Set(ctx, util.ProductCacheKey(productID), notFoundPlaceholder, ttl).Err()
+}
+
+// MightExist 通过布隆过滤器判断商品是否可能存在
+func (r *ProductRepo) MightExist(ctx context.Context, productID int) bool {
+	return r.bloom.MightContain(ctx, strconv.Itoa(productID))
+}
+
+// RebuildBloom 从数据库分批加载所有商品ID重建布隆过滤器
+func (r *ProductRepo) RebuildBloom(ctx context.Context) error {
+	var batch []model.Product
+	err := r.db.WithContext(ctx).Model(&model.Product{}).Select("id").
+		FindInBatches(&batch, bloomRebuildBatchSize, func(tx *gorm.DB, _ int) error {
+			ids := make([]string, len(batch))
+			for i := range batch {
+				ids[i] = strconv.Itoa(batch[i].ID)
+			}
+			return r.bloom.Add(ctx, ids...)
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	r.bloom.MarkReady()
+	return nil
+}
+
+// GetStatusesByExternalSKU 按外部编码查询未删除商品的当前状态 返回外部编码到状态的映射
+func (r *ProductRepo) GetStatusesByExternalSKU(ctx context.Context, codes []string) (map[string]string, error) {
+	var products []model.Product
+	if err := r.db.WithContext(ctx).Select("external_sku", "status").
+		Where("external_sku IN ?", codes).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	statuses := make(map[string]string, len(products))
+	for _, p := range products {
+		statuses[*p.ExternalSKU] = p.Status
+	}
+	return statuses, nil
+}
+
+// 批量导入时按外部编码更新的字段
+var productImportColumns = []string{"name", "description", "price", "stock", "category_id", "category", "status"}
+
+// UpsertByExternalSKU 按外部编码批量新增或更新商品 同时写入库存表 返回其中已存在（被更新）的商品数量
+// 一批商品在一个事务中完成 成功后products中的ID会被回填
+// 已存在的商品价格发生变化时写入价格变更记录 已软删除的同编码商品会被恢复
+func (r *ProductRepo) UpsertByExternalSKU(ctx context.Context, products []model.Product, changedBy string) (int, error) {
+	if len(products) == 0 {
+		return 0, nil
+	}
+	codes := make([]string, 0, len(products))
+	for i := range products {
+		codes = append(codes, *products[i].ExternalSKU)
+	}
+
+	var existing []model.Product
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 查询已存在的商品 用于区分新增和更新 以及记录价格变化
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "external_sku", "price").
+			Where("external_sku IN ?", codes).Find(&existing).Error; err != nil {
+			return err
+		}
+		oldPrices := make(map[string]float64, len(existing))
+		for _, p := range existing {
+			oldPrices[*p.ExternalSKU] = p.Price
+		}
+
+		// 2. INSERT ... ON DUPLICATE KEY UPDATE 批量写入商品
+		updates := append(clause.AssignmentColumns(productImportColumns),
+			clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("version + 1")},
+			clause.Assignment{Column: clause.Column{Name: "deleted_at"}, Value: nil},
+		)
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "external_sku"}},
+			DoUpdates: updates,
+		}).Omit(clause.Associations).CreateInBatches(products, len(products)).Error; err != nil {
+			return err
+		}
+
+		// 3. 更新的行拿不到自增ID 按编码重新查询回填
+		var rows []model.Product
+		if err := tx.Unscoped().Select("id", "external_sku").Where("external_sku IN ?", codes).Find(&rows).Error; err != nil {
+			return err
+		}
+		ids := make(map[string]int, len(rows))
+		for _, row := range rows {
+			ids[*row.ExternalSKU] = row.ID
+		}
+		now := time.Now()
+		inventories := make([]model.Inventory, len(products))
+		var histories []model.PriceHistory
+		for i := range products {
+			p := &products[i]
+			p.ID = ids[*p.ExternalSKU]
+			inventories[i] = model.Inventory{ProductID: p.ID, Stock: p.Stock}
+			if old, ok := oldPrices[*p.ExternalSKU]; ok && old != p.Price {
+				histories = append(histories, model.PriceHistory{
+					ProductID: p.ID,
+					OldPrice:  old,
+					NewPrice:  p.Price,
+					Source:    model.PriceSourceImport,
+					ChangedBy: changedBy,
+					ChangedAt: now,
+				})
+			}
+		}
+		if len(histories) > 0 {
+			if err := tx.Create(&histories).Error; err != nil {
+				return err
+			}
+		}
+
+		// 4. 同步库存表 下单扣减的是库存表中的库存
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "product_id"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "stock"}, Value: gorm.Expr("VALUES(stock)")},
+				{Column: clause.Column{Name: "version"}, Value: gorm.Expr("version + 1")},
+			},
+		}).Create(&inventories).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, len(products))
+	for i := range products {
+		ids[i] = strconv.Itoa(products[i].ID)
+	}
+	if err := r.bloom.Add(ctx, ids...); err != nil {
+		util.GlobalLogger.Warn(ctx, "导入商品ID写入布隆过滤器失败",
+			util.Field{Key: "count", Value: len(ids)},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	return len(existing), nil
+}
+
+// ExportInBatches 按ID顺序分批遍历商品（不含已删除） 库存以库存表为准 没有库存记录时使用商品表中的库存
+// status为空时导出所有状态的商品
+func (r *ProductRepo) ExportInBatches(ctx context.Context, status string, batchSize int, fn func(products []model.Product) error) error {
+	query := r.db.WithContext(ctx).Model(&model.Product{}).
+		Select("products.id, products.external_sku, products.name, products.description, products.price, " +
+			"COALESCE(inventories.stock, products.stock) AS stock, products.category_id, products.category, products.status").
+		Joins("LEFT JOIN inventories ON inventories.product_id = products.id")
+	if status != "" {
+		query = query.Where("products.status = ?", status)
+	}
+
+	var batch []model.Product
+	return query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// CountCache 统计Redis中的商品缓存数量（包含空值缓存）
+func (r *ProductRepo) CountCache(ctx context.Context) (int, error) {
+	if r.redisClient == nil {
+		return 0, nil
+	}
+	return util.ScanCount(ctx, r.redisClient, "product:*")
+}
+
+// PurgeCache 清空Redis中的所有商品缓存
+func (r *ProductRepo) PurgeCache(ctx context.Context) (int64, error) {
+	if r.redisClient == nil {
+		return 0, nil
+	}
+	return util.ScanDelete(ctx, r.redisClient, "product:*")
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 推荐结果的redis key
+const (
+	coPurchaseKeyPrefix = "recommend:copurchase:" // 有序集合 成员为同单购买的商品ID 分数为同单次数
+	bestSellersKey      = "recommend:bestsellers" // 有序集合 成员为商品ID 分数为购买数量
+)
+
+// 每个事务管道写入的商品数量
+const recommendWriteChunk = 200
+
+// RecommendRepo 推荐结果存储 离线计算后写入Redis有序集合
+type RecommendRepo struct {
+	redisClient *redis.Client
+}
+
+func NewRecommendRepo(redisClient *redis.Client) *RecommendRepo {
+	return &RecommendRepo{redisClient: redisClient}
+}
+
+// coPurchaseKey 商品的共同购买推荐key
+// 格式: recommend:copurchase:{product_id}
+func coPurchaseKey(productID int) string {
+	return fmt.Sprintf("%s%d", coPurchaseKeyPrefix, productID)
+}
+
+// SaveCoPurchase 写入每个商品的共同购买推荐 覆盖之前的结果
+// 每个key的删除和写入在同一个事务中执行 读取方不会看到空集合
+// 本轮没有出现的商品不会被覆盖 到期后自动删除
+func (r *RecommendRepo) SaveCoPurchase(ctx context.Context, recs map[int][]model.ScoredProduct, ttl time.Duration) error {
+	ids := make([]int, 0, len(recs))
+	for id := range recs {
+		ids = append(ids, id)
+	}
+
+	for start := 0; start < len(ids); start += recommendWriteChunk {
+		end := start + recommendWriteChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+		_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, id := range ids[start:end] {
+				writeScoredSet(ctx, pipe, coPurchaseKey(id), recs[id], ttl)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCoPurchase 查询与商品同单购买次数最多的limit个商品
+func (r *RecommendRepo) GetCoPurchase(ctx context.Context, productID, limit int) ([]model.ScoredProduct, error) {
+	return r.readScoredSet(ctx, coPurchaseKey(productID), limit)
+}
+
+// GetCoPurchaseBatch 通过管道一次查询多个商品的共同购买推荐 结果与productIDs一一对应
+func (r *RecommendRepo) GetCoPurchaseBatch(ctx context.Context, productIDs []int, limit int) ([][]model.ScoredProduct, error) {
+	if len(productIDs) == 0 || limit <= 0 {
+		return [][]model.ScoredProduct{}, nil
+	}
+	cmds := make([]*redis.ZSliceCmd, len(productIDs))
+	_, err := r.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range productIDs {
+			cmds[i] = pipe.ZRevRangeWithScores(ctx, coPurchaseKey(id), 0, int64(limit-1))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	result := make([][]model.ScoredProduct, len(cmds))
+	for i, cmd := range cmds {
+		result[i] = toScoredProducts(cmd.Val())
+	}
+	return result, nil
+}
+
+// SaveBestSellers 写入热销商品榜单
+func (r *RecommendRepo) SaveBestSellers(ctx context.Context, list []model.ScoredProduct, ttl time.Duration) error {
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		writeScoredSet(ctx, pipe, bestSellersKey, list, ttl)
+		return nil
+	})
+	return err
+}
+
+// GetBestSellers 查询热销商品榜单 还没有计算过时返回空列表
+func (r *RecommendRepo) GetBestSellers(ctx context.Context, limit int) ([]model.ScoredProduct, error) {
+	return r.readScoredSet(ctx, bestSellersKey, limit)
+}
+
+// writeScoredSet 用新结果替换有序集合 结果为空时只删除
+func writeScoredSet(ctx context.Context, pipe redis.Pipeliner, key string, list []model.ScoredProduct, ttl time.Duration) {
+	pipe.Del(ctx, key)
+	if len(list) == 0 {
+		return
+	}
+	members := make([]redis.Z, len(list))
+	for i, sp := range list {
+		members[i] = redis.Z{Score: sp.Score, Member: sp.ProductID}
+	}
+	pipe.ZAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, ttl)
+}
+
+// readScoredSet 按分数降序读取有序集合的前limit个成员
+func (r *RecommendRepo) readScoredSet(ctx context.Context, key string, limit int) ([]model.ScoredProduct, error) {
+	if limit <= 0 {
+		return []model.ScoredProduct{}, nil
+	}
+	members, err := r.redisClient.ZRevRangeWithScores(ctx, key, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return toScoredProducts(members), nil
+}
+
+// toScoredProducts 将有序集合成员转换为商品ID和分数
+func toScoredProducts(members []redis.Z) []model.ScoredProduct {
+	result := make([]model.ScoredProduct, 0, len(members))
+	for _, m := range members {
+		member, _ := m.Member.(string)
+		id, err := strconv.Atoi(member)
+		if err != nil {
+			continue
+		}
+		result = append(result, model.ScoredProduct{ProductID: id, Score: m.Score})
+	}
+	return result
+}
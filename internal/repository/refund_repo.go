@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 退款单相关操作
+type RefundRepo struct {
+	db *gorm.DB
+}
+
+func NewRefundRepo(db *gorm.DB) *RefundRepo {
+	return &RefundRepo{db: db}
+}
+
+// CreateWithTx 在外部事务中创建退款单
+func (r *RefundRepo) CreateWithTx(tx *gorm.DB, refund *model.Refund) error {
+	return tx.Create(refund).Error
+}
+
+// ListByOrder 查询订单的全部退款单 按创建时间排列
+func (r *RefundRepo) ListByOrder(ctx context.Context, orderID string) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).
+		Order("created_at ASC").Find(&refunds).Error
+	return refunds, err
+}
+
+// ListActiveByOrderWithTx 在事务中查询订单未失败的退款单 处理中的退款也计入已退金额
+func (r *RefundRepo) ListActiveByOrderWithTx(tx *gorm.DB, orderID string) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := tx.Where("order_id = ? AND status <> ?", orderID, model.RefundStatusFailed).
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// SumSucceededWithTx 在事务中统计订单退款成功的总金额
+func (r *RefundRepo) SumSucceededWithTx(tx *gorm.DB, orderID string) (float64, error) {
+	var total float64
+	err := tx.Model(&model.Refund{}).
+		Where("order_id = ? AND status = ?", orderID, model.RefundStatusSucceeded).
+		Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+// MarkSucceededWithTx 在事务中将处理中的退款单标记为成功 已处理过的退款单返回false
+func (r *RefundRepo) MarkSucceededWithTx(tx *gorm.DB, id, providerRef string, refundedAt time.Time) (bool, error) {
+	result := tx.Model(&model.Refund{}).
+		Where("id = ? AND status = ?", id, model.RefundStatusPending).
+		Updates(map[string]interface{}{
+			"status":       model.RefundStatusSucceeded,
+			"provider_ref": providerRef,
+			"refunded_at":  refundedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkFailed 将处理中的退款单标记为失败
+func (r *RefundRepo) MarkFailed(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&model.Refund{}).
+		Where("id = ? AND status = ?", id, model.RefundStatusPending).
+		Update("status", model.RefundStatusFailed).Error
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *RefundRepo) GetDB() *gorm.DB {
+	return r.db
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"encoding/json"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SKU相关操作
+type SKURepo struct {
+	db *gorm.DB
+}
+
+func NewSKURepo(db *gorm.DB) *SKURepo {
+	return &SKURepo{db: db}
+}
+
+// Create 创建SKU
+func (r *SKURepo) Create(ctx context.Context, sku *model.SKU) error {
+	return r.db.WithContext(ctx).Create(sku).Error
+}
+
+// GetByID 根据ID查询SKU
+func (r *SKURepo) GetByID(ctx context.Context, skuID int) (*model.SKU, error) {
+	var sku model.SKU
+	if err := r.db.WithContext(ctx).Where("id = ?", skuID).First(&sku).Error; err != nil {
+		return nil, err
+	}
+	return &sku, nil
+}
+
+// Update 更新SKU（乐观锁） 只有版本号匹配时才更新 同时版本号+1
+func (r *SKURepo) Update(ctx context.Context, skuID, version int, updates map[string]interface{}) error {
+	return r.UpdateWithTx(ctx, r.db.WithContext(ctx), skuID, version, updates)
+}
+
+// UpdateWithTx 在外部事务中更新SKU（乐观锁）
+func (r *SKURepo) UpdateWithTx(ctx context.Context, tx *gorm.DB, skuID, version int, updates map[string]interface{}) error {
+	// 使用map更新时不会经过字段的serializer 规格属性需要手动序列化
+	if attrs, ok := updates["attributes"].([]model.SKUAttribute); ok {
+		attrsJSON, err := json.Marshal(attrs)
+		if err != nil {
+			return err
+		}
+		updates["attributes"] = string(attrsJSON)
+	}
+	updates["version"] = gorm.Expr("version + 1")
+	result := tx.Model(&model.SKU{}).
+		Where("id = ? AND version = ?", skuID, version).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.notFoundOrConflict(ctx, skuID)
+	}
+	return nil
+}
+
+// GetPriceForUpdateWithTx 在事务中查询SKU当前价格并加行锁 保证记录的变更前价格准确
+func (r *SKURepo) GetPriceForUpdateWithTx(tx *gorm.DB, skuID int) (float64, error) {
+	var sku model.SKU
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "price").
+		Where("id = ?", skuID).First(&sku).Error
+	return sku.Price, err
+}
+
+// Delete 删除SKU 已下单的订单中保存了规格快照 所以直接物理删除
+func (r *SKURepo) Delete(ctx context.Context, productID, skuID int) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND product_id = ?", skuID, productID).Delete(&model.SKU{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DecreaseStockWithTx 在外部事务中扣减SKU库存
+// 库存判断和扣减在同一条UPDATE中完成 数据库行锁保证不会超卖 不需要额外的分布式锁
+func (r *SKURepo) DecreaseStockWithTx(tx *gorm.DB, skuID, quantity int) error {
+	result := tx.Model(&model.SKU{}).
+		Where("id = ? AND stock >= ?", skuID, quantity).
+		Updates(map[string]interface{}{
+			"stock":   gorm.Expr("stock - ?", quantity),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// notFoundOrConflict 乐观锁更新失败时区分是SKU不存在还是版本冲突
+func (r *SKURepo) notFoundOrConflict(ctx context.Context, skuID int) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.SKU{}).Where("id = ?", skuID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrVersionConflict
+}
+
+// IncreaseStockWithTx 在外部事务中退回SKU库存 SKU已删除时返回gorm.ErrRecordNotFound
+func (r *SKURepo) IncreaseStockWithTx(tx *gorm.DB, skuID, quantity int) error {
+	result := tx.Model(&model.SKU{}).Where("id = ?", skuID).
+		Updates(map[string]interface{}{
+			"stock":   gorm.Expr("stock + ?", quantity),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
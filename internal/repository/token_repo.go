@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrRefreshTokenRevoked 刷新令牌已被使用、吊销或过期
+var ErrRefreshTokenRevoked = errors.New("刷新令牌已失效")
+
+// TokenRepo 刷新令牌登记 只有登记过且未被吊销的刷新令牌才能换取新令牌
+type TokenRepo struct {
+	redisClient *redis.Client
+}
+
+func NewTokenRepo(redisClient *redis.Client) *TokenRepo {
+	return &TokenRepo{redisClient: redisClient}
+}
+
+// refreshTokenKey 刷新令牌的登记key 值为用户ID
+// 格式: auth:refresh:{jti}
+func refreshTokenKey(tokenID string) string {
+	return "auth:refresh:" + tokenID
+}
+
+// userRefreshTokensKey 用户持有的刷新令牌集合 用于吊销用户的所有令牌
+// 格式: auth:user_refresh:{user_id}
+func userRefreshTokensKey(userID int) string {
+	return fmt.Sprintf("auth:user_refresh:%d", userID)
+}
+
+// SaveRefresh 登记刷新令牌
+func (r *TokenRepo) SaveRefresh(ctx context.Context, userID int, tokenID string, ttl time.Duration) error {
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, refreshTokenKey(tokenID), userID, ttl)
+		pipe.SAdd(ctx, userRefreshTokensKey(userID), tokenID)
+		// 集合的过期时间跟随最新的令牌 令牌全部过期后集合也会被删除
+		pipe.Expire(ctx, userRefreshTokensKey(userID), ttl)
+		return nil
+	})
+	return err
+}
+
+// ConsumeRefresh 使用刷新令牌 每个令牌只能使用一次 返回登记的用户ID
+func (r *TokenRepo) ConsumeRefresh(ctx context.Context, tokenID string) (int, error) {
+	value, err := r.redisClient.GetDel(ctx, refreshTokenKey(tokenID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrRefreshTokenRevoked
+	}
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, ErrRefreshTokenRevoked
+	}
+	r.redisClient.SRem(ctx, userRefreshTokensKey(userID), tokenID)
+	return userID, nil
+}
+
+// RevokeRefresh 吊销单个刷新令牌 用于退出登录
+func (r *TokenRepo) RevokeRefresh(ctx context.Context, userID int, tokenID string) error {
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, refreshTokenKey(tokenID))
+		pipe.SRem(ctx, userRefreshTokensKey(userID), tokenID)
+		return nil
+	})
+	return err
+}
+
+// RevokeAllForUser 吊销用户的所有刷新令牌 用于修改密码和禁用账号
+func (r *TokenRepo) RevokeAllForUser(ctx context.Context, userID int) error {
+	setKey := userRefreshTokensKey(userID)
+	tokenIDs, err := r.redisClient.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(tokenIDs)+1)
+	for _, id := range tokenIDs {
+		keys = append(keys, refreshTokenKey(id))
+	}
+	keys = append(keys, setKey)
+	return r.redisClient.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"demo01/internal/model"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+var ErrUsernameTaken = errors.New("用户名已被注册")
+
+// MySQL唯一索引冲突错误码
+const mysqlDuplicateEntry = 1062
+
+// 用户相关操作
+type UserRepo struct {
+	db *gorm.DB
+}
+
+func NewUserRepo(db *gorm.DB) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+// Create 创建用户 用户名重复时返回ErrUsernameTaken
+func (r *UserRepo) Create(ctx context.Context, user *model.User) error {
+	err := r.db.WithContext(ctx).Create(user).Error
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+// GetByID 根据ID查询用户
+func (r *UserRepo) GetByID(ctx context.Context, id int) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername 根据用户名查询用户
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update 更新用户字段
+func (r *UserRepo) Update(ctx context.Context, id int, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// MySQL在值未变化时影响行数也为0 需要区分用户不存在
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// UpdateLastLogin 记录最后登录时间
+func (r *UserRepo) UpdateLastLogin(ctx context.Context, id int, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).
+		UpdateColumn("last_login_at", at).Error
+}
+
+// List 按条件分页查询用户 同时返回符合条件的总数
+func (r *UserRepo) List(ctx context.Context, filter model.UserFilter) ([]model.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Keyword != "" {
+		like := "%" + escapeLike(filter.Keyword) + "%"
+		query = query.Where("(username LIKE ? OR nickname LIKE ? OR email LIKE ? OR phone LIKE ?)", like, like, like, like)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []model.User
+	err := query.Order("id").
+		Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize).
+		Find(&users).Error
+	return users, total, err
+}
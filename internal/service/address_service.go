@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddressInput 新增或修改收货地址的内容
+type AddressInput struct {
+	Receiver  string
+	Phone     string
+	Province  string
+	City      string
+	District  string
+	Detail    string
+	IsDefault bool
+}
+
+// AddressService 用户收货地址簿
+type AddressService struct {
+	addressRepo *repository.AddressRepo
+}
+
+// NewAddressService 创建收货地址服务实例
+func NewAddressService(addressRepo *repository.AddressRepo) *AddressService {
+	return &AddressService{addressRepo: addressRepo}
+}
+
+// ListAddresses 查询用户的所有收货地址
+func (s *AddressService) ListAddresses(ctx context.Context, userID int) ([]model.Address, error) {
+	addresses, err := s.addressRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询收货地址失败", err)
+	}
+	return addresses, nil
+}
+
+// GetAddress 查询用户的某个收货地址
+func (s *AddressService) GetAddress(ctx context.Context, userID, id int) (*model.Address, error) {
+	address, err := s.addressRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, addressError(err)
+	}
+	return address, nil
+}
+
+// CreateAddress 新增收货地址 用户的第一个地址自动设为默认
+func (s *AddressService) CreateAddress(ctx context.Context, userID int, input AddressInput) (*model.Address, error) {
+	// 1. 参数验证
+	address := input.toAddress()
+	address.UserID = userID
+	if err := address.Validate(); err != nil {
+		return nil, util.NewBusinessError("INVALID_ADDRESS", err.Error(), util.ErrInvalidInput)
+	}
+
+	// 2. 检查数量上限
+	count, err := s.addressRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询收货地址失败", err)
+	}
+	if count >= model.MaxAddressesPerUser {
+		return nil, util.NewBusinessError("ADDRESS_LIMIT", fmt.Sprintf("最多只能保存%d个收货地址", model.MaxAddressesPerUser), util.ErrInvalidInput)
+	}
+	if count == 0 {
+		address.IsDefault = true
+	}
+
+	// 3. 写入数据库
+	if err := s.addressRepo.Create(ctx, address); err != nil {
+		return nil, util.NewBusinessError("ADDRESS_SAVE_FAILED", "保存收货地址失败", err)
+	}
+	return address, nil
+}
+
+// UpdateAddress 修改收货地址 默认地址不能通过修改取消默认 只能把其他地址设为默认
+func (s *AddressService) UpdateAddress(ctx context.Context, userID, id int, input AddressInput) (*model.Address, error) {
+	existing, err := s.GetAddress(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	address := input.toAddress()
+	address.ID = existing.ID
+	address.UserID = userID
+	address.IsDefault = input.IsDefault || existing.IsDefault
+	if err := address.Validate(); err != nil {
+		return nil, util.NewBusinessError("INVALID_ADDRESS", err.Error(), util.ErrInvalidInput)
+	}
+
+	if err := s.addressRepo.Update(ctx, address); err != nil {
+		return nil, util.NewBusinessError("ADDRESS_SAVE_FAILED", "保存收货地址失败", err)
+	}
+	return s.GetAddress(ctx, userID, id)
+}
+
+// SetDefault 设置默认收货地址
+func (s *AddressService) SetDefault(ctx context.Context, userID, id int) (*model.Address, error) {
+	if err := s.addressRepo.SetDefault(ctx, userID, id); err != nil {
+		return nil, addressError(err)
+	}
+	return s.GetAddress(ctx, userID, id)
+}
+
+// DeleteAddress 删除收货地址 已下单的订单保存的是地址快照 不受影响
+func (s *AddressService) DeleteAddress(ctx context.Context, userID, id int) error {
+	if err := s.addressRepo.Delete(ctx, userID, id); err != nil {
+		return addressError(err)
+	}
+	return nil
+}
+
+// ResolveShippingAddress 确定订单的收货地址并生成快照
+// addressID为0时使用默认地址 没有默认地址时返回错误 订单必须有收货地址
+func (s *AddressService) ResolveShippingAddress(ctx context.Context, userID, addressID int) (*model.ShippingAddress, error) {
+	if addressID != 0 {
+		address, err := s.GetAddress(ctx, userID, addressID)
+		if err != nil {
+			return nil, err
+		}
+		return address.Snapshot(), nil
+	}
+
+	address, err := s.addressRepo.GetDefault(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("ADDRESS_REQUIRED", "请先添加收货地址或指定收货地址", util.ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询收货地址失败", err)
+	}
+	return address.Snapshot(), nil
+}
+
+// toAddress 转换为地址模型并去掉首尾空白
+func (in AddressInput) toAddress() *model.Address {
+	address := &model.Address{
+		Receiver:  in.Receiver,
+		Phone:     in.Phone,
+		Province:  in.Province,
+		City:      in.City,
+		District:  in.District,
+		Detail:    in.Detail,
+		IsDefault: in.IsDefault,
+	}
+	address.Normalize()
+	return address
+}
+
+// addressError 将repo层的错误转换为业务错误
+func addressError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.NewBusinessError("ADDRESS_NOT_FOUND", "收货地址不存在", util.ErrNotFound)
+	}
+	return util.NewBusinessError("ADDRESS_SAVE_FAILED", "操作收货地址失败", err)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"time"
+)
+
+// TokenPair 登录或刷新后返回的令牌
+type TokenPair struct {
+	AccessToken  string      `json:"access_token"`
+	RefreshToken string      `json:"refresh_token"`
+	TokenType    string      `json:"token_type"` // Bearer
+	ExpiresIn    int64       `json:"expires_in"` // 访问令牌有效期 单位秒
+	User         *model.User `json:"user"`
+}
+
+// AuthService 签发和校验JWT 刷新令牌登记在Redis中 可以吊销
+type AuthService struct {
+	userService *UserService
+	tokenRepo   *repository.TokenRepo
+	secret      []byte
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+}
+
+// NewAuthService 创建认证服务实例
+func NewAuthService(userService *UserService, tokenRepo *repository.TokenRepo, secret []byte, accessTTL, refreshTTL time.Duration) *AuthService {
+	return &AuthService{
+		userService: userService,
+		tokenRepo:   tokenRepo,
+		secret:      secret,
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
+	}
+}
+
+// Login 校验用户名和密码后签发令牌
+func (s *AuthService) Login(ctx context.Context, username, password string) (*TokenPair, error) {
+	user, err := s.userService.Login(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user)
+}
+
+// Refresh 使用刷新令牌换取新的令牌 旧的刷新令牌立即失效
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	// 1. 校验签名和类型
+	claims, err := s.parse(refreshToken, util.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 每个刷新令牌只能使用一次 被吊销或已使用的令牌无法再换取
+	if _, err := s.tokenRepo.ConsumeRefresh(ctx, claims.ID); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+			return nil, util.NewBusinessError("TOKEN_REVOKED", err.Error(), util.ErrUnauthorized)
+		}
+		return nil, util.NewBusinessError("TOKEN_REFRESH_FAILED", "刷新令牌失败", err)
+	}
+
+	// 3. 重新读取用户 角色变更和禁用在刷新时生效
+	user, err := s.userService.GetUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, util.NewBusinessError("USER_NOT_FOUND", "用户不存在", util.ErrUnauthorized)
+	}
+	if !user.IsActive() {
+		return nil, util.NewBusinessError("USER_DISABLED", "账号已被禁用", util.ErrUnauthorized)
+	}
+	return s.issue(ctx, user)
+}
+
+// Logout 吊销刷新令牌 访问令牌在过期前仍然有效 所以访问令牌的有效期应当设置得比较短
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := s.parse(refreshToken, util.TokenTypeRefresh)
+	if err != nil {
+		return err
+	}
+	if err := s.tokenRepo.RevokeRefresh(ctx, claims.UserID, claims.ID); err != nil {
+		return util.NewBusinessError("TOKEN_REVOKE_FAILED", "退出登录失败", err)
+	}
+	return nil
+}
+
+// RevokeUserTokens 吊销用户的所有刷新令牌 修改密码或禁用账号后调用
+func (s *AuthService) RevokeUserTokens(ctx context.Context, userID int) error {
+	if err := s.tokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return util.NewBusinessError("TOKEN_REVOKE_FAILED", "吊销令牌失败", err)
+	}
+	return nil
+}
+
+// Authenticate 校验访问令牌 供中间件使用 不查询数据库
+func (s *AuthService) Authenticate(accessToken string) (*util.TokenClaims, error) {
+	return s.parse(accessToken, util.TokenTypeAccess)
+}
+
+// issue 签发访问令牌和刷新令牌 并登记刷新令牌
+func (s *AuthService) issue(ctx context.Context, user *model.User) (*TokenPair, error) {
+	access := util.NewTokenClaims(user.ID, user.Username, user.Role, util.TokenTypeAccess, s.accessTTL)
+	refresh := util.NewTokenClaims(user.ID, user.Username, user.Role, util.TokenTypeRefresh, s.refreshTTL)
+
+	accessToken, err := util.SignToken(s.secret, access)
+	if err != nil {
+		return nil, util.NewBusinessError("TOKEN_SIGN_FAILED", "签发令牌失败", err)
+	}
+	refreshToken, err := util.SignToken(s.secret, refresh)
+	if err != nil {
+		return nil, util.NewBusinessError("TOKEN_SIGN_FAILED", "签发令牌失败", err)
+	}
+	if err := s.tokenRepo.SaveRefresh(ctx, user.ID, refresh.ID, s.refreshTTL); err != nil {
+		return nil, util.NewBusinessError("TOKEN_SIGN_FAILED", "登记刷新令牌失败", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+		User:         user,
+	}, nil
+}
+
+// parse 校验令牌并检查令牌类型 防止用刷新令牌调用接口
+func (s *AuthService) parse(token, tokenType string) (*util.TokenClaims, error) {
+	claims, err := util.ParseToken(s.secret, token)
+	if errors.Is(err, util.ErrTokenExpired) {
+		return nil, util.NewBusinessError("TOKEN_EXPIRED", err.Error(), util.ErrUnauthorized)
+	}
+	if err != nil || claims.Type != tokenType {
+		return nil, util.NewBusinessError("TOKEN_INVALID", util.ErrTokenInvalid.Error(), util.ErrUnauthorized)
+	}
+	return claims, nil
+}
@@ -0,0 +1,9 @@
+package service
+
+import "demo01/internal/util"
+
+// CacheTierStats 各级缓存的统计信息
+type CacheTierStats struct {
+	Local util.CacheStats `json:"local"` // 本地缓存
+	Redis util.CacheStats `json:"redis"` // Redis缓存 size为SCAN统计的key数量
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 购物车商品不可购买的原因
+const (
+	CartUnavailableNotFound     = "product_not_found"  // 商品已删除
+	CartUnavailableOffShelf     = "not_purchasable"    // 商品已下架或停售
+	CartUnavailableSKUNotFound  = "sku_not_found"      // 规格已删除
+	CartUnavailableInsufficient = "insufficient_stock" // 库存不足
+)
+
+// CartLine 购物车中的一行 价格和库存为实时数据
+type CartLine struct {
+	ID        string    `json:"id"`
+	ProductID int       `json:"product_id"`
+	SKUID     int       `json:"sku_id,omitempty"`
+	SKUName   string    `json:"sku_name,omitempty"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	Quantity  int       `json:"quantity"`
+	Stock     int       `json:"stock"`
+	Subtotal  float64   `json:"subtotal"`
+	Selected  bool      `json:"selected"`
+	Available bool      `json:"available"`
+	Reason    string    `json:"reason,omitempty"` // 不可购买的原因
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// Cart 购物车 合计只统计勾选且可购买的商品
+type Cart struct {
+	Lines         []CartLine `json:"lines"`
+	SelectedCount int        `json:"selected_count"`
+	TotalQuantity int        `json:"total_quantity"`
+	TotalAmount   float64    `json:"total_amount"`
+}
+
+// CartItemUpdate 购物车商品修改内容 nil表示不修改
+type CartItemUpdate struct {
+	Quantity *int
+	Selected *bool
+}
+
+// CartService 购物车 数据保存在Redis中 结算时调用订单服务创建订单
+type CartService struct {
+	cartRepo       *repository.CartRepo
+	productRepo    *repository.ProductRepo
+	inventoryRepo  *repository.InventoryRepo
+	productService *ProductService
+	orderService   *OrderService
+}
+
+// NewCartService 创建购物车服务实例
+func NewCartService(cartRepo *repository.CartRepo, productRepo *repository.ProductRepo, inventoryRepo *repository.InventoryRepo, productService *ProductService, orderService *OrderService) *CartService {
+	return &CartService{
+		cartRepo:       cartRepo,
+		productRepo:    productRepo,
+		inventoryRepo:  inventoryRepo,
+		productService: productService,
+		orderService:   orderService,
+	}
+}
+
+// AddItem 加入购物车 同一商品同一规格已存在时累加数量
+func (s *CartService) AddItem(ctx context.Context, userID, productID, skuID, quantity int) (*model.CartItem, error) {
+	// 1. 参数验证
+	if err := model.ValidateCartQuantity(quantity); err != nil {
+		return nil, util.NewBusinessError("INVALID_QUANTITY", err.Error(), util.ErrInvalidInput)
+	}
+
+	// 2. 校验商品可以购买 多规格商品必须选择规格
+	product, err := s.productService.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsPurchasable() {
+		return nil, util.NewBusinessError("PRODUCT_NOT_AVAILABLE", fmt.Sprintf("商品不可购买: %s", product.Name), util.ErrInvalidInput)
+	}
+	if product.HasSKUs() && skuID == 0 {
+		return nil, util.NewBusinessError("SKU_REQUIRED", fmt.Sprintf("请选择商品规格: %s", product.Name), util.ErrInvalidInput)
+	}
+	if skuID != 0 && product.FindSKU(skuID) == nil {
+		return nil, util.NewBusinessError("SKU_NOT_FOUND", fmt.Sprintf("SKU不属于该商品: %d", skuID), util.ErrInvalidInput)
+	}
+
+	// 3. 原子地写入购物车 已在购物车中的累加数量 新商品检查购物车行数上限
+	item, err := s.cartRepo.AddItem(ctx, userID, &model.CartItem{
+		ProductID: productID,
+		SKUID:     skuID,
+		Quantity:  quantity,
+		Selected:  true,
+		AddedAt:   time.Now(),
+	}, model.MaxCartLines, model.MaxCartItemQuantity)
+	switch {
+	case errors.Is(err, model.ErrCartFull):
+		return nil, util.NewBusinessError("CART_FULL", err.Error(), util.ErrInvalidInput)
+	case errors.Is(err, model.ErrCartQuantityExceeded):
+		return nil, util.NewBusinessError("INVALID_QUANTITY", err.Error(), util.ErrInvalidInput)
+	case err != nil:
+		return nil, util.NewBusinessError("CART_UPDATE_FAILED", "更新购物车失败", err)
+	}
+	return item, nil
+}
+
+// UpdateItem 修改购物车商品的数量或勾选状态
+func (s *CartService) UpdateItem(ctx context.Context, userID int, itemID string, update CartItemUpdate) (*model.CartItem, error) {
+	if update.Quantity != nil {
+		if err := model.ValidateCartQuantity(*update.Quantity); err != nil {
+			return nil, util.NewBusinessError("INVALID_QUANTITY", err.Error(), util.ErrInvalidInput)
+		}
+	}
+
+	item, err := s.getItem(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Quantity != nil {
+		item.Quantity = *update.Quantity
+	}
+	if update.Selected != nil {
+		item.Selected = *update.Selected
+	}
+
+	if err := s.cartRepo.Save(ctx, userID, item); err != nil {
+		return nil, util.NewBusinessError("CART_UPDATE_FAILED", "更新购物车失败", err)
+	}
+	return item, nil
+}
+
+// RemoveItem 从购物车删除商品
+func (s *CartService) RemoveItem(ctx context.Context, userID int, itemID string) error {
+	if _, _, err := model.ParseCartItemID(itemID); err != nil {
+		return util.NewBusinessError("INVALID_CART_ITEM", err.Error(), util.ErrInvalidInput)
+	}
+	removed, err := s.cartRepo.Remove(ctx, userID, itemID)
+	if err != nil {
+		return util.NewBusinessError("CART_UPDATE_FAILED", "更新购物车失败", err)
+	}
+	if removed == 0 {
+		return util.NewBusinessError("CART_ITEM_NOT_FOUND", "购物车中没有该商品", util.ErrNotFound)
+	}
+	return nil
+}
+
+// ClearCart 清空购物车
+func (s *CartService) ClearCart(ctx context.Context, userID int) error {
+	if err := s.cartRepo.Clear(ctx, userID); err != nil {
+		return util.NewBusinessError("CART_UPDATE_FAILED", "清空购物车失败", err)
+	}
+	return nil
+}
+
+// GetCart 查看购物车 价格和库存从数据库实时读取 不使用商品缓存
+func (s *CartService) GetCart(ctx context.Context, userID int) (*Cart, error) {
+	// 1. 读取购物车
+	items, err := s.cartRepo.GetAll(ctx, userID)
+	if err != nil {
+		return nil, util.NewBusinessError("CART_QUERY_FAILED", "查询购物车失败", err)
+	}
+	cart := &Cart{Lines: make([]CartLine, 0, len(items))}
+	if len(items) == 0 {
+		return cart, nil
+	}
+
+	// 2. 批量查询商品和库存 无规格商品的库存以库存表为准 与下单扣减保持一致
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+	ids = uniqueIDs(ids)
+	products, err := s.productRepo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询商品失败", err)
+	}
+	stocks, err := s.inventoryRepo.GetStocks(ctx, ids)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询库存失败", err)
+	}
+	productMap := make(map[int]*model.Product, len(products))
+	for i := range products {
+		productMap[products[i].ID] = &products[i]
+	}
+
+	// 3. 组装每一行并计算合计
+	for _, item := range items {
+		line := BuildCartLine(item, productMap[item.ProductID], stocks)
+		if line.Selected && line.Available {
+			cart.SelectedCount++
+			cart.TotalQuantity += line.Quantity
+			cart.TotalAmount += line.Subtotal
+		}
+		cart.Lines = append(cart.Lines, line)
+	}
+	return cart, nil
+}
+
+// Checkout 结算购物车 itemIDs为空时结算所有勾选的商品 opts为收货地址等下单参数
+// 订单创建成功后从购物车中删除已结算的商品 失败时购物车保持不变
+func (s *CartService) Checkout(ctx context.Context, userID int, itemIDs []string, opts CreateOrderOptions) (*model.Order, error) {
+	// 1. 选出要结算的商品
+	items, err := s.cartRepo.GetAll(ctx, userID)
+	if err != nil {
+		return nil, util.NewBusinessError("CART_QUERY_FAILED", "查询购物车失败", err)
+	}
+	selected, err := SelectCartItems(items, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 价格、规格和库存由订单服务在下单时重新校验
+	orderItems := make([]model.OrderItem, 0, len(selected))
+	lineIDs := make([]string, 0, len(selected))
+	for _, item := range selected {
+		orderItems = append(orderItems, model.OrderItem{
+			ProductID: item.ProductID,
+			SKUID:     item.SKUID,
+			Quantity:  item.Quantity,
+		})
+		lineIDs = append(lineIDs, item.ID())
+	}
+	order, err := s.orderService.CreateOrder(ctx, strconv.Itoa(userID), orderItems, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 删除已结算的商品 失败只记录日志 订单已经创建成功
+	if _, err := s.cartRepo.Remove(ctx, userID, lineIDs...); err != nil {
+		util.GlobalLogger.Error(ctx, "结算后清理购物车失败", err,
+			util.Field{Key: "user_id", Value: userID},
+			util.Field{Key: "order_id", Value: order.ID},
+		)
+	}
+	return order, nil
+}
+
+// getItem 查询购物车中的一行 不存在时返回业务错误
+func (s *CartService) getItem(ctx context.Context, userID int, itemID string) (*model.CartItem, error) {
+	if _, _, err := model.ParseCartItemID(itemID); err != nil {
+		return nil, util.NewBusinessError("INVALID_CART_ITEM", err.Error(), util.ErrInvalidInput)
+	}
+	item, err := s.cartRepo.Get(ctx, userID, itemID)
+	if err != nil {
+		return nil, util.NewBusinessError("CART_QUERY_FAILED", "查询购物车失败", err)
+	}
+	if item == nil {
+		return nil, util.NewBusinessError("CART_ITEM_NOT_FOUND", "购物车中没有该商品", util.ErrNotFound)
+	}
+	return item, nil
+}
+
+// SelectCartItems 按行ID选出要结算的商品 未指定时选出所有勾选的商品
+func SelectCartItems(items []model.CartItem, itemIDs []string) ([]model.CartItem, error) {
+	var selected []model.CartItem
+	if len(itemIDs) == 0 {
+		for _, item := range items {
+			if item.Selected {
+				selected = append(selected, item)
+			}
+		}
+	} else {
+		itemMap := make(map[string]model.CartItem, len(items))
+		for _, item := range items {
+			itemMap[item.ID()] = item
+		}
+		seen := make(map[string]bool, len(itemIDs))
+		for _, id := range itemIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			item, ok := itemMap[id]
+			if !ok {
+				return nil, util.NewBusinessError("CART_ITEM_NOT_FOUND", "购物车中没有该商品: "+id, util.ErrNotFound)
+			}
+			selected = append(selected, item)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, util.NewBusinessError("CART_EMPTY", "没有要结算的商品", util.ErrInvalidInput)
+	}
+	return selected, nil
+}
+
+// BuildCartLine 用实时的商品数据组装购物车行 product为nil表示商品已删除
+func BuildCartLine(item model.CartItem, product *model.Product, stocks map[int]int) CartLine {
+	line := CartLine{
+		ID:        item.ID(),
+		ProductID: item.ProductID,
+		SKUID:     item.SKUID,
+		Quantity:  item.Quantity,
+		Selected:  item.Selected,
+		AddedAt:   item.AddedAt,
+	}
+	if product == nil {
+		line.Reason = CartUnavailableNotFound
+		return line
+	}
+	line.Name = product.Name
+	line.Price = product.Price
+	line.Stock = stocks[product.ID]
+
+	if product.HasSKUs() || item.SKUID != 0 {
+		sku := product.FindSKU(item.SKUID)
+		if sku == nil {
+			line.Reason = CartUnavailableSKUNotFound
+			return line
+		}
+		line.SKUName = sku.VariantKey()
+		line.Price = sku.Price
+		line.Stock = sku.Stock
+	}
+
+	line.Subtotal = line.Price * float64(line.Quantity)
+	switch {
+	case !product.IsPurchasable():
+		line.Reason = CartUnavailableOffShelf
+	case line.Stock < line.Quantity:
+		line.Reason = CartUnavailableInsufficient
+	default:
+		line.Available = true
+	}
+	return line
+}
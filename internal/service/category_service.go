@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CategoryService 商品分类树的维护
+type CategoryService struct {
+	categoryRepo   *repository.CategoryRepo
+	productRepo    *repository.ProductRepo
+	productService *ProductService // 分类改名后同步商品缓存和全文索引
+}
+
+// NewCategoryService 创建分类服务实例
+func NewCategoryService(categoryRepo *repository.CategoryRepo, productRepo *repository.ProductRepo, productService *ProductService) *CategoryService {
+	return &CategoryService{
+		categoryRepo:   categoryRepo,
+		productRepo:    productRepo,
+		productService: productService,
+	}
+}
+
+// CreateCategory 创建分类 parentID为0时创建一级分类
+func (s *CategoryService) CreateCategory(ctx context.Context, name string, parentID, sortOrder int) (*model.Category, error) {
+	// 1. 参数验证
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "分类名称不能为空", util.ErrInvalidInput)
+	}
+
+	// 2. 校验父分类和层级
+	var parent *model.Category
+	if parentID != 0 {
+		var err error
+		if parent, err = s.getCategory(ctx, parentID); err != nil {
+			return nil, err
+		}
+		if parent.Level >= model.MaxCategoryLevel {
+			return nil, util.NewBusinessError("CATEGORY_TOO_DEEP",
+				fmt.Sprintf("分类最多%d级", model.MaxCategoryLevel), util.ErrInvalidInput)
+		}
+	}
+
+	// 3. 写入数据库
+	category := &model.Category{Name: name, SortOrder: sortOrder}
+	if err := s.categoryRepo.Create(ctx, category, parent); err != nil {
+		return nil, util.NewBusinessError("CATEGORY_CREATE_FAILED", "创建分类失败", err)
+	}
+	return category, nil
+}
+
+// GetCategoryTree 获取完整的分类树
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]*model.Category, error) {
+	categories, err := s.categoryRepo.List(ctx)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询分类失败", err)
+	}
+	return model.BuildCategoryTree(categories), nil
+}
+
+// GetCategory 获取分类及其子树
+func (s *CategoryService) GetCategory(ctx context.Context, id int) (*model.Category, error) {
+	category, err := s.getCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subtree, err := s.categoryRepo.ListSubtree(ctx, category)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询子分类失败", err)
+	}
+	for _, root := range model.BuildCategoryTree(subtree) {
+		if root.ID == id {
+			return root, nil
+		}
+	}
+	return category, nil
+}
+
+// CategoryUpdate 分类更新字段 nil表示不修改
+type CategoryUpdate struct {
+	Name      *string
+	ParentID  *int // 修改父分类会连同子孙分类一起移动 0表示移动为一级分类
+	SortOrder *int
+}
+
+// UpdateCategory 更新分类名称、排序或移动到其他父分类下
+func (s *CategoryService) UpdateCategory(ctx context.Context, id int, update CategoryUpdate) (*model.Category, error) {
+	category, err := s.getCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 基础字段
+	updates := make(map[string]interface{})
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, util.NewBusinessError("INVALID_PARAMS", "分类名称不能为空", util.ErrInvalidInput)
+		}
+		updates["name"] = name
+	}
+	if update.SortOrder != nil {
+		updates["sort_order"] = *update.SortOrder
+	}
+	if len(updates) == 0 && update.ParentID == nil {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "没有需要更新的字段", util.ErrInvalidInput)
+	}
+
+	// 2. 移动分类 校验不能移动到自身子树下 且移动后不超过最大层级
+	if update.ParentID != nil && *update.ParentID != category.ParentID {
+		if err := s.moveCategory(ctx, category, *update.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.categoryRepo.Update(ctx, id, updates); err != nil {
+			return nil, categoryWriteError(err)
+		}
+	}
+
+	// 3. 改名后同步商品上的分类名称
+	if name, ok := updates["name"].(string); ok && name != category.Name {
+		if err := s.productService.SyncCategoryName(ctx, id, name); err != nil {
+			util.GlobalLogger.Error(ctx, "同步商品分类名称失败", err,
+				util.Field{Key: "category_id", Value: id},
+			)
+		}
+	}
+	return s.getCategory(ctx, id)
+}
+
+// moveCategory 将分类移动到新的父分类下
+func (s *CategoryService) moveCategory(ctx context.Context, category *model.Category, parentID int) error {
+	var parent *model.Category
+	parentLevel := 0
+	if parentID != 0 {
+		var err error
+		if parent, err = s.getCategory(ctx, parentID); err != nil {
+			return err
+		}
+		parentLevel = parent.Level
+	}
+
+	// 子树整体移动后最深的分类不能超过最大层级
+	maxLevel, err := s.categoryRepo.MaxSubtreeLevel(ctx, category)
+	if err != nil {
+		return util.NewBusinessError("QUERY_FAILED", "查询子分类失败", err)
+	}
+	if maxLevel-category.Level+parentLevel+1 > model.MaxCategoryLevel {
+		return util.NewBusinessError("CATEGORY_TOO_DEEP",
+			fmt.Sprintf("分类最多%d级", model.MaxCategoryLevel), util.ErrInvalidInput)
+	}
+
+	if err := s.categoryRepo.Move(ctx, category, parent); err != nil {
+		return categoryWriteError(err)
+	}
+	return nil
+}
+
+// DeleteCategory 删除分类 存在子分类或商品时不允许删除
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int) error {
+	if _, err := s.getCategory(ctx, id); err != nil {
+		return err
+	}
+
+	children, err := s.categoryRepo.CountChildren(ctx, id)
+	if err != nil {
+		return util.NewBusinessError("QUERY_FAILED", "查询子分类失败", err)
+	}
+	if children > 0 {
+		return util.NewBusinessError("CATEGORY_NOT_EMPTY", "请先删除或移动子分类", util.ErrInvalidInput)
+	}
+	products, err := s.productRepo.CountByCategory(ctx, id)
+	if err != nil {
+		return util.NewBusinessError("QUERY_FAILED", "查询分类商品失败", err)
+	}
+	if products > 0 {
+		return util.NewBusinessError("CATEGORY_NOT_EMPTY",
+			fmt.Sprintf("分类下还有%d个商品，请先移动商品", products), util.ErrInvalidInput)
+	}
+
+	if err := s.categoryRepo.Delete(ctx, id); err != nil {
+		return categoryWriteError(err)
+	}
+	return nil
+}
+
+// getCategory 查询分类 不存在时返回业务错误
+func (s *CategoryService) getCategory(ctx context.Context, id int) (*model.Category, error) {
+	category, err := s.categoryRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("CATEGORY_NOT_FOUND", "分类不存在", util.ErrNotFound)
+	}
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询分类失败", err)
+	}
+	return category, nil
+}
+
+// categoryWriteError 将repo层的错误转换为业务错误
+func categoryWriteError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return util.NewBusinessError("CATEGORY_NOT_FOUND", "分类不存在", util.ErrNotFound)
+	case errors.Is(err, repository.ErrCategoryCycle):
+		return util.NewBusinessError("INVALID_PARENT", err.Error(), util.ErrInvalidInput)
+	default:
+		return util.NewBusinessError("CATEGORY_UPDATE_FAILED", "分类更新失败", err)
+	}
+}
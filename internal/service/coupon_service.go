@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CouponService 优惠券的管理和下单时的使用
+type CouponService struct {
+	couponRepo   *repository.CouponRepo
+	categoryRepo *repository.CategoryRepo
+}
+
+// NewCouponService 创建优惠券服务实例
+func NewCouponService(couponRepo *repository.CouponRepo, categoryRepo *repository.CategoryRepo) *CouponService {
+	return &CouponService{
+		couponRepo:   couponRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// CreateCoupon 创建优惠券
+func (s *CouponService) CreateCoupon(ctx context.Context, coupon *model.Coupon) error {
+	coupon.Code = model.NormalizeCouponCode(coupon.Code)
+	if coupon.Status == "" {
+		coupon.Status = model.CouponStatusActive
+	}
+	if err := coupon.Validate(); err != nil {
+		return util.NewBusinessError("INVALID_COUPON", err.Error(), util.ErrInvalidInput)
+	}
+	if err := s.couponRepo.Create(ctx, coupon); err != nil {
+		if errors.Is(err, repository.ErrCouponCodeTaken) {
+			return util.NewBusinessError("COUPON_CODE_TAKEN", err.Error(), util.ErrInvalidInput)
+		}
+		return util.NewBusinessError("COUPON_CREATE_FAILED", "创建优惠券失败", err)
+	}
+	return nil
+}
+
+// GetCoupon 查询优惠券
+func (s *CouponService) GetCoupon(ctx context.Context, id int) (*model.Coupon, error) {
+	coupon, err := s.couponRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("COUPON_NOT_FOUND", "优惠券不存在", util.ErrNotFound)
+	}
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询优惠券失败", err)
+	}
+	return coupon, nil
+}
+
+// ListCoupons 分页查询优惠券
+func (s *CouponService) ListCoupons(ctx context.Context, status string, page, pageSize int) ([]model.Coupon, int64, error) {
+	if status != "" && status != model.CouponStatusActive && status != model.CouponStatusDisabled {
+		return nil, 0, util.NewBusinessError("INVALID_STATUS", "无效的优惠券状态: "+status, util.ErrInvalidInput)
+	}
+	coupons, total, err := s.couponRepo.List(ctx, status, page, pageSize)
+	if err != nil {
+		return nil, 0, util.NewBusinessError("QUERY_FAILED", "查询优惠券列表失败", err)
+	}
+	return coupons, total, nil
+}
+
+// ChangeStatus 启用或停用优惠券 停用后已下的订单不受影响
+func (s *CouponService) ChangeStatus(ctx context.Context, id int, status string) (*model.Coupon, error) {
+	if status != model.CouponStatusActive && status != model.CouponStatusDisabled {
+		return nil, util.NewBusinessError("INVALID_STATUS", "无效的优惠券状态: "+status, util.ErrInvalidInput)
+	}
+	if err := s.couponRepo.UpdateStatus(ctx, id, status); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, util.NewBusinessError("COUPON_NOT_FOUND", "优惠券不存在", util.ErrNotFound)
+		}
+		return nil, util.NewBusinessError("COUPON_UPDATE_FAILED", "更新优惠券失败", err)
+	}
+	return s.GetCoupon(ctx, id)
+}
+
+// Apply 校验优惠券可以用于订单并计算优惠 优惠金额会分摊写入items
+// productMap为订单中的商品 用于判断商品和分类范围
+func (s *CouponService) Apply(ctx context.Context, code string, items []model.OrderItem, productMap map[int]*model.Product) (*model.Coupon, *model.OrderDiscount, error) {
+	// 1. 查询优惠券并检查状态和有效期
+	coupon, err := s.couponRepo.GetByCode(ctx, model.NormalizeCouponCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, util.NewBusinessError("COUPON_NOT_FOUND", "优惠券不存在", util.ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, nil, util.NewBusinessError("QUERY_FAILED", "查询优惠券失败", err)
+	}
+	if err := coupon.CheckUsable(time.Now()); err != nil {
+		return nil, nil, util.NewBusinessError("COUPON_UNAVAILABLE", err.Error(), util.ErrInvalidInput)
+	}
+
+	// 2. 判断每个订单项是否在适用范围内 分类范围包含子分类
+	var paths map[int]string
+	if len(coupon.CategoryIDs) > 0 {
+		categoryIDs := make([]int, 0, len(productMap))
+		for _, product := range productMap {
+			categoryIDs = append(categoryIDs, product.CategoryID)
+		}
+		if paths, err = s.categoryRepo.GetPaths(ctx, uniqueIDs(categoryIDs)); err != nil {
+			return nil, nil, util.NewBusinessError("QUERY_FAILED", "查询商品分类失败", err)
+		}
+	}
+	eligible := make([]bool, len(items))
+	for i, item := range items {
+		product := productMap[item.ProductID]
+		eligible[i] = product != nil && coupon.InScope(product.ID, paths[product.CategoryID])
+	}
+
+	// 3. 计算优惠
+	discount, err := coupon.Calculate(items, eligible)
+	if err != nil {
+		return nil, nil, util.NewBusinessError("COUPON_UNAVAILABLE", err.Error(), util.ErrInvalidInput)
+	}
+	return coupon, discount, nil
+}
+
+// Reserve 下单前预占一次使用次数 下单失败时需要调用Release归还
+func (s *CouponService) Reserve(ctx context.Context, coupon *model.Coupon, userID int) error {
+	err := s.couponRepo.Reserve(ctx, coupon.ID, userID, coupon.TotalLimit, coupon.PerUserLimit)
+	if errors.Is(err, model.ErrCouponSoldOut) || errors.Is(err, model.ErrCouponUserLimitReached) {
+		return util.NewBusinessError("COUPON_UNAVAILABLE", err.Error(), util.ErrInvalidInput)
+	}
+	if err != nil {
+		return util.NewBusinessError("COUPON_RESERVE_FAILED", "使用优惠券失败", err)
+	}
+	return nil
+}
+
+// Release 归还预占的使用次数 失败只记录日志
+// 下单失败往往是请求已经取消或超时 归还不跟随请求取消 否则次数会被一直占用
+func (s *CouponService) Release(ctx context.Context, couponID, userID int) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.couponRepo.Release(ctx, couponID, userID); err != nil {
+		util.GlobalLogger.Error(ctx, "归还优惠券使用次数失败", err,
+			util.Field{Key: "coupon_id", Value: couponID},
+			util.Field{Key: "user_id", Value: userID},
+		)
+	}
+}
+
+// RecordRedemptionWithTx 在下单事务中写入优惠券使用记录
+func (s *CouponService) RecordRedemptionWithTx(tx *gorm.DB, redemption *model.CouponRedemption) error {
+	return s.couponRepo.CreateRedemptionWithTx(tx, redemption)
+}
@@ -6,7 +6,9 @@ import (
 	"demo01/internal/repository"
 	"demo01/internal/util"
 	"encoding/json"
-	"sync"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,24 +19,62 @@ import (
 
 type OrderService struct {
 	// 订单服务 需要用到订单repo和库存的repo 去进行数据库的交互
-	orderRepo     *repository.OrderRepo
-	inventoryRepo *repository.InventoryRepo
-	localCache    sync.Map // 本地缓存 使用Sync.Map本地缓存 加速订单查询
-	// 读多写少的场景操作map 可以直接使用sync map 使用简单性能也比较好
-	// 读写较为均衡的场景 或者写较多 可以使用RWLock 好处是更加灵活地对map实现加锁 缺点是需要手动管理 并且有死锁风险
+	orderRepo      *repository.OrderRepo
+	inventoryRepo  *repository.InventoryRepo
+	productRepo    *repository.ProductRepo                // 下单前校验商品状态
+	skuRepo        *repository.SKURepo                    // 多规格商品扣减SKU库存
+	userService    *UserService                           // 下单前校验用户
+	addressService *AddressService                        // 下单时生成收货地址快照
+	couponService  *CouponService                         // 下单时使用优惠券
+	localCache     *util.LocalCache[string, *model.Order] // 本地缓存 加速订单查询
+	// 一开始使用的是sync.Map 读多写少的场景使用简单性能也比较好
+	// 但是sync.Map没有容量限制和过期机制 读过的订单会一直留在内存里 所以换成了带TTL和LRU淘汰的本地缓存
+	invalidator *util.CacheInvalidator          // 跨实例缓存失效广播 多副本部署时通知其他实例删除本地缓存
+	loader      *util.CacheLoader[*model.Order] // 缓存未命中时合并并发的数据库查询
+	redisStats  util.CacheCounter               // Redis缓存命中统计
 }
 
+// 订单本地缓存参数
+const (
+	orderLocalCacheSize = 10000
+	orderLocalCacheTTL  = 5 * time.Minute
+)
+
 // NewOrderService 创建订单服务实例
-func NewOrderService(orderRepo *repository.OrderRepo, inventoryRepo *repository.InventoryRepo) *OrderService {
-	return &OrderService{
+func NewOrderService(orderRepo *repository.OrderRepo, inventoryRepo *repository.InventoryRepo, productRepo *repository.ProductRepo, skuRepo *repository.SKURepo, userService *UserService, addressService *AddressService, couponService *CouponService, invalidator *util.CacheInvalidator) *OrderService {
+	s := &OrderService{
 		// 需要创建订单和扣减库存
-		orderRepo:     orderRepo,
-		inventoryRepo: inventoryRepo,
+		orderRepo:      orderRepo,
+		inventoryRepo:  inventoryRepo,
+		productRepo:    productRepo,
+		skuRepo:        skuRepo,
+		userService:    userService,
+		addressService: addressService,
+		couponService:  couponService,
+		localCache:     util.NewLocalCache[string, *model.Order](orderLocalCacheSize, orderLocalCacheTTL),
+		invalidator:    invalidator,
+		loader:         util.NewCacheLoader[*model.Order](),
 	}
+
+	// 收到其他实例（或自己）发布的订单失效消息后删除本地缓存
+	invalidator.Register(util.CacheEntityOrder, func(id string) {
+		if id == "" {
+			s.localCache.Purge()
+			return
+		}
+		s.localCache.Delete(id)
+	})
+	return s
+}
+
+// CreateOrderOptions 下单的可选参数
+type CreateOrderOptions struct {
+	AddressID  int    // 收货地址ID 为0时使用默认地址
+	CouponCode string // 优惠券码 为空时不使用优惠券
 }
 
 // CreateOrder 创建订单（带补偿机制）
-func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []model.OrderItem) (*model.Order, error) {
+func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []model.OrderItem, opts CreateOrderOptions) (*model.Order, error) {
 	// 性能监控
 	startTime := time.Now()
 
@@ -49,6 +89,37 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 		return nil, util.NewBusinessError("INVALID_PARAMS", "订单参数无效", util.ErrInvalidInput)
 	}
 
+	// 校验用户存在且没有被禁用
+	user, err := s.userService.GetActiveUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 收货地址以下单时的快照保存在订单上 之后修改地址簿不影响订单 没有收货地址时不能下单
+	shippingAddress, err := s.addressService.ResolveShippingAddress(ctx, user.ID, opts.AddressID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 校验商品是否存在且上架中 草稿、下架、停售的商品不能下单
+	// 多规格商品校验SKU 并以SKU价格和规格作为订单快照
+	productMap, err := s.resolveItems(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	// 使用优惠券 先计算优惠 再在Redis中原子地预占使用次数 下单失败时归还
+	var coupon *model.Coupon
+	var discount *model.OrderDiscount
+	if opts.CouponCode != "" {
+		if coupon, discount, err = s.couponService.Apply(ctx, opts.CouponCode, items, productMap); err != nil {
+			return nil, err
+		}
+		if err := s.couponService.Reserve(ctx, coupon, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 2. 生成订单ID（缩短格式，避免数据库字段长度限制）
 	orderID := "o" + time.Now().Format("0102150405") + userID
 
@@ -63,14 +134,33 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 	// 假设订单创建失败还可以进行重试 即使被取消支付或者订单创建失败 也可以通过补偿机制 将库存回补！
 	// 但是 事务也只能够保证库存扣减和订单创建的原子性 但是无法保证超卖问题 因为在判断库存扣减的过程中可能会发生库存判断失误
 	var order *model.Order
-	err := s.orderRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.orderRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 通过 Repository 层的事务方法扣减库存（使用分布式锁防止超卖）
 		for _, item := range items {
 			util.GlobalLogger.Debug(ctx, "扣减库存",
 				util.Field{Key: "product_id", Value: item.ProductID},
+				util.Field{Key: "sku_id", Value: item.SKUID},
 				util.Field{Key: "quantity", Value: item.Quantity},
 			)
 
+			// 多规格商品扣减SKU库存 与订单在同一事务中 订单创建失败时一起回滚
+			if item.SKUID != 0 {
+				if err := s.skuRepo.DecreaseStockWithTx(tx, item.SKUID, item.Quantity); err != nil {
+					util.GlobalLogger.Error(ctx, "SKU库存扣减失败", err,
+						util.Field{Key: "sku_id", Value: item.SKUID},
+						util.Field{Key: "quantity", Value: item.Quantity},
+					)
+					if errors.Is(err, repository.ErrInsufficientStock) {
+						return util.NewBusinessError("INSUFFICIENT_STOCK", "库存不足: "+item.SKUName, err)
+					}
+					return util.NewBusinessError("ORDER_CREATE_FAILED", "扣减SKU库存失败", err)
+				}
+				if err := s.productRepo.IncreaseSalesWithTx(tx, item.ProductID, item.Quantity); err != nil {
+					return util.NewBusinessError("ORDER_CREATE_FAILED", "更新商品销量失败", err)
+				}
+				continue
+			}
+
 			// 使用分布式锁保护库存扣减，防止超卖
 			if err := s.inventoryRepo.DecreaseStockWithDistributedLock(ctx, item.ProductID, item.Quantity); err != nil {
 				util.GlobalLogger.Error(ctx, "库存扣减失败", err,
@@ -82,6 +172,11 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 				// TODO error需要进一步精确
 				return util.NewBusinessError("INSUFFICIENT_STOCK", "库存不足", err)
 			}
+
+			// 累计商品销量 用于按销量排序和热门商品统计
+			if err := s.productRepo.IncreaseSalesWithTx(tx, item.ProductID, item.Quantity); err != nil {
+				return util.NewBusinessError("ORDER_CREATE_FAILED", "更新商品销量失败", err)
+			}
 		}
 
 		// 分割items 因为这里传递过来的是一个商品列表 需要将商品列表转换为json字符串
@@ -90,16 +185,27 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 			util.GlobalLogger.Error(ctx, "商品信息序列化失败", err)
 			return util.NewBusinessError("JSON_MARSHAL_FAILED", "商品信息序列化失败", err)
 		}
-		// 组装订单model
+		// 组装订单model 应付金额为商品总价减去优惠
+		totalAmount := calculateTotal(items)
+		if discount != nil {
+			totalAmount = model.RoundAmount(totalAmount - discount.Amount)
+		}
 		order = &model.Order{
 			// 一一对应 创建一个订单的对象 准备通过repo写入数据库
 			ID:          orderID,
 			UserID:      userID,
-			Items:       string(itemsJSON),     // 这里是一个商品列表 但是我在想 一个订单下单多个商品，查看单个商品订单详情怎么处理呢
-			TotalAmount: calculateTotal(items), // 计算商品总价值 即订单的总金额
-			Status:      "pending",             // 订单状态 待支付 已支付 已取消 已完成 这里可以定义枚举类型 如ORDER_STATUS_PENDING
+			Items:       string(itemsJSON), // 这里是一个商品列表 但是我在想 一个订单下单多个商品，查看单个商品订单详情怎么处理呢
+			TotalAmount: totalAmount,       // 订单的应付金额
+			Status:      model.OrderStatusPending,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
+
+			ShippingAddress: shippingAddress,
+		}
+		if discount != nil {
+			order.CouponCode = coupon.Code
+			order.DiscountAmount = discount.Amount
+			order.Discount = discount
 		}
 
 		// 通过 Repository 层的事务方法创建订单
@@ -112,19 +218,41 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 			return util.NewBusinessError("ORDER_CREATE_FAILED", "订单创建失败", err)
 		}
 
+		// 优惠券使用记录与订单一起提交 Redis计数丢失时可以据此恢复
+		if coupon != nil {
+			err := s.couponService.RecordRedemptionWithTx(tx, &model.CouponRedemption{
+				CouponID: coupon.ID,
+				UserID:   user.ID,
+				OrderID:  orderID,
+				Amount:   discount.Amount,
+			})
+			if err != nil {
+				return util.NewBusinessError("ORDER_CREATE_FAILED", "记录优惠券使用失败", err)
+			}
+		}
+
 		return nil
 	})
 
-	// 4. 事务失败处理
+	// 4. 事务失败处理 归还预占的优惠券使用次数
 	if err != nil {
 		util.GlobalLogger.Error(ctx, "订单创建事务失败", err,
 			util.Field{Key: "order_id", Value: orderID},
 		)
+		if coupon != nil {
+			s.couponService.Release(ctx, coupon.ID, user.ID)
+		}
 		return nil, err
 	}
 
-	// 5. 写入多级缓存
-	s.localCache.Store(orderID, order)
+	// 5. 写入多级缓存和布隆过滤器
+	if err := s.orderRepo.AddToBloom(ctx, orderID); err != nil {
+		util.GlobalLogger.Warn(ctx, "订单ID写入布隆过滤器失败",
+			util.Field{Key: "order_id", Value: orderID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	s.localCache.Set(orderID, order)
 	// 通过 Repository 层写入 Redis 缓存
 	if err := s.orderRepo.SetToCache(ctx, orderID, order); err != nil {
 		util.GlobalLogger.Warn(ctx, "Redis缓存写入失败",
@@ -132,6 +260,10 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 			util.Field{Key: "error", Value: err.Error()},
 		)
 	}
+	// 库存已变更 商品详情缓存中包含SKU库存 删除Redis缓存并通知所有实例删除本地缓存
+	for _, item := range items {
+		s.invalidateProduct(ctx, item.ProductID)
+	}
 
 	// 6. 记录完成时间
 	duration := time.Since(startTime)
@@ -144,24 +276,49 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 	return order, nil
 }
 
-// GetAllOrders 查询所有订单（分页）
-func (s *OrderService) GetAllOrders(ctx context.Context, page, pageSize int) ([]model.Order, error) {
+// OrderPage 订单列表的一页
+type OrderPage struct {
+	Orders     []model.Order `json:"orders"`
+	NextCursor string        `json:"next_cursor"` // 为空表示没有下一页
+	HasMore    bool          `json:"has_more"`
+}
+
+// SearchOrders 按条件查询订单（游标分页）
+func (s *OrderService) SearchOrders(ctx context.Context, filter model.OrderFilter) (*OrderPage, error) {
 	util.GlobalLogger.Debug(ctx, "查询订单列表",
-		util.Field{Key: "page", Value: page},
-		util.Field{Key: "page_size", Value: pageSize},
+		util.Field{Key: "user_id", Value: filter.UserID},
+		util.Field{Key: "status", Value: filter.Status},
+		util.Field{Key: "limit", Value: filter.Limit},
 	)
 
-	orders, err := s.orderRepo.GetAll(ctx, page, pageSize)
+	// 1. 参数验证
+	if filter.Status != "" && !model.IsValidOrderStatus(filter.Status) {
+		return nil, util.NewBusinessError("INVALID_STATUS", "无效的订单状态: "+filter.Status, util.ErrInvalidInput)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "开始时间不能晚于结束时间", util.ErrInvalidInput)
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "最低金额不能大于最高金额", util.ErrInvalidInput)
+	}
+
+	// 2. 查询 多查出的一条说明还有下一页
+	orders, err := s.orderRepo.Search(ctx, filter)
 	if err != nil {
 		util.GlobalLogger.Error(ctx, "查询订单列表失败", err)
 		return nil, util.NewBusinessError("QUERY_FAILED", "查询订单列表失败", err)
 	}
+	page := &OrderPage{Orders: orders}
+	if len(orders) > filter.Limit {
+		page.Orders = orders[:filter.Limit]
+		page.HasMore = true
+		page.NextCursor = model.EncodeOrderCursor(&page.Orders[filter.Limit-1])
+	}
 
 	util.GlobalLogger.Debug(ctx, "查询订单列表成功",
-		util.Field{Key: "count", Value: len(orders)},
+		util.Field{Key: "count", Value: len(page.Orders)},
 	)
-
-	return orders, nil
+	return page, nil
 }
 
 // GetOrder 根据id查询订单（多级缓存）
@@ -177,27 +334,70 @@ func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*model.Ord
 	// 其次 一般很少出现同时对订单不断刷新和修改的情况 所以并发程度不是很高 读取性能上不会那么耗时
 	// 如果追求用户体验的话 多级缓存也是合理的 响应非常快 只是不能跨会话 但是这一点redis能够做到补偿 并且能够反写回本地缓存
 	// 即使用户短时间多次刷新页面 也会有一个非常极速的响应
-	if orderValue, exists := s.localCache.Load(orderID); exists {
-		if order, ok := orderValue.(*model.Order); ok {
-			util.GlobalLogger.Debug(ctx, "从本地缓存获取订单",
-				util.Field{Key: "order_id", Value: orderID},
-			)
-			return order, nil
-		}
+	if order, exists := s.localCache.Get(orderID); exists {
+		util.GlobalLogger.Debug(ctx, "从本地缓存获取订单",
+			util.Field{Key: "order_id", Value: orderID},
+		)
+		return order, nil
 	}
 
-	// 2. 二级缓存：Redis缓存查询（较快）
-	if order, err := s.orderRepo.GetFromCache(ctx, orderID); err == nil {
+	// 2. 布隆过滤器拦截一定不存在的订单ID 防止缓存穿透
+	if !s.orderRepo.MightExist(ctx, orderID) {
+		util.GlobalLogger.Debug(ctx, "布隆过滤器拦截不存在的订单",
+			util.Field{Key: "order_id", Value: orderID},
+		)
+		return nil, util.NewBusinessError("ORDER_NOT_FOUND", "订单不存在", util.ErrNotFound)
+	}
+
+	// 3. 二级缓存：Redis缓存查询（较快） 命中空值缓存说明订单不存在
+	order, err := s.orderRepo.GetFromCache(ctx, orderID)
+	if errors.Is(err, repository.ErrCachedNotFound) {
+		s.redisStats.Hit()
+		return nil, util.NewBusinessError("ORDER_NOT_FOUND", "订单不存在", util.ErrNotFound)
+	}
+	if err == nil {
+		s.redisStats.Hit()
 		// 反序列化成功，存入本地缓存
-		s.localCache.Store(orderID, order)
+		s.localCache.Set(orderID, order)
 		util.GlobalLogger.Debug(ctx, "从Redis缓存获取订单",
 			util.Field{Key: "order_id", Value: orderID},
 		)
 		return order, nil
 	}
 
-	// 3. 三级存储：数据库查询（最慢）
-	order, err := s.orderRepo.GetByID(ctx, orderID)
+	s.redisStats.Miss()
+
+	// 4. 三级存储：数据库查询（最慢）
+	// 同一个订单的并发未命中合并为一次数据库查询 防止缓存击穿
+	order, err = s.loader.Load(ctx, util.OrderCacheKey(orderID),
+		func(ctx context.Context) (*model.Order, bool) {
+			order, err := s.orderRepo.GetFromCache(ctx, orderID)
+			return order, err == nil
+		},
+		func(ctx context.Context) (*model.Order, error) {
+			order, err := s.orderRepo.GetByID(ctx, orderID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// 写入空值缓存 短时间内同一个ID不再查数据库
+				if err := s.orderRepo.SetNotFoundToCache(ctx, orderID); err != nil {
+					util.GlobalLogger.Warn(ctx, "空值缓存写入失败",
+						util.Field{Key: "order_id", Value: orderID},
+						util.Field{Key: "error", Value: err.Error()},
+					)
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			// 通过 Repository 层回填 Redis 缓存
+			if err := s.orderRepo.SetToCache(ctx, orderID, order); err != nil {
+				util.GlobalLogger.Warn(ctx, "Redis缓存写入失败",
+					util.Field{Key: "order_id", Value: orderID},
+					util.Field{Key: "error", Value: err.Error()},
+				)
+			}
+			return order, nil
+		},
+	)
 	if err != nil {
 		util.GlobalLogger.Error(ctx, "查询订单失败", err,
 			util.Field{Key: "order_id", Value: orderID},
@@ -205,15 +405,8 @@ func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*model.Ord
 		return nil, util.NewBusinessError("ORDER_NOT_FOUND", "订单不存在", err)
 	}
 
-	// 4. 回填多级缓存
-	s.localCache.Store(orderID, order)
-	// 通过 Repository 层写入 Redis 缓存
-	if err := s.orderRepo.SetToCache(ctx, orderID, order); err != nil {
-		util.GlobalLogger.Warn(ctx, "Redis缓存写入失败",
-			util.Field{Key: "order_id", Value: orderID},
-			util.Field{Key: "error", Value: err.Error()},
-		)
-	}
+	// 5. 回填本地缓存
+	s.localCache.Set(orderID, order)
 
 	util.GlobalLogger.Debug(ctx, "从数据库获取订单并回填多级缓存",
 		util.Field{Key: "order_id", Value: orderID},
@@ -222,6 +415,130 @@ func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*model.Ord
 	return order, nil
 }
 
+// WarmUp 预热最近的订单到本地缓存和Redis缓存 返回已预热的订单
+func (s *OrderService) WarmUp(ctx context.Context, limit int) ([]model.Order, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	orders, err := s.orderRepo.GetRecent(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// 超出时间预算时停止预热 已加载的部分保留
+	n, err := WarmUpEach(ctx, orders, func(ctx context.Context, order *model.Order) error {
+		s.localCache.Set(order.ID, order)
+		return s.orderRepo.SetToCache(ctx, order.ID, order)
+	})
+	return orders[:n], err
+}
+
+// CacheStats 获取订单各级缓存的统计信息
+func (s *OrderService) CacheStats(ctx context.Context) (CacheTierStats, error) {
+	stats := CacheTierStats{
+		Local: s.localCache.Stats(),
+		Redis: s.redisStats.Stats(),
+	}
+	size, err := s.orderRepo.CountCache(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Redis.Size = size
+	return stats, nil
+}
+
+// EvictCache 删除单个订单的本地缓存和Redis缓存 并通知其他实例
+func (s *OrderService) EvictCache(ctx context.Context, orderID string) error {
+	s.localCache.Delete(orderID)
+	if err := s.orderRepo.DelFromCache(ctx, orderID); err != nil {
+		return err
+	}
+	s.publishInvalidation(ctx, util.CacheEntityOrder, orderID)
+	return nil
+}
+
+// PurgeCache 清空所有订单的本地缓存和Redis缓存 并通知其他实例 返回删除的Redis key数量
+func (s *OrderService) PurgeCache(ctx context.Context) (int64, error) {
+	s.localCache.Purge()
+	deleted, err := s.orderRepo.PurgeCache(ctx)
+	if err != nil {
+		return deleted, err
+	}
+	s.publishInvalidation(ctx, util.CacheEntityOrder, "")
+	return deleted, nil
+}
+
+// invalidateProduct 下单或退款改变库存后删除商品的Redis缓存 并通知所有实例删除本地缓存
+func (s *OrderService) invalidateProduct(ctx context.Context, productID int) {
+	if err := s.productRepo.DelFromCache(ctx, productID); err != nil {
+		util.GlobalLogger.Warn(ctx, "商品Redis缓存删除失败",
+			util.Field{Key: "product_id", Value: productID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	s.publishInvalidation(ctx, util.CacheEntityProduct, strconv.Itoa(productID))
+}
+
+// publishInvalidation 广播缓存失效消息 失败只记录日志 本地缓存还有TTL兜底
+func (s *OrderService) publishInvalidation(ctx context.Context, entity, id string) {
+	if err := s.invalidator.Publish(ctx, entity, id); err != nil {
+		util.GlobalLogger.Warn(ctx, "缓存失效广播失败",
+			util.Field{Key: "entity", Value: entity},
+			util.Field{Key: "id", Value: id},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
+
+// resolveItems 批量查询订单中的商品 校验是否都可以下单 返回以商品ID为key的商品
+// 订单项的价格以商品或SKU的当前价格为准 多规格商品同时写入规格快照
+func (s *OrderService) resolveItems(ctx context.Context, items []model.OrderItem) (map[int]*model.Product, error) {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		if err := item.ValidateQuantity(); err != nil {
+			return nil, util.NewBusinessError("INVALID_QUANTITY", fmt.Sprintf("%s: 商品%d", err.Error(), item.ProductID), util.ErrInvalidInput)
+		}
+		ids = append(ids, item.ProductID)
+	}
+	products, err := s.productRepo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询商品失败", err)
+	}
+
+	productMap := make(map[int]*model.Product, len(products))
+	for i := range products {
+		productMap[products[i].ID] = &products[i]
+	}
+	for _, item := range items {
+		product, ok := productMap[item.ProductID]
+		if !ok {
+			return nil, util.NewBusinessError("PRODUCT_NOT_FOUND", fmt.Sprintf("商品不存在: %d", item.ProductID), util.ErrNotFound)
+		}
+		if !product.IsPurchasable() {
+			util.GlobalLogger.Warn(ctx, "商品不可下单",
+				util.Field{Key: "product_id", Value: product.ID},
+				util.Field{Key: "status", Value: product.Status},
+			)
+			return nil, util.NewBusinessError("PRODUCT_NOT_AVAILABLE", fmt.Sprintf("商品不可购买: %s", product.Name), util.ErrInvalidInput)
+		}
+	}
+
+	// 按商品或SKU的当前价格定价 请求中的价格一律忽略
+	for i := range items {
+		item := &items[i]
+		product := productMap[item.ProductID]
+		if err := product.PriceOrderItem(item); err != nil {
+			code := "SKU_NOT_FOUND"
+			if errors.Is(err, model.ErrSKURequired) {
+				code = "SKU_REQUIRED"
+			}
+			return nil, util.NewBusinessError(code, fmt.Sprintf("%s: %s", err.Error(), product.Name), util.ErrInvalidInput)
+		}
+	}
+	return productMap, nil
+}
+
 // calculateTotal 计算订单总金额
 func calculateTotal(items []model.OrderItem) float64 {
 	total := 0.0
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderTracking 订单物流信息
+type OrderTracking struct {
+	OrderID         string                 `json:"order_id"`
+	Status          string                 `json:"status"`
+	Carrier         string                 `json:"carrier,omitempty"`
+	TrackingNumber  string                 `json:"tracking_number,omitempty"`
+	ShippedAt       *time.Time             `json:"shipped_at,omitempty"`
+	ShippingAddress *model.ShippingAddress `json:"shipping_address,omitempty"`
+}
+
+// ShipOrder 订单发货 只有已支付的订单可以发货 同时记录承运商和运单号
+func (s *OrderService) ShipOrder(ctx context.Context, orderID, carrier, trackingNumber string) (*model.Order, error) {
+	// 1. 参数验证
+	carrier = strings.TrimSpace(carrier)
+	trackingNumber = strings.TrimSpace(trackingNumber)
+	if carrier == "" || len(carrier) > 32 {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "承运商不能为空且不超过32个字符", util.ErrInvalidInput)
+	}
+	if trackingNumber == "" || len(trackingNumber) > 64 {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "运单号不能为空且不超过64个字符", util.ErrInvalidInput)
+	}
+
+	// 2. 状态流转 发货需要收货地址
+	order, err := s.loadForUpdate(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.ShippingAddress == nil {
+		return nil, util.NewBusinessError("ADDRESS_REQUIRED", "订单没有收货地址 无法发货", util.ErrInvalidInput)
+	}
+	now := time.Now()
+	err = s.transition(ctx, order, model.OrderStatusShipped, map[string]interface{}{
+		"carrier":         carrier,
+		"tracking_number": trackingNumber,
+		"shipped_at":      now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	util.GlobalLogger.Info(ctx, "订单已发货",
+		util.Field{Key: "order_id", Value: orderID},
+		util.Field{Key: "carrier", Value: carrier},
+		util.Field{Key: "tracking_number", Value: trackingNumber},
+	)
+	return s.loadForUpdate(ctx, orderID)
+}
+
+// TrackingOf 从订单中取出收货地址和物流信息
+func TrackingOf(order *model.Order) *OrderTracking {
+	return &OrderTracking{
+		OrderID:         order.ID,
+		Status:          order.Status,
+		Carrier:         order.Carrier,
+		TrackingNumber:  order.TrackingNumber,
+		ShippedAt:       order.ShippedAt,
+		ShippingAddress: order.ShippingAddress,
+	}
+}
+
+// loadForUpdate 从数据库读取订单 修改订单前使用 不读缓存避免基于过期的状态判断
+func (s *OrderService) loadForUpdate(ctx context.Context, orderID string) (*model.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, gorm.ErrInvalidData) {
+		return nil, util.NewBusinessError("ORDER_NOT_FOUND", "订单不存在", util.ErrNotFound)
+	}
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询订单失败", err)
+	}
+	return order, nil
+}
+
+// transition 修改订单状态并删除各级缓存
+func (s *OrderService) transition(ctx context.Context, order *model.Order, to string, updates map[string]interface{}) error {
+	if !model.CanTransitionOrderStatus(order.Status, to) {
+		return util.NewBusinessError("INVALID_STATUS_TRANSITION", "订单状态不允许从"+order.Status+"变更为"+to, util.ErrInvalidInput)
+	}
+	if err := s.orderRepo.TransitionStatus(ctx, order.ID, order.Status, to, updates); err != nil {
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			return util.NewBusinessError("VERSION_CONFLICT", "订单状态已变更 请刷新后重试", err)
+		}
+		return util.NewBusinessError("ORDER_UPDATE_FAILED", "更新订单状态失败", err)
+	}
+	s.evictOrder(ctx, order.ID)
+	return nil
+}
+
+// evictOrder 订单修改后删除本地缓存和Redis缓存 并通知其他实例
+func (s *OrderService) evictOrder(ctx context.Context, orderID string) {
+	if err := s.EvictCache(ctx, orderID); err != nil {
+		util.GlobalLogger.Warn(ctx, "订单缓存删除失败",
+			util.Field{Key: "order_id", Value: orderID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
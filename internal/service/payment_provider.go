@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/util"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// PaymentRequest 向支付渠道发起支付的参数
+type PaymentRequest struct {
+	PaymentID string
+	OrderID   string
+	Amount    float64
+	ExpiresAt time.Time
+}
+
+// PaymentIntent 支付渠道返回的支付意图 用户打开PayURL完成支付
+type PaymentIntent struct {
+	ProviderRef string // 支付渠道的交易号 回调时用于找到支付单
+	PayURL      string
+}
+
+// RefundRequest 向支付渠道发起退款的参数
+type RefundRequest struct {
+	RefundID   string
+	PaymentRef string // 原支付的渠道交易号
+	Amount     float64
+}
+
+// RefundResult 支付渠道返回的退款结果
+type RefundResult struct {
+	ProviderRef string // 渠道的退款交易号
+}
+
+// PaymentProvider 支付渠道 接入真实网关时实现这个接口即可
+type PaymentProvider interface {
+	// Name 渠道名称 保存在支付单上
+	Name() string
+	// CreateIntent 创建支付意图
+	CreateIntent(ctx context.Context, req PaymentRequest) (*PaymentIntent, error)
+	// VerifyCallback 校验回调签名并解析回调内容
+	VerifyCallback(signature, timestamp string, body []byte) (*model.PaymentEvent, error)
+	// Refund 原路退款 返回错误表示渠道拒绝退款
+	Refund(ctx context.Context, req RefundRequest) (*RefundResult, error)
+}
+
+// 回调时间戳允许的误差
+const paymentCallbackTolerance = 5 * time.Minute
+
+// MockPaymentProviderName 模拟支付渠道的名称 与配置PAYMENT_PROVIDER的取值一致
+const MockPaymentProviderName = "mock"
+
+// MockPaymentProvider 本地模拟的支付渠道 不调用外部网关
+// 回调使用与真实网关相同的HMAC签名方式 可以完整地走通支付流程
+type MockPaymentProvider struct {
+	secret []byte
+}
+
+// NewMockPaymentProvider 创建模拟支付渠道 secret为回调签名密钥
+func NewMockPaymentProvider(secret []byte) *MockPaymentProvider {
+	return &MockPaymentProvider{secret: secret}
+}
+
+// Name 渠道名称
+func (p *MockPaymentProvider) Name() string {
+	return MockPaymentProviderName
+}
+
+// CreateIntent 模拟渠道直接以支付单ID生成交易号 支付页面为本服务的模拟支付接口
+func (p *MockPaymentProvider) CreateIntent(ctx context.Context, req PaymentRequest) (*PaymentIntent, error) {
+	ref := "mock_" + req.PaymentID
+	return &PaymentIntent{
+		ProviderRef: ref,
+		PayURL:      "/payments/mock/" + ref + "/pay",
+	}, nil
+}
+
+// VerifyCallback 校验回调签名和时间戳
+func (p *MockPaymentProvider) VerifyCallback(signature, timestamp string, body []byte) (*model.PaymentEvent, error) {
+	if err := util.VerifyPayload(p.secret, timestamp, body, signature, paymentCallbackTolerance, time.Now()); err != nil {
+		return nil, err
+	}
+	var event model.PaymentEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.ProviderRef == "" {
+		return nil, util.ErrSignatureInvalid
+	}
+	return &event, nil
+}
+
+// Refund 模拟渠道直接退款成功
+func (p *MockPaymentProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	return &RefundResult{ProviderRef: "mock_" + req.RefundID}, nil
+}
+
+// SignCallback 模拟网关生成签名后的回调 返回请求体、签名和时间戳
+func (p *MockPaymentProvider) SignCallback(event model.PaymentEvent) (body []byte, signature, timestamp string, err error) {
+	body, err = json.Marshal(event)
+	if err != nil {
+		return nil, "", "", err
+	}
+	timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+	return body, util.SignPayload(p.secret, timestamp, body), timestamp, nil
+}
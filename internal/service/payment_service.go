@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentService 订单支付 通过PaymentProvider创建支付 收到签名回调后把订单标记为已支付
+type PaymentService struct {
+	paymentRepo  *repository.PaymentRepo
+	orderRepo    *repository.OrderRepo
+	orderService *OrderService
+	provider     PaymentProvider
+	intentTTL    time.Duration // 支付单有效期 过期后重新发起支付会创建新的支付单
+}
+
+// NewPaymentService 创建支付服务实例
+func NewPaymentService(paymentRepo *repository.PaymentRepo, orderRepo *repository.OrderRepo, orderService *OrderService, provider PaymentProvider, intentTTL time.Duration) *PaymentService {
+	return &PaymentService{
+		paymentRepo:  paymentRepo,
+		orderRepo:    orderRepo,
+		orderService: orderService,
+		provider:     provider,
+		intentTTL:    intentTTL,
+	}
+}
+
+// CreatePayment 为待支付的订单发起支付 未过期的支付单直接返回 重复请求不会重复创建
+func (s *PaymentService) CreatePayment(ctx context.Context, orderID string, userID int) (*model.Payment, error) {
+	// 1. 校验订单属于当前用户且待支付 其他用户的订单按不存在处理
+	order, err := s.orderService.loadForUpdate(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != strconv.Itoa(userID) {
+		return nil, util.NewBusinessError("ORDER_NOT_FOUND", "订单不存在", util.ErrNotFound)
+	}
+	if order.Status != model.OrderStatusPending {
+		return nil, util.NewBusinessError("ORDER_NOT_PAYABLE", "订单不是待支付状态: "+order.Status, util.ErrInvalidInput)
+	}
+
+	// 2. 复用未过期的支付单
+	latest, err := s.paymentRepo.GetLatestByOrder(ctx, orderID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询支付单失败", err)
+	}
+	if latest != nil && latest.IsReusable(time.Now()) && model.RoundAmount(latest.Amount) == model.RoundAmount(order.TotalAmount) {
+		return latest, nil
+	}
+
+	// 3. 通过支付渠道创建支付意图并保存支付单
+	payment := &model.Payment{
+		ID:        newPaymentID(),
+		OrderID:   order.ID,
+		UserID:    userID,
+		Amount:    order.TotalAmount,
+		Provider:  s.provider.Name(),
+		Status:    model.PaymentStatusPending,
+		ExpiresAt: time.Now().Add(s.intentTTL),
+	}
+	intent, err := s.provider.CreateIntent(ctx, PaymentRequest{
+		PaymentID: payment.ID,
+		OrderID:   order.ID,
+		Amount:    payment.Amount,
+		ExpiresAt: payment.ExpiresAt,
+	})
+	if err != nil {
+		return nil, util.NewBusinessError("PAYMENT_CREATE_FAILED", "发起支付失败", err)
+	}
+	payment.ProviderRef = intent.ProviderRef
+	payment.PayURL = intent.PayURL
+	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+		return nil, util.NewBusinessError("PAYMENT_CREATE_FAILED", "保存支付单失败", err)
+	}
+
+	util.GlobalLogger.Info(ctx, "发起支付",
+		util.Field{Key: "order_id", Value: order.ID},
+		util.Field{Key: "payment_id", Value: payment.ID},
+		util.Field{Key: "amount", Value: payment.Amount},
+	)
+	return payment, nil
+}
+
+// HandleCallback 处理支付渠道的回调 校验签名后更新支付单和订单状态
+// 渠道可能重复推送同一个回调 已处理过的回调直接返回成功
+func (s *PaymentService) HandleCallback(ctx context.Context, signature, timestamp string, body []byte) (*model.Payment, error) {
+	// 1. 校验签名
+	event, err := s.provider.VerifyCallback(signature, timestamp, body)
+	if err != nil {
+		util.GlobalLogger.Warn(ctx, "支付回调签名校验失败",
+			util.Field{Key: "error", Value: err.Error()},
+		)
+		return nil, util.NewBusinessError("SIGNATURE_INVALID", err.Error(), util.ErrUnauthorized)
+	}
+
+	// 2. 查询支付单 已处理过的回调直接返回
+	payment, err := s.paymentRepo.GetByProviderRef(ctx, event.ProviderRef)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("PAYMENT_NOT_FOUND", "支付单不存在", util.ErrNotFound)
+	}
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询支付单失败", err)
+	}
+	if payment.Status != model.PaymentStatusPending {
+		return payment, nil
+	}
+
+	// 3. 支付失败只更新支付单 订单保持待支付 用户可以重新发起支付
+	if event.Status != model.PaymentStatusSucceeded {
+		if err := s.paymentRepo.MarkFailed(ctx, payment.ID); err != nil {
+			return nil, util.NewBusinessError("PAYMENT_UPDATE_FAILED", "更新支付单失败", err)
+		}
+		payment.Status = model.PaymentStatusFailed
+		return payment, nil
+	}
+	if model.RoundAmount(event.Amount) != model.RoundAmount(payment.Amount) {
+		util.GlobalLogger.Error(ctx, "支付回调金额不一致", util.ErrInvalidInput,
+			util.Field{Key: "payment_id", Value: payment.ID},
+			util.Field{Key: "expected", Value: payment.Amount},
+			util.Field{Key: "actual", Value: event.Amount},
+		)
+		return nil, util.NewBusinessError("PAYMENT_AMOUNT_MISMATCH", "支付金额与支付单不一致", util.ErrInvalidInput)
+	}
+
+	// 4. 支付单和订单状态在同一事务中更新
+	paidAt := event.PaidAt
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+	orderPaid := false
+	err = s.paymentRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updated, err := s.paymentRepo.MarkSucceededWithTx(tx, payment.ID, paidAt)
+		if err != nil || !updated {
+			// 并发的重复回调已经处理过
+			return err
+		}
+		err = s.orderRepo.TransitionStatusWithTx(tx, payment.OrderID, model.OrderStatusPending, model.OrderStatusPaid,
+			map[string]interface{}{"paid_at": paidAt})
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			// 订单已取消或已被其他支付单支付 支付单仍记为成功 需要人工退款
+			util.GlobalLogger.Warn(ctx, "支付成功但订单不是待支付状态 需要退款",
+				util.Field{Key: "order_id", Value: payment.OrderID},
+				util.Field{Key: "payment_id", Value: payment.ID},
+			)
+			return nil
+		}
+		orderPaid = err == nil
+		return err
+	})
+	if err != nil {
+		return nil, util.NewBusinessError("PAYMENT_UPDATE_FAILED", "更新支付状态失败", err)
+	}
+	if orderPaid {
+		s.orderService.evictOrder(ctx, payment.OrderID)
+		util.GlobalLogger.Info(ctx, "订单支付成功",
+			util.Field{Key: "order_id", Value: payment.OrderID},
+			util.Field{Key: "payment_id", Value: payment.ID},
+		)
+	}
+
+	payment.Status = model.PaymentStatusSucceeded
+	payment.PaidAt = &paidAt
+	return payment, nil
+}
+
+// MockPay 模拟用户在支付页面完成支付 只在开发测试环境使用模拟渠道时可用 已过期的支付单不能支付
+// 由模拟渠道生成签名回调 再走一遍正常的回调处理流程
+func (s *PaymentService) MockPay(ctx context.Context, providerRef string, userID int) (*model.Payment, error) {
+	mock, ok := s.provider.(*MockPaymentProvider)
+	if !ok {
+		return nil, util.NewBusinessError("PAYMENT_PROVIDER_UNSUPPORTED", "当前支付渠道不支持模拟支付", util.ErrNotFound)
+	}
+	payment, err := s.paymentRepo.GetByProviderRef(ctx, providerRef)
+	if err != nil || payment.UserID != userID {
+		return nil, util.NewBusinessError("PAYMENT_NOT_FOUND", "支付单不存在", util.ErrNotFound)
+	}
+	if !time.Now().Before(payment.ExpiresAt) {
+		return nil, util.NewBusinessError("PAYMENT_EXPIRED", "支付单已过期 请重新发起支付", util.ErrInvalidInput)
+	}
+
+	body, signature, timestamp, err := mock.SignCallback(model.PaymentEvent{
+		ProviderRef: providerRef,
+		Status:      model.PaymentStatusSucceeded,
+		Amount:      payment.Amount,
+		PaidAt:      time.Now(),
+	})
+	if err != nil {
+		return nil, util.NewBusinessError("PAYMENT_UPDATE_FAILED", "生成模拟回调失败", err)
+	}
+	return s.HandleCallback(ctx, signature, timestamp, body)
+}
+
+// newPaymentID 生成支付单ID 时间戳加随机数
+func newPaymentID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return "p" + time.Now().Format("060102150405") + hex.EncodeToString(b)
+}
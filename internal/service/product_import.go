@@ -0,0 +1,468 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/util"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 导入导出支持的文件格式
+const (
+	ProductFileCSV   = "csv"
+	ProductFileJSONL = "jsonl"
+)
+
+// 导入导出参数
+const (
+	productImportBatchSize = 500     // 每批写入的商品数量
+	productImportMaxErrors = 1000    // 报告中最多保留的错误行数 超过后只计数
+	productImportMaxLine   = 1 << 20 // JSONL单行最大长度
+	productExportBatchSize = 1000    // 导出时每批查询的商品数量
+)
+
+// productFileColumns 导入导出文件的列 导出的文件可以直接重新导入
+var productFileColumns = []string{"id", "external_sku", "name", "description", "price", "stock", "category_id", "category", "status"}
+
+// ProductImportRow 导入文件中的一行
+type ProductImportRow struct {
+	ExternalSKU string  `json:"external_sku"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Stock       int     `json:"stock"`
+	CategoryID  int     `json:"category_id"`
+	Category    string  `json:"category"`
+	Status      string  `json:"status"`
+}
+
+// ImportRowError 导入失败的行
+type ImportRowError struct {
+	Line        int    `json:"line"` // 文件中的行号 CSV表头为第1行
+	ExternalSKU string `json:"external_sku,omitempty"`
+	Error       string `json:"error"`
+}
+
+// ImportReport 导入结果报告
+type ImportReport struct {
+	Total           int              `json:"total"`   // 数据行数
+	Created         int              `json:"created"` // 新增的商品数
+	Updated         int              `json:"updated"` // 按外部编码更新的商品数
+	Failed          int              `json:"failed"`  // 校验失败的行数
+	Errors          []ImportRowError `json:"errors"`
+	ErrorsTruncated bool             `json:"errors_truncated"` // 错误行过多时只保留前面的部分
+}
+
+// addError 记录失败的行
+func (r *ImportReport) addError(line int, externalSKU, message string) {
+	r.Failed++
+	if len(r.Errors) >= productImportMaxErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, ImportRowError{Line: line, ExternalSKU: externalSKU, Error: message})
+}
+
+// ImportProducts 流式导入商品 按外部编码新增或更新
+// 逐行解析和校验 校验失败的行记录到报告中并跳过 合法的行攒够一批后批量写入
+// 写入数据库失败时中止导入 之前的批次已经提交 报告中的数量反映已写入的部分
+// operator为操作人 记录在价格变更记录中
+func (s *ProductService) ImportProducts(ctx context.Context, r io.Reader, format, operator string) (*ImportReport, error) {
+	next, err := NewProductRowReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Errors: []ImportRowError{}}
+	seen := make(map[string]int)       // 外部编码 -> 首次出现的行号 同一文件中编码不能重复
+	categories := make(map[int]string) // 分类ID -> 分类名称 避免每行都查一次分类表
+	batch := make([]model.Product, 0, productImportBatchSize)
+	batchLines := make([]int, 0, productImportBatchSize) // 批次中每个商品所在的行号 状态校验失败时报告
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() {
+			batch = batch[:0]
+			batchLines = batchLines[:0]
+		}()
+
+		// 已有商品的状态变更与其他修改接口一样需要符合状态流转规则
+		codes := make([]string, len(batch))
+		for i := range batch {
+			codes[i] = *batch[i].ExternalSKU
+		}
+		statuses, err := s.productRepo.GetStatusesByExternalSKU(ctx, codes)
+		if err != nil {
+			return util.NewBusinessError("IMPORT_FAILED", "查询商品状态失败", err)
+		}
+		valid := batch[:0]
+		for i := range batch {
+			p := batch[i]
+			current := statuses[*p.ExternalSKU]
+			status, ok := model.ResolveImportStatus(current, p.Status)
+			if !ok {
+				report.addError(batchLines[i], *p.ExternalSKU, "商品状态不能从"+current+"变更为"+p.Status)
+				continue
+			}
+			p.Status = status
+			valid = append(valid, p)
+		}
+		if len(valid) == 0 {
+			return nil
+		}
+
+		updated, err := s.productRepo.UpsertByExternalSKU(ctx, valid, operator)
+		if err != nil {
+			return util.NewBusinessError("IMPORT_FAILED", "批量写入商品失败", err)
+		}
+		report.Updated += updated
+		report.Created += len(valid) - updated
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		// 1. 读取一行 格式错误的行记录后继续
+		line, row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *importRowError
+		if errors.As(err, &rowErr) {
+			report.Total++
+			report.addError(line, "", rowErr.msg)
+			continue
+		}
+		if err != nil {
+			return report, util.NewBusinessError("INVALID_FILE", "读取导入文件失败: "+err.Error(), util.ErrInvalidInput)
+		}
+		report.Total++
+
+		// 2. 校验
+		product, msg := s.validateImportRow(ctx, row, categories)
+		if msg == "" {
+			if first, ok := seen[row.ExternalSKU]; ok {
+				msg = fmt.Sprintf("外部编码与第%d行重复", first)
+			}
+		}
+		if msg != "" {
+			report.addError(line, row.ExternalSKU, msg)
+			continue
+		}
+		seen[row.ExternalSKU] = line
+
+		// 3. 攒批写入
+		batch = append(batch, *product)
+		batchLines = append(batchLines, line)
+		if len(batch) >= productImportBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	// 4. 导入的商品可能很多 直接清空商品缓存并重建全文索引
+	if report.Created+report.Updated > 0 {
+		if _, err := s.PurgeCache(ctx); err != nil {
+			util.GlobalLogger.Warn(ctx, "导入后清空商品缓存失败",
+				util.Field{Key: "error", Value: err.Error()},
+			)
+		}
+		if err := s.RebuildSearchIndex(ctx); err != nil {
+			util.GlobalLogger.Error(ctx, "导入后重建全文索引失败", err)
+		}
+	}
+
+	util.GlobalLogger.Info(ctx, "商品导入完成",
+		util.Field{Key: "total", Value: report.Total},
+		util.Field{Key: "created", Value: report.Created},
+		util.Field{Key: "updated", Value: report.Updated},
+		util.Field{Key: "failed", Value: report.Failed},
+	)
+	return report, nil
+}
+
+// ValidateImportRow 校验导入行的字段 返回的字符串非空表示校验失败的原因
+// 状态为空时不在这里填默认值 新商品默认上架 已有商品保持原状态 写入前由ResolveImportStatus决定
+func ValidateImportRow(row *ProductImportRow) string {
+	row.ExternalSKU = strings.TrimSpace(row.ExternalSKU)
+	row.Name = strings.TrimSpace(row.Name)
+	row.Status = strings.TrimSpace(row.Status)
+	switch {
+	case row.ExternalSKU == "":
+		return "外部编码不能为空"
+	case len(row.ExternalSKU) > 64:
+		return "外部编码不能超过64个字符"
+	case row.Name == "":
+		return "商品名称不能为空"
+	case len([]rune(row.Name)) > 100:
+		return "商品名称不能超过100个字符"
+	case len([]rune(row.Description)) > 500:
+		return "商品描述不能超过500个字符"
+	case row.Price <= 0:
+		return "商品价格必须大于0"
+	case row.Stock < 0:
+		return "库存不能为负数"
+	case len([]rune(row.Category)) > 50:
+		return "分类名称不能超过50个字符"
+	case row.Status != "" && !model.IsValidProductStatus(row.Status):
+		return "商品状态无效: " + row.Status
+	}
+	return ""
+}
+
+// validateImportRow 校验导入行并转换为商品 返回的字符串非空表示校验失败的原因
+func (s *ProductService) validateImportRow(ctx context.Context, row *ProductImportRow, categories map[int]string) (*model.Product, string) {
+	if msg := ValidateImportRow(row); msg != "" {
+		return nil, msg
+	}
+
+	// 指定分类ID时分类名称以分类表为准
+	if row.CategoryID != 0 {
+		name, ok := categories[row.CategoryID]
+		if !ok {
+			category, err := s.getCategory(ctx, row.CategoryID)
+			if err != nil {
+				return nil, fmt.Sprintf("分类不存在: %d", row.CategoryID)
+			}
+			name = category.Name
+			categories[row.CategoryID] = name
+		}
+		row.Category = name
+	}
+
+	externalSKU := row.ExternalSKU
+	return &model.Product{
+		ExternalSKU: &externalSKU,
+		Name:        row.Name,
+		Description: row.Description,
+		Price:       row.Price,
+		Stock:       row.Stock,
+		CategoryID:  row.CategoryID,
+		Category:    row.Category,
+		Status:      row.Status,
+	}, ""
+}
+
+// importRowError 单行格式错误 跳过该行继续读取
+type importRowError struct {
+	msg string
+}
+
+func (e *importRowError) Error() string {
+	return e.msg
+}
+
+// ProductRowReader 逐行读取导入文件 返回行号和解析后的行 读完时返回io.EOF
+// 单行格式错误时返回的错误可以跳过 继续读取下一行
+type ProductRowReader func() (int, *ProductImportRow, error)
+
+// NewProductRowReader 根据文件格式创建行读取器
+func NewProductRowReader(r io.Reader, format string) (ProductRowReader, error) {
+	switch format {
+	case ProductFileCSV:
+		return newCSVRowReader(r)
+	case ProductFileJSONL:
+		return newJSONLRowReader(r), nil
+	default:
+		return nil, util.NewBusinessError("INVALID_FORMAT", "不支持的文件格式: "+format, util.ErrInvalidInput)
+	}
+}
+
+// newCSVRowReader CSV读取器 第一行为表头 列的顺序不限 未知的列（如导出文件中的id）会被忽略
+func newCSVRowReader(r io.Reader) (ProductRowReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // 列数不一致的行单独报错 不中断整个文件
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, util.NewBusinessError("INVALID_FILE", "读取CSV表头失败", util.ErrInvalidInput)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// 兼容Excel导出的带BOM的UTF-8文件
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"external_sku", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, util.NewBusinessError("INVALID_FILE", "CSV缺少必需的列: "+required, util.ErrInvalidInput)
+		}
+	}
+
+	return func() (int, *ProductImportRow, error) {
+		record, err := reader.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return parseErr.StartLine, nil, &importRowError{msg: "CSV格式错误: " + parseErr.Err.Error()}
+			}
+			return 0, nil, err
+		}
+		// 带引号的字段中可以包含换行 行号以记录起始行为准
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			return line, nil, &importRowError{msg: fmt.Sprintf("列数错误: 期望%d列, 实际%d列", len(header), len(record))}
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := &ProductImportRow{
+			ExternalSKU: get("external_sku"),
+			Name:        get("name"),
+			Description: get("description"),
+			Category:    get("category"),
+			Status:      get("status"),
+		}
+		if row.Price, err = strconv.ParseFloat(get("price"), 64); err != nil {
+			return line, nil, &importRowError{msg: "价格格式错误: " + get("price")}
+		}
+		if v := get("stock"); v != "" {
+			if row.Stock, err = strconv.Atoi(v); err != nil {
+				return line, nil, &importRowError{msg: "库存格式错误: " + v}
+			}
+		}
+		if v := get("category_id"); v != "" {
+			if row.CategoryID, err = strconv.Atoi(v); err != nil {
+				return line, nil, &importRowError{msg: "分类ID格式错误: " + v}
+			}
+		}
+		return line, row, nil
+	}, nil
+}
+
+// newJSONLRowReader JSON Lines读取器 每行一个JSON对象 空行会被跳过
+func newJSONLRowReader(r io.Reader) ProductRowReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), productImportMaxLine)
+
+	line := 0
+	return func() (int, *ProductImportRow, error) {
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var row ProductImportRow
+			if err := json.Unmarshal([]byte(text), &row); err != nil {
+				return line, nil, &importRowError{msg: "JSON格式错误: " + err.Error()}
+			}
+			return line, &row, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return line, nil, err
+		}
+		return line, nil, io.EOF
+	}
+}
+
+// ExportProducts 流式导出商品 每写完一批刷新一次 不会把整个商品目录加载到内存
+// status为空时导出所有状态的商品
+func (s *ProductService) ExportProducts(ctx context.Context, w io.Writer, format, status string) error {
+	if status != "" && !model.IsValidProductStatus(status) {
+		return util.NewBusinessError("INVALID_STATUS", "商品状态无效: "+status, util.ErrInvalidInput)
+	}
+	writeBatch, err := NewProductFileWriter(w, format)
+	if err != nil {
+		return err
+	}
+	flusher, _ := w.(interface{ Flush() })
+
+	return s.productRepo.ExportInBatches(ctx, status, productExportBatchSize, func(products []model.Product) error {
+		if err := writeBatch(products); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+}
+
+// ProductFileWriter 按导入导出文件格式写出一批商品 CSV在创建时先写出表头
+type ProductFileWriter func(products []model.Product) error
+
+// NewProductFileWriter 根据文件格式创建写出器 写出的文件可以直接重新导入
+func NewProductFileWriter(w io.Writer, format string) (ProductFileWriter, error) {
+	switch format {
+	case ProductFileCSV:
+		cw := csv.NewWriter(w)
+		// 表头立即写出 没有商品时也能得到只有表头的文件
+		if err := cw.Write(productFileColumns); err != nil {
+			return nil, err
+		}
+		cw.Flush()
+		return func(products []model.Product) error {
+			for i := range products {
+				if err := cw.Write(productRecord(&products[i])); err != nil {
+					return err
+				}
+			}
+			cw.Flush()
+			return cw.Error()
+		}, nil
+	case ProductFileJSONL:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return func(products []model.Product) error {
+			for i := range products {
+				p := &products[i]
+				if err := encoder.Encode(map[string]interface{}{
+					"id":           p.ID,
+					"external_sku": p.ExternalSKU,
+					"name":         p.Name,
+					"description":  p.Description,
+					"price":        p.Price,
+					"stock":        p.Stock,
+					"category_id":  p.CategoryID,
+					"category":     p.Category,
+					"status":       p.Status,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	default:
+		return nil, util.NewBusinessError("INVALID_FORMAT", "不支持的文件格式: "+format, util.ErrInvalidInput)
+	}
+}
+
+// productRecord 商品转换为CSV行 顺序与productFileColumns一致
+func productRecord(p *model.Product) []string {
+	externalSKU := ""
+	if p.ExternalSKU != nil {
+		externalSKU = *p.ExternalSKU
+	}
+	return []string{
+		strconv.Itoa(p.ID),
+		externalSKU,
+		p.Name,
+		p.Description,
+		strconv.FormatFloat(p.Price, 'f', 2, 64),
+		strconv.Itoa(p.Stock),
+		strconv.Itoa(p.CategoryID),
+		p.Category,
+		p.Status,
+	}
+}
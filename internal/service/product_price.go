@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/util"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 每轮最多执行的定时调价数量
+const priceScheduleBatchSize = 100
+
+// updatePrice 修改商品价格（可以同时修改其他字段） 在同一事务中写入价格变更记录
+// 先对商品加行锁读取当前价格 保证记录的变更前价格就是被覆盖的价格
+// version小于0时不校验版本号 history中只需要填写新价格、来源、操作人和原因
+func (s *ProductService) updatePrice(ctx context.Context, productID, version int, updates map[string]interface{}, history model.PriceHistory) error {
+	return s.productRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		oldPrice, err := s.productRepo.GetPriceForUpdateWithTx(tx, productID)
+		if err != nil {
+			return err
+		}
+		updates["price"] = history.NewPrice
+		if err := s.productRepo.UpdateWithTx(tx, productID, version, updates); err != nil {
+			return err
+		}
+		if oldPrice == history.NewPrice {
+			return nil
+		}
+		history.ProductID = productID
+		history.OldPrice = oldPrice
+		history.ChangedAt = time.Now()
+		return s.priceRepo.CreateHistoryWithTx(tx, history)
+	})
+}
+
+// updateSKUPrice 修改SKU价格（可以同时修改其他字段） 与商品价格一样在同一事务中写入价格变更记录
+func (s *ProductService) updateSKUPrice(ctx context.Context, productID, skuID, version int, updates map[string]interface{}, history model.PriceHistory) error {
+	return s.productRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		oldPrice, err := s.skuRepo.GetPriceForUpdateWithTx(tx, skuID)
+		if err != nil {
+			return err
+		}
+		updates["price"] = history.NewPrice
+		if err := s.skuRepo.UpdateWithTx(ctx, tx, skuID, version, updates); err != nil {
+			return err
+		}
+		if oldPrice == history.NewPrice {
+			return nil
+		}
+		history.ProductID = productID
+		history.SKUID = skuID
+		history.OldPrice = oldPrice
+		history.ChangedAt = time.Now()
+		return s.priceRepo.CreateHistoryWithTx(tx, history)
+	})
+}
+
+// PriceHistoryResult 价格变更记录查询结果
+type PriceHistoryResult struct {
+	ProductID    int                  `json:"product_id"`
+	SKUID        int                  `json:"sku_id,omitempty"`
+	CurrentPrice float64              `json:"current_price"`
+	Histories    []model.PriceHistory `json:"histories"`
+	At           *time.Time           `json:"at,omitempty"`       // 查询的时刻
+	PriceAt      *float64             `json:"price_at,omitempty"` // 该时刻的价格 用于核对订单纠纷
+}
+
+// GetPriceHistory 查询商品的价格变更记录 at非空时同时计算该时刻的价格
+// skuID非0时查询该SKU的价格 多规格商品下单时使用的是SKU价格
+func (s *ProductService) GetPriceHistory(ctx context.Context, productID, skuID int, from, to time.Time, at *time.Time) (*PriceHistoryResult, error) {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, productWriteError(err)
+	}
+	currentPrice := product.Price
+	if skuID != 0 {
+		sku := product.FindSKU(skuID)
+		if sku == nil {
+			return nil, util.NewBusinessError("SKU_NOT_FOUND", "SKU不存在", util.ErrNotFound)
+		}
+		currentPrice = sku.Price
+	}
+
+	histories, err := s.priceRepo.ListHistory(ctx, productID, skuID, from, to)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询价格变更记录失败", err)
+	}
+	result := &PriceHistoryResult{
+		ProductID:    productID,
+		SKUID:        skuID,
+		CurrentPrice: currentPrice,
+		Histories:    histories,
+	}
+
+	if at != nil {
+		// 计算某一时刻的价格需要完整的变更记录 不受from/to限制
+		all := histories
+		if !from.IsZero() || !to.IsZero() {
+			if all, err = s.priceRepo.ListHistory(ctx, productID, skuID, time.Time{}, time.Time{}); err != nil {
+				return nil, util.NewBusinessError("QUERY_FAILED", "查询价格变更记录失败", err)
+			}
+		}
+		price := model.PriceAt(all, *at, currentPrice)
+		result.At = at
+		result.PriceAt = &price
+	}
+	return result, nil
+}
+
+// SchedulePriceChange 创建定时调价 到达生效时间后由后台任务修改价格
+func (s *ProductService) SchedulePriceChange(ctx context.Context, productID int, price float64, effectiveAt time.Time, operator, reason string) (*model.PriceSchedule, error) {
+	// 1. 参数验证
+	if price <= 0 {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "商品价格必须大于0", util.ErrInvalidInput)
+	}
+	if !effectiveAt.After(time.Now()) {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "生效时间必须晚于当前时间", util.ErrInvalidInput)
+	}
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, productWriteError(err)
+	}
+
+	// 2. 写入数据库
+	schedule := &model.PriceSchedule{
+		ProductID:   productID,
+		Price:       price,
+		EffectiveAt: effectiveAt,
+		Status:      model.PriceScheduleStatusPending,
+		CreatedBy:   operator,
+		Reason:      reason,
+	}
+	if err := s.priceRepo.CreateSchedule(ctx, schedule); err != nil {
+		return nil, util.NewBusinessError("SCHEDULE_CREATE_FAILED", "创建定时调价失败", err)
+	}
+	return schedule, nil
+}
+
+// ListPriceSchedules 查询商品的定时调价
+func (s *ProductService) ListPriceSchedules(ctx context.Context, productID int) ([]model.PriceSchedule, error) {
+	schedules, err := s.priceRepo.ListSchedules(ctx, productID)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询定时调价失败", err)
+	}
+	return schedules, nil
+}
+
+// CancelPriceSchedule 取消还未生效的定时调价
+func (s *ProductService) CancelPriceSchedule(ctx context.Context, productID, scheduleID int) error {
+	err := s.priceRepo.CancelSchedule(ctx, productID, scheduleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.NewBusinessError("SCHEDULE_NOT_FOUND", "定时调价不存在或已生效", util.ErrNotFound)
+	}
+	if err != nil {
+		return util.NewBusinessError("SCHEDULE_CANCEL_FAILED", "取消定时调价失败", err)
+	}
+	return nil
+}
+
+// ApplyDuePriceSchedules 执行已到生效时间的定时调价 返回本轮生效的数量
+// 多实例同时执行时 通过状态的条件更新保证每个调价只会执行一次
+func (s *ProductService) ApplyDuePriceSchedules(ctx context.Context) (int, error) {
+	now := time.Now()
+	schedules, err := s.priceRepo.DueSchedules(ctx, now, priceScheduleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			return applied, ctx.Err()
+		}
+
+		ok, err := s.applyPriceSchedule(ctx, schedule, now)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 商品已被删除 调价无法执行
+			if err := s.priceRepo.MarkScheduleFailed(ctx, schedule.ID); err != nil {
+				util.GlobalLogger.Error(ctx, "标记定时调价失败状态出错", err,
+					util.Field{Key: "schedule_id", Value: schedule.ID},
+				)
+			}
+			continue
+		}
+		if err != nil {
+			// 其他错误保持pending 下一轮重试
+			util.GlobalLogger.Error(ctx, "定时调价执行失败", err,
+				util.Field{Key: "schedule_id", Value: schedule.ID},
+				util.Field{Key: "product_id", Value: schedule.ProductID},
+			)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		applied++
+		s.invalidate(ctx, schedule.ProductID)
+		util.GlobalLogger.Info(ctx, "定时调价已生效",
+			util.Field{Key: "schedule_id", Value: schedule.ID},
+			util.Field{Key: "product_id", Value: schedule.ProductID},
+			util.Field{Key: "price", Value: schedule.Price},
+		)
+	}
+	return applied, nil
+}
+
+// applyPriceSchedule 在一个事务中标记调价已生效并修改价格 返回false表示已被其他实例执行
+func (s *ProductService) applyPriceSchedule(ctx context.Context, schedule model.PriceSchedule, now time.Time) (bool, error) {
+	claimed := false
+	err := s.productRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if claimed, err = s.priceRepo.ClaimScheduleWithTx(tx, schedule.ID, now); err != nil || !claimed {
+			return err
+		}
+
+		oldPrice, err := s.productRepo.GetPriceForUpdateWithTx(tx, schedule.ProductID)
+		if err != nil {
+			return err
+		}
+		// 定时调价不校验版本号 但会让版本号+1 使之前读取的客户端更新时得到版本冲突
+		if err := s.productRepo.UpdateWithTx(tx, schedule.ProductID, -1, map[string]interface{}{"price": schedule.Price}); err != nil {
+			return err
+		}
+		if oldPrice == schedule.Price {
+			return nil
+		}
+		return s.priceRepo.CreateHistoryWithTx(tx, model.PriceHistory{
+			ProductID: schedule.ProductID,
+			OldPrice:  oldPrice,
+			NewPrice:  schedule.Price,
+			Source:    model.PriceSourceSchedule,
+			ChangedBy: schedule.CreatedBy,
+			Reason:    schedule.Reason,
+			ChangedAt: now,
+		})
+	})
+	return claimed && err == nil, err
+}
+
+// StartPriceScheduler 定时执行到期的调价 阻塞直到ctx取消 interval不大于0时不启动
+func (s *ProductService) StartPriceScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		util.GlobalLogger.Warn(ctx, "定时调价检查间隔无效 不启动定时调价任务",
+			util.Field{Key: "interval", Value: interval.String()},
+		)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ApplyDuePriceSchedules(ctx); err != nil {
+				util.GlobalLogger.Error(ctx, "定时调价任务执行失败", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/util"
+	"time"
+)
+
+// 全文检索字段权重 名称命中最重要 其次是分类
+var productSearchWeights = map[string]float64{
+	"name":        3,
+	"category":    2,
+	"description": 1,
+}
+
+// ProductSearchResult 全文检索结果
+type ProductSearchResult struct {
+	Product   *model.Product    `json:"product"`
+	Score     float64           `json:"score"`
+	Highlight map[string]string `json:"highlight"` // 字段名 -> 带<em>标签的高亮文本
+}
+
+// newProductSearchIndex 创建商品倒排索引
+func newProductSearchIndex() *util.SearchIndex {
+	return util.NewSearchIndex(productSearchWeights)
+}
+
+// productSearchFields 商品参与检索的字段
+func productSearchFields(p *model.Product) map[string]string {
+	return map[string]string{
+		"name":        p.Name,
+		"category":    p.Category,
+		"description": p.Description,
+	}
+}
+
+// indexProduct 更新单个商品的索引 只有上架中的商品可以被搜到
+func (s *ProductService) indexProduct(p *model.Product) {
+	index := s.searchIndex.Load()
+	if p.Status != model.ProductStatusActive {
+		index.Remove(p.ID)
+		return
+	}
+	index.Upsert(p.ID, productSearchFields(p))
+}
+
+// reloadAndIndex 商品修改后重新加载最新数据并更新索引
+func (s *ProductService) reloadAndIndex(ctx context.Context, productID int) (*model.Product, error) {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	s.indexProduct(product)
+	return product, nil
+}
+
+// RebuildSearchIndex 从数据库全量重建索引 建好后整体替换 重建期间不影响查询
+func (s *ProductService) RebuildSearchIndex(ctx context.Context) error {
+	index := newProductSearchIndex()
+	err := s.productRepo.FindActiveInBatches(ctx, 500, func(products []model.Product) error {
+		for i := range products {
+			index.Upsert(products[i].ID, productSearchFields(&products[i]))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.searchIndex.Store(index)
+	util.GlobalLogger.Info(ctx, "商品全文索引重建完成",
+		util.Field{Key: "documents", Value: index.Len()},
+	)
+	return nil
+}
+
+// StartSearchIndexRefresher 定时重建索引 阻塞直到ctx取消
+// 本实例的修改会实时更新索引 其他实例的修改依赖定时重建同步 interval不大于0时不启动
+func (s *ProductService) StartSearchIndexRefresher(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		util.GlobalLogger.Warn(ctx, "搜索索引重建间隔无效 不启动定时重建任务",
+			util.Field{Key: "interval", Value: interval.String()},
+		)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RebuildSearchIndex(ctx); err != nil {
+				util.GlobalLogger.Error(ctx, "商品全文索引重建失败", err)
+			}
+		}
+	}
+}
+
+// FullTextSearch 全文检索商品 按相关度排序并返回高亮结果和命中总数
+func (s *ProductService) FullTextSearch(ctx context.Context, query string, page, pageSize int) ([]ProductSearchResult, int, error) {
+	if query == "" {
+		return nil, 0, util.NewBusinessError("INVALID_PARAMS", "搜索关键词不能为空", util.ErrInvalidInput)
+	}
+
+	// 1. 倒排索引检索 取当前页的命中
+	hits := s.searchIndex.Load().Search(query)
+	total := len(hits)
+	start := (page - 1) * pageSize
+	if start >= total {
+		return []ProductSearchResult{}, total, nil
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	hits = hits[start:end]
+
+	// 2. 批量查询商品详情
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	products, err := s.productRepo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, 0, util.NewBusinessError("QUERY_FAILED", "查询商品失败", err)
+	}
+	productMap := make(map[int]*model.Product, len(products))
+	for i := range products {
+		productMap[products[i].ID] = &products[i]
+	}
+
+	// 3. 按相关度顺序组装结果 跳过索引中已过期的商品
+	results := make([]ProductSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		product, ok := productMap[hit.ID]
+		if !ok || product.Status != model.ProductStatusActive {
+			continue
+		}
+		results = append(results, ProductSearchResult{
+			Product: product,
+			Score:   hit.Score,
+			Highlight: map[string]string{
+				"name":        util.Highlight(product.Name, query),
+				"description": util.Highlight(product.Description, query),
+			},
+		})
+	}
+	return results, total, nil
+}
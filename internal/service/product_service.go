@@ -4,20 +4,60 @@ import (
 	"context"
 	"demo01/internal/model"
 	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"fmt"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type ProductService struct {
-	productRepo *repository.ProductRepo
-	localCache  sync.Map // 本地缓存，存储热点商品信息
+	productRepo  *repository.ProductRepo
+	skuRepo      *repository.SKURepo
+	categoryRepo *repository.CategoryRepo
+	priceRepo    *repository.PriceRepo
+	localCache   *util.LocalCache[int, *model.Product] // 本地缓存，存储热点商品信息
+	invalidator  *util.CacheInvalidator                // 跨实例缓存失效广播
+	loader       *util.CacheLoader[*model.Product]     // 缓存未命中时合并并发的数据库查询
+	redisStats   util.CacheCounter                     // Redis缓存命中统计
+	searchIndex  atomic.Pointer[util.SearchIndex]      // 全文检索倒排索引 重建时整体替换
 }
 
+// 商品本地缓存参数 库存会变化 所以过期时间比订单短
+const (
+	productLocalCacheSize = 5000
+	productLocalCacheTTL  = time.Minute
+)
+
 // 创建商品服务实例
-func NewProductService(productRepo *repository.ProductRepo) *ProductService {
-	return &ProductService{
+func NewProductService(productRepo *repository.ProductRepo, skuRepo *repository.SKURepo, categoryRepo *repository.CategoryRepo, priceRepo *repository.PriceRepo, invalidator *util.CacheInvalidator) *ProductService {
+	s := &ProductService{
 		// 提供操作数据库的实例
-		productRepo: productRepo,
+		productRepo:  productRepo,
+		skuRepo:      skuRepo,
+		categoryRepo: categoryRepo,
+		priceRepo:    priceRepo,
+		localCache:   util.NewLocalCache[int, *model.Product](productLocalCacheSize, productLocalCacheTTL),
+		invalidator:  invalidator,
+		loader:       util.NewCacheLoader[*model.Product](),
 	}
+	s.searchIndex.Store(newProductSearchIndex())
+
+	// 收到商品失效消息后删除本地缓存
+	invalidator.Register(util.CacheEntityProduct, func(id string) {
+		if id == "" {
+			s.localCache.Purge()
+			return
+		}
+		if productID, err := strconv.Atoi(id); err == nil {
+			s.localCache.Delete(productID)
+		}
+	})
+	return s
 }
 
 // GetProduct 获取商品信息
@@ -25,35 +65,290 @@ func (s *ProductService) GetProduct(ctx context.Context, productID int) (*model.
 	// 1. 先从本地缓存查询
 	// 本地缓存指的是当前进程的内存空间 不是用户浏览器的本地存储也不是单个协程的内存空间
 	// 结合go的并发模型 我们其实不难理解 一个进程可能对应很多个协程 那么从这个意义上来说 是否可以认为在本地缓存中 也是有多个协程去共享内存的
-	// 从而产生了竞态条件 需要加锁 但是加锁的性能损耗很大 所以需要使用sync.map
+	// 从而产生了竞态条件 需要加锁 但是加锁的性能损耗很大 所以需要使用并发安全的本地缓存（util.LocalCache）
 	// 本地缓存局限于一个进程内 如果想要实现全局的缓存 需要使用redis
-	if cacheValue, exists := s.localCache.Load(productID); exists {
-		if cache, ok := cacheValue.(*model.Product); ok {
-			return cache, nil
-		}
+	if cache, exists := s.localCache.Get(productID); exists {
+		return cache, nil
 	}
 
-	// 2. 从数据库查询
-	product, err := s.productRepo.GetByID(ctx, productID)
+	// 2. 布隆过滤器拦截一定不存在的商品ID 防止缓存穿透
+	if !s.productRepo.MightExist(ctx, productID) {
+		return nil, util.NewBusinessError("PRODUCT_NOT_FOUND", "商品不存在", util.ErrNotFound)
+	}
+
+	// 3. 二级缓存：Redis缓存查询 命中后回写本地缓存 命中空值缓存说明商品不存在
+	product, err := s.productRepo.GetFromCache(ctx, productID)
+	if errors.Is(err, repository.ErrCachedNotFound) {
+		s.redisStats.Hit()
+		return nil, util.NewBusinessError("PRODUCT_NOT_FOUND", "商品不存在", util.ErrNotFound)
+	}
+	if err == nil {
+		s.redisStats.Hit()
+		s.localCache.Set(productID, product)
+		return product, nil
+	}
+	s.redisStats.Miss()
+
+	// 4. 从数据库查询 同一个商品的并发未命中只查一次数据库
+	product, err = s.loader.Load(ctx, util.ProductCacheKey(productID),
+		func(ctx context.Context) (*model.Product, bool) {
+			product, err := s.productRepo.GetFromCache(ctx, productID)
+			return product, err == nil
+		},
+		func(ctx context.Context) (*model.Product, error) {
+			product, err := s.productRepo.GetByID(ctx, productID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// 写入空值缓存 短时间内同一个ID不再查数据库
+				if err := s.productRepo.SetNotFoundToCache(ctx, productID); err != nil {
+					util.GlobalLogger.Warn(ctx, "空值缓存写入失败",
+						util.Field{Key: "product_id", Value: productID},
+						util.Field{Key: "error", Value: err.Error()},
+					)
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := s.productRepo.SetToCache(ctx, productID, product); err != nil {
+				util.GlobalLogger.Warn(ctx, "Redis缓存写入失败",
+					util.Field{Key: "product_id", Value: productID},
+					util.Field{Key: "error", Value: err.Error()},
+				)
+			}
+			return product, nil
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	// 3. 写入本地缓存
-	s.localCache.Store(productID, product)
-
-	// TODO 4. 写入redis缓存
+	// 5. 回填本地缓存
+	s.localCache.Set(productID, product)
 	return product, nil
 }
 
+// WarmUp 预热热门商品到本地缓存和Redis缓存 返回预热的商品数量
+// hotIDs 为按热度排序的商品ID 不足limit时用上架中的商品补齐
+// 所有商品都通过GetProductsByIDs加载 与正常读取时一样带上SKU和规格矩阵
+func (s *ProductService) WarmUp(ctx context.Context, hotIDs []int, limit int) (int, error) {
+	return WarmUpProducts(ctx, hotIDs, limit, ProductWarmUpSource{
+		ActiveIDs: s.productRepo.GetActiveIDs,
+		Load:      s.productRepo.GetProductsByIDs,
+		Store: func(ctx context.Context, product *model.Product) error {
+			s.localCache.Set(product.ID, product)
+			return s.productRepo.SetToCache(ctx, product.ID, product)
+		},
+	})
+}
+
 // GetAllProducts 获取所有商品（分页）
 func (s *ProductService) GetAllProducts(ctx context.Context, page, pageSize int) ([]model.Product, error) {
 	return s.productRepo.GetAll(ctx, page, pageSize)
 }
 
+// SearchProducts 按条件筛选商品 返回当前页商品和总数
+func (s *ProductService) SearchProducts(ctx context.Context, filter model.ProductFilter) ([]model.Product, int64, error) {
+	if filter.Status != "" && !model.IsValidProductStatus(filter.Status) {
+		return nil, 0, util.NewBusinessError("INVALID_STATUS", "商品状态无效: "+filter.Status, util.ErrInvalidInput)
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return nil, 0, util.NewBusinessError("INVALID_PARAMS", "最低价格不能大于最高价格", util.ErrInvalidInput)
+	}
+	// 按分类筛选时包含所有子孙分类下的商品
+	if filter.CategoryID != 0 {
+		category, err := s.getCategory(ctx, filter.CategoryID)
+		if err != nil {
+			return nil, 0, err
+		}
+		filter.CategoryIDs, err = s.categoryRepo.SubtreeIDs(ctx, category)
+		if err != nil {
+			return nil, 0, util.NewBusinessError("QUERY_FAILED", "查询子分类失败", err)
+		}
+	}
+
+	products, total, err := s.productRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, 0, util.NewBusinessError("QUERY_FAILED", "查询商品列表失败", err)
+	}
+	return products, total, nil
+}
+
 // CreateProduct 创建商品
 func (s *ProductService) CreateProduct(ctx context.Context, product *model.Product) error {
-	return s.productRepo.Create(ctx, product)
+	// 未指定状态时默认上架
+	if product.Status == "" {
+		product.Status = model.ProductStatusActive
+	}
+	if !model.IsValidProductStatus(product.Status) {
+		return util.NewBusinessError("INVALID_STATUS", "商品状态无效: "+product.Status, util.ErrInvalidInput)
+	}
+	// 指定分类ID时以分类表中的名称为准
+	if product.CategoryID != 0 {
+		category, err := s.getCategory(ctx, product.CategoryID)
+		if err != nil {
+			return err
+		}
+		product.Category = category.Name
+	}
+	// 可以在创建商品时一起传入SKU 与商品在同一事务中写入
+	if err := model.ValidateSKUs(product.SKUs); err != nil {
+		return util.NewBusinessError("INVALID_SKU", err.Error(), util.ErrInvalidInput)
+	}
+	if err := s.productRepo.Create(ctx, product); err != nil {
+		return err
+	}
+	product.Variants = model.BuildVariantMatrix(product.SKUs)
+	// 清除可能存在的空值缓存 并加入全文索引
+	s.invalidate(ctx, product.ID)
+	s.indexProduct(product)
+	return nil
+}
+
+// ProductUpdate 商品更新字段 nil表示不修改
+type ProductUpdate struct {
+	Name        *string
+	Description *string
+	Price       *float64
+	Category    *string
+	CategoryID  *int   // 0表示取消关联分类
+	Operator    string // 操作人 修改价格时记录在价格变更记录中
+}
+
+// UpdateProduct 更新商品信息（乐观锁）
+// version为客户端读取商品时的版本号 期间被其他人修改过则返回版本冲突
+func (s *ProductService) UpdateProduct(ctx context.Context, productID, version int, update ProductUpdate) (*model.Product, error) {
+	// 1. 参数验证
+	updates := make(map[string]interface{})
+	if update.Name != nil {
+		if *update.Name == "" {
+			return nil, util.NewBusinessError("INVALID_PARAMS", "商品名称不能为空", util.ErrInvalidInput)
+		}
+		updates["name"] = *update.Name
+	}
+	if update.Description != nil {
+		updates["description"] = *update.Description
+	}
+	if update.Price != nil {
+		if *update.Price <= 0 {
+			return nil, util.NewBusinessError("INVALID_PARAMS", "商品价格必须大于0", util.ErrInvalidInput)
+		}
+		updates["price"] = *update.Price
+	}
+	if update.Category != nil {
+		updates["category"] = *update.Category
+	}
+	if update.CategoryID != nil {
+		updates["category_id"] = *update.CategoryID
+		if *update.CategoryID != 0 {
+			category, err := s.getCategory(ctx, *update.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			updates["category"] = category.Name
+		}
+	}
+	if len(updates) == 0 {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "没有需要更新的字段", util.ErrInvalidInput)
+	}
+
+	// 2. 乐观锁更新 修改价格时在同一事务中写入价格变更记录
+	var err error
+	if update.Price != nil {
+		err = s.updatePrice(ctx, productID, version, updates, model.PriceHistory{
+			NewPrice:  *update.Price,
+			Source:    model.PriceSourceManual,
+			ChangedBy: update.Operator,
+		})
+	} else {
+		err = s.productRepo.Update(ctx, productID, version, updates)
+	}
+	if err != nil {
+		return nil, productWriteError(err)
+	}
+
+	// 3. 清除多级缓存 返回最新数据
+	s.invalidate(ctx, productID)
+	return s.reloadAndIndex(ctx, productID)
+}
+
+// ChangeStatus 变更商品状态 只允许合法的状态流转
+// draft -> active/discontinued, active <-> inactive, active/inactive -> discontinued
+func (s *ProductService) ChangeStatus(ctx context.Context, productID, version int, status string) (*model.Product, error) {
+	if !model.IsValidProductStatus(status) {
+		return nil, util.NewBusinessError("INVALID_STATUS", "商品状态无效: "+status, util.ErrInvalidInput)
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, productWriteError(err)
+	}
+	if product.Version != version {
+		return nil, productWriteError(repository.ErrVersionConflict)
+	}
+	if !model.CanTransitionProductStatus(product.Status, status) {
+		return nil, util.NewBusinessError("INVALID_STATUS_TRANSITION",
+			"商品状态不能从"+product.Status+"变更为"+status, util.ErrInvalidInput)
+	}
+
+	if err := s.productRepo.UpdateStatus(ctx, productID, version, status); err != nil {
+		return nil, productWriteError(err)
+	}
+
+	s.invalidate(ctx, productID)
+	return s.reloadAndIndex(ctx, productID)
+}
+
+// DeleteProduct 软删除商品 version小于0时不校验版本号
+func (s *ProductService) DeleteProduct(ctx context.Context, productID, version int) error {
+	if err := s.productRepo.Delete(ctx, productID, version); err != nil {
+		return productWriteError(err)
+	}
+	s.invalidate(ctx, productID)
+	s.searchIndex.Load().Remove(productID)
+	return nil
+}
+
+// SyncCategoryName 分类改名后同步商品上的分类名称
+// 改名是低频操作 直接清空商品缓存并重建全文索引 不逐个失效
+func (s *ProductService) SyncCategoryName(ctx context.Context, categoryID int, name string) error {
+	affected, err := s.productRepo.SyncCategoryName(ctx, categoryID, name)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return nil
+	}
+	if _, err := s.PurgeCache(ctx); err != nil {
+		util.GlobalLogger.Warn(ctx, "清空商品缓存失败",
+			util.Field{Key: "category_id", Value: categoryID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	return s.RebuildSearchIndex(ctx)
+}
+
+// getCategory 查询分类 不存在时返回业务错误
+func (s *ProductService) getCategory(ctx context.Context, categoryID int) (*model.Category, error) {
+	category, err := s.categoryRepo.GetByID(ctx, categoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("CATEGORY_NOT_FOUND", "分类不存在", util.ErrNotFound)
+	}
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询分类失败", err)
+	}
+	return category, nil
+}
+
+// productWriteError 将repo层的错误转换为业务错误
+func productWriteError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return util.NewBusinessError("PRODUCT_NOT_FOUND", "商品不存在", util.ErrNotFound)
+	case errors.Is(err, repository.ErrVersionConflict):
+		return util.NewBusinessError("VERSION_CONFLICT", "商品已被修改，请刷新后重试", err)
+	default:
+		return util.NewBusinessError("PRODUCT_UPDATE_FAILED", "商品更新失败", err)
+	}
 }
 
 // DecreaseStock 扣减库存（乐观锁）
@@ -75,81 +370,168 @@ func (s *ProductService) DecreaseStock(ctx context.Context, productID, quantity
 		return err
 	}
 
-	// 4. 清除本地缓存
-	s.localCache.Delete(productID)
+	// 4. 清除多级缓存
+	s.invalidate(ctx, productID)
 
 	return nil
 }
 
+// CacheStats 获取商品各级缓存的统计信息
+func (s *ProductService) CacheStats(ctx context.Context) (CacheTierStats, error) {
+	stats := CacheTierStats{
+		Local: s.localCache.Stats(),
+		Redis: s.redisStats.Stats(),
+	}
+	size, err := s.productRepo.CountCache(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Redis.Size = size
+	return stats, nil
+}
+
+// EvictCache 删除单个商品的本地缓存和Redis缓存 并通知其他实例
+func (s *ProductService) EvictCache(ctx context.Context, productID int) error {
+	s.localCache.Delete(productID)
+	if err := s.productRepo.DelFromCache(ctx, productID); err != nil {
+		return err
+	}
+	return s.invalidator.Publish(ctx, util.CacheEntityProduct, strconv.Itoa(productID))
+}
+
+// PurgeCache 清空所有商品的本地缓存和Redis缓存 并通知其他实例 返回删除的Redis key数量
+func (s *ProductService) PurgeCache(ctx context.Context) (int64, error) {
+	s.localCache.Purge()
+	deleted, err := s.productRepo.PurgeCache(ctx)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, s.invalidator.Publish(ctx, util.CacheEntityProduct, "")
+}
+
+// invalidate 商品或库存变更后清除本地缓存和Redis缓存 并通知其他实例
+func (s *ProductService) invalidate(ctx context.Context, productID int) {
+	s.localCache.Delete(productID)
+	if err := s.productRepo.DelFromCache(ctx, productID); err != nil {
+		util.GlobalLogger.Warn(ctx, "Redis缓存删除失败",
+			util.Field{Key: "product_id", Value: productID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	if err := s.invalidator.Publish(ctx, util.CacheEntityProduct, strconv.Itoa(productID)); err != nil {
+		util.GlobalLogger.Warn(ctx, "缓存失效广播失败",
+			util.Field{Key: "product_id", Value: productID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
+
 // GetStock 获取库存
 func (s *ProductService) GetStock(ctx context.Context, productID int) (int, error) {
 	return s.productRepo.GetStock(ctx, productID)
 }
 
+// 批量查询商品的参数
+const (
+	maxRecommendIDs           = 100 // 一次查询的ID数量上限
+	recommendFetchConcurrency = 8   // 并发查询的协程数上限
+)
+
+// RecommendResult 批量查询商品结果
+type RecommendResult struct {
+	Products []*model.Product `json:"products"` // 按请求的ID顺序排列 重复的ID只返回一次
+	Missing  []int            `json:"missing"`  // 不存在、已删除或不可购买的商品ID
+}
+
 // RecommendProducts 批量推荐商品
-// 使用wait group 并发查询每个商品的信息 统一聚合结果
-func (s *ProductService) RecommendProducts(ctx context.Context, ids []int) ([]*model.Product, error) {
+// 每个商品都走GetProduct的多级缓存和缓存加载器 与单个商品查询共用缓存统计和失效逻辑
+func (s *ProductService) RecommendProducts(ctx context.Context, ids []int) (*RecommendResult, error) {
 	if len(ids) == 0 {
-		// 默认推荐前10个商品
-		products, err := s.productRepo.GetAll(ctx, 1, 10)
+		// 默认推荐销量最高的10个商品
+		products, err := s.productRepo.GetBestSellers(ctx, 10)
 		if err != nil {
-			return nil, err
+			return nil, util.NewBusinessError("QUERY_FAILED", "查询商品失败", err)
 		}
-		result := make([]*model.Product, 0, len(products))
+		result := &RecommendResult{Products: make([]*model.Product, 0, len(products)), Missing: []int{}}
 		for i := range products {
-			result = append(result, &products[i])
+			result.Products = append(result.Products, &products[i])
 		}
 		return result, nil
 	}
+	return FetchProductsInOrder(ctx, ids, s.GetProduct)
+}
 
-	// 并发拉取每个商品详情
-	result := make([]*model.Product, len(ids))
-	errList := make([]error, len(ids))
-	// 通过waitgroup并发查询每一个商品详情 最后汇聚成一个推荐页
-	// 比如我们的推荐页默认为10个商品 那么并发查找就能够让原来的10个商品查询时间 变为最慢的一个
-	// 这是我想到的适合waitgroup的场景
-	var wg sync.WaitGroup
+// ProductFetcher 查询单个商品 商品不存在时返回util.ErrNotFound或gorm.ErrRecordNotFound
+type ProductFetcher func(ctx context.Context, productID int) (*model.Product, error)
 
+// FetchProductsInOrder 用有限个协程并发查询一组商品 按请求顺序返回可购买的商品
+// 查不到或不可购买的ID记录在Missing中 其他查询错误直接返回 不静默丢弃
+func FetchProductsInOrder(ctx context.Context, ids []int, fetch ProductFetcher) (*RecommendResult, error) {
+	// 1. 去重 保持请求顺序
+	ids = uniqueIDs(ids)
+	if len(ids) > maxRecommendIDs {
+		return nil, util.NewBusinessError("INVALID_PARAMS", fmt.Sprintf("一次最多查询%d个商品", maxRecommendIDs), util.ErrInvalidInput)
+	}
+
+	// 2. 并发查询 结果按下标写回 不需要加锁
+	products := make([]*model.Product, len(ids))
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, recommendFetchConcurrency)
+	var wg sync.WaitGroup
 	for i, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
 		wg.Add(1)
-		go func(idx, pid int) {
+		go func(i, id int) {
 			defer wg.Done()
-			// 先查本地缓存
-			if cacheValue, exists := s.localCache.Load(pid); exists {
-				if cache, ok := cacheValue.(*model.Product); ok {
-					result[idx] = cache
-					return
-				}
-			}
-			// 查数据库
-			product, err := s.productRepo.GetByID(ctx, pid)
-			if err != nil {
-				errList[idx] = err
-				return
-			}
-			result[idx] = product
-			// 写入本地缓存
-			s.localCache.Store(pid, product)
+			defer func() { <-sem }()
+			products[i], errs[i] = fetch(ctx, id)
 		}(i, id)
 	}
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// 3. 按请求顺序组装结果 记录查不到的ID
+	result := &RecommendResult{
+		Products: make([]*model.Product, 0, len(ids)),
+		Missing:  []int{},
+	}
+	for i, id := range ids {
+		switch err := errs[i]; {
+		case err == nil && products[i] != nil && products[i].IsPurchasable():
+			result.Products = append(result.Products, products[i])
+		case err == nil, errors.Is(err, util.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+			result.Missing = append(result.Missing, id)
+		default:
+			return nil, util.NewBusinessError("QUERY_FAILED", "查询商品失败", err)
+		}
+	}
+	return result, nil
+}
 
-	// 过滤掉未查到的商品（如有）
-	finalResult := make([]*model.Product, 0, len(ids))
-	for i, p := range result {
-		if p != nil && errList[i] == nil {
-			finalResult = append(finalResult, p)
+// uniqueIDs 去掉重复的ID 保持第一次出现的顺序
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	result := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
 		}
 	}
-	return finalResult, nil
+	return result
 }
 
 // RecommendProductsSerial 串行方式批量查询商品详情
 func (s *ProductService) RecommendProductsSerial(ctx context.Context, ids []int) ([]*model.Product, error) {
 	var products []*model.Product
 	if len(ids) == 0 {
-		// 默认推荐前10个商品
-		all, err := s.productRepo.GetAll(ctx, 1, 10)
+		// 默认推荐销量最高的10个商品
+		all, err := s.productRepo.GetBestSellers(ctx, 10)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ListSKUs 查询商品下的所有SKU
+func (s *ProductService) ListSKUs(ctx context.Context, productID int) ([]model.SKU, error) {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, productWriteError(err)
+	}
+	return product.SKUs, nil
+}
+
+// CreateSKU 为商品新增SKU 规格维度需要与已有SKU一致且组合不重复
+func (s *ProductService) CreateSKU(ctx context.Context, productID int, sku *model.SKU) error {
+	// 1. 校验商品存在 并与已有SKU一起校验规格
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return productWriteError(err)
+	}
+	sku.ID = 0
+	sku.ProductID = productID
+	if err := model.ValidateSKUs(append(product.SKUs, *sku)); err != nil {
+		return util.NewBusinessError("INVALID_SKU", err.Error(), util.ErrInvalidInput)
+	}
+
+	// 2. 写入数据库
+	if err := s.skuRepo.Create(ctx, sku); err != nil {
+		return util.NewBusinessError("SKU_CREATE_FAILED", "创建SKU失败", err)
+	}
+
+	// 3. 商品缓存中包含SKU列表和规格矩阵 需要一起失效
+	s.invalidate(ctx, productID)
+	return nil
+}
+
+// SKUUpdate SKU更新字段 nil表示不修改
+type SKUUpdate struct {
+	Code       *string
+	Attributes []model.SKUAttribute
+	Price      *float64
+	Stock      *int
+	Operator   string // 操作人 修改价格时记录在价格变更记录中
+}
+
+// UpdateSKU 更新SKU（乐观锁）
+func (s *ProductService) UpdateSKU(ctx context.Context, productID, skuID, version int, update SKUUpdate) (*model.SKU, error) {
+	// 1. 查询商品和SKU 在修改后的SKU列表上重新校验规格
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, productWriteError(err)
+	}
+	current := product.FindSKU(skuID)
+	if current == nil {
+		return nil, util.NewBusinessError("SKU_NOT_FOUND", "SKU不存在", util.ErrNotFound)
+	}
+
+	updates := make(map[string]interface{})
+	updated := *current
+	if update.Code != nil {
+		// 传入空字符串表示清除编码
+		updated.Code = model.NormalizeSKUCode(update.Code)
+		updates["code"] = updated.Code
+	}
+	if update.Attributes != nil {
+		updated.Attributes = update.Attributes
+		updates["attributes"] = update.Attributes
+	}
+	if update.Price != nil {
+		updated.Price = *update.Price
+		updates["price"] = *update.Price
+	}
+	if update.Stock != nil {
+		updated.Stock = *update.Stock
+		updates["stock"] = *update.Stock
+	}
+	if len(updates) == 0 {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "没有需要更新的字段", util.ErrInvalidInput)
+	}
+
+	skus := make([]model.SKU, len(product.SKUs))
+	copy(skus, product.SKUs)
+	for i := range skus {
+		if skus[i].ID == skuID {
+			skus[i] = updated
+		}
+	}
+	if err := model.ValidateSKUs(skus); err != nil {
+		return nil, util.NewBusinessError("INVALID_SKU", err.Error(), util.ErrInvalidInput)
+	}
+
+	// 2. 乐观锁更新
+	// 修改价格时在同一事务中写入价格变更记录
+	if update.Price != nil {
+		err = s.updateSKUPrice(ctx, productID, skuID, version, updates, model.PriceHistory{
+			NewPrice:  *update.Price,
+			Source:    model.PriceSourceManual,
+			ChangedBy: update.Operator,
+		})
+	} else {
+		err = s.skuRepo.Update(ctx, skuID, version, updates)
+	}
+	if err != nil {
+		return nil, skuWriteError(err)
+	}
+
+	// 3. 清除商品缓存 返回最新数据
+	s.invalidate(ctx, productID)
+	sku, err := s.skuRepo.GetByID(ctx, skuID)
+	if err != nil {
+		return nil, skuWriteError(err)
+	}
+	return sku, nil
+}
+
+// DeleteSKU 删除SKU
+func (s *ProductService) DeleteSKU(ctx context.Context, productID, skuID int) error {
+	if err := s.skuRepo.Delete(ctx, productID, skuID); err != nil {
+		return skuWriteError(err)
+	}
+	s.invalidate(ctx, productID)
+	return nil
+}
+
+// skuWriteError 将repo层的错误转换为业务错误
+func skuWriteError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return util.NewBusinessError("SKU_NOT_FOUND", "SKU不存在", util.ErrNotFound)
+	case errors.Is(err, repository.ErrVersionConflict):
+		return util.NewBusinessError("VERSION_CONFLICT", "SKU已被修改，请刷新后重试", err)
+	default:
+		return util.NewBusinessError("SKU_UPDATE_FAILED", "SKU更新失败", err)
+	}
+}
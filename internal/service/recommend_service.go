@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 推荐来源
+const (
+	RecommendReasonCoPurchase = "co_purchase" // 经常一起购买
+	RecommendReasonBestSeller = "best_seller" // 热销商品兜底
+)
+
+// 推荐计算参数
+const (
+	recommendOrderBatchSize = 1000 // 遍历订单的批次大小
+	coPurchaseTopK          = 50   // 每个商品保存的共同购买商品数量
+	bestSellersTopK         = 200  // 保存的热销商品数量
+	recommendUserOrders     = 20   // 用户推荐参考的最近订单数量
+	recommendUserSeeds      = 20   // 用户推荐参考的已购商品数量
+	recommendJobName        = "recommend_rebuild"
+)
+
+// Recommendation 推荐结果
+type Recommendation struct {
+	Product *model.Product `json:"product"`
+	Score   float64        `json:"score"`
+	Reason  string         `json:"reason"` // co_purchase / best_seller
+}
+
+// RecommendService 基于订单历史的商品推荐
+// 定时离线计算商品两两同单购买的次数写入Redis 查询时只读Redis和商品详情
+type RecommendService struct {
+	orderRepo      *repository.OrderRepo
+	productRepo    *repository.ProductRepo
+	recommendRepo  *repository.RecommendRepo
+	productService *ProductService
+	window         time.Duration // 统计最近多长时间的订单
+}
+
+// NewRecommendService 创建推荐服务实例
+func NewRecommendService(orderRepo *repository.OrderRepo, productRepo *repository.ProductRepo, recommendRepo *repository.RecommendRepo, productService *ProductService, window time.Duration) *RecommendService {
+	return &RecommendService{
+		orderRepo:      orderRepo,
+		productRepo:    productRepo,
+		recommendRepo:  recommendRepo,
+		productService: productService,
+		window:         window,
+	}
+}
+
+// Rebuild 遍历统计窗口内的订单 重新计算共同购买推荐和热销榜单
+// ttl为结果的过期时间 需要大于重建间隔 重建失败时旧结果继续可用
+func (s *RecommendService) Rebuild(ctx context.Context, ttl time.Duration) error {
+	start := time.Now()
+	counter := model.NewCoPurchaseCounter()
+	orders := 0
+
+	// 1. 分批遍历订单统计同单购买次数
+	err := s.orderRepo.FindSinceInBatches(ctx, start.Add(-s.window), recommendOrderBatchSize, func(batch []model.Order) error {
+		for i := range batch {
+			var items []model.OrderItem
+			if err := json.Unmarshal([]byte(batch[i].Items), &items); err != nil {
+				util.GlobalLogger.Warn(ctx, "订单商品列表解析失败 跳过",
+					util.Field{Key: "order_id", Value: batch[i].ID},
+					util.Field{Key: "error", Value: err.Error()},
+				)
+				continue
+			}
+			if counter.CountOrder(batch[i].Status, items) {
+				orders++
+			}
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	// 2. 写入Redis
+	products := counter.Products()
+	recs := make(map[int][]model.ScoredProduct, len(products))
+	for _, id := range products {
+		recs[id] = counter.Top(id, coPurchaseTopK)
+	}
+	if err := s.recommendRepo.SaveCoPurchase(ctx, recs, ttl); err != nil {
+		return err
+	}
+	if err := s.recommendRepo.SaveBestSellers(ctx, counter.BestSellers(bestSellersTopK), ttl); err != nil {
+		return err
+	}
+
+	util.GlobalLogger.Info(ctx, "商品推荐重建完成",
+		util.Field{Key: "orders", Value: orders},
+		util.Field{Key: "products", Value: len(products)},
+		util.Field{Key: "duration", Value: time.Since(start).String()},
+	)
+	return nil
+}
+
+// StartRefresher 启动后立即重建一次 之后定时重建 阻塞直到ctx取消
+// 多实例部署时通过任务锁保证每个周期只有一个实例计算 锁不主动释放 略短于周期后自动过期
+// interval不大于0时不启动
+func (s *RecommendService) StartRefresher(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		util.GlobalLogger.Warn(ctx, "商品推荐重建间隔无效 不启动定时重建任务",
+			util.Field{Key: "interval", Value: interval.String()},
+		)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lockKey := util.NewLockKeyGenerator().GenerateJobLockKey(recommendJobName)
+	for {
+		lock := util.NewDistributedLock(util.RedisClient, lockKey, interval*9/10)
+		if locked, err := lock.TryLock(ctx); err != nil {
+			util.GlobalLogger.Error(ctx, "商品推荐重建任务加锁失败", err)
+		} else if locked {
+			// 过期时间为三个周期 连续失败两次之前旧结果都不会丢失
+			if err := s.Rebuild(ctx, 3*interval); err != nil {
+				util.GlobalLogger.Error(ctx, "商品推荐重建失败", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecommendForProduct 查询经常与该商品一起购买的商品 不足limit时用热销商品补齐
+func (s *RecommendService) RecommendForProduct(ctx context.Context, productID, limit int) ([]Recommendation, error) {
+	// 1. 确认商品存在
+	if _, err := s.productService.GetProduct(ctx, productID); err != nil {
+		if errors.Is(err, util.ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, util.NewBusinessError("PRODUCT_NOT_FOUND", "商品不存在", util.ErrNotFound)
+		}
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询商品失败", err)
+	}
+
+	// 2. 读取共同购买推荐 多取一些以便过滤已下架的商品 Redis不可用时直接用热销商品
+	coPurchase, err := s.recommendRepo.GetCoPurchase(ctx, productID, 2*limit)
+	if err != nil {
+		util.GlobalLogger.Warn(ctx, "共同购买推荐读取失败 降级为热销商品",
+			util.Field{Key: "product_id", Value: productID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+
+	return s.assemble(ctx, coPurchase, map[int]bool{productID: true}, limit)
+}
+
+// RecommendForUser 根据用户最近购买的商品汇总共同购买推荐 排除已经买过的商品
+// 新用户或没有共同购买数据时返回热销商品
+func (s *RecommendService) RecommendForUser(ctx context.Context, userID string, limit int) ([]Recommendation, error) {
+	// 1. 查询用户最近买过的商品
+	orders, err := s.orderRepo.GetRecentByUser(ctx, userID, recommendUserOrders)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询用户订单失败", err)
+	}
+	purchased := make(map[int]bool)
+	var seeds []int
+	for _, order := range orders {
+		var items []model.OrderItem
+		if err := json.Unmarshal([]byte(order.Items), &items); err != nil {
+			continue
+		}
+		for _, item := range items {
+			if !purchased[item.ProductID] {
+				purchased[item.ProductID] = true
+				if len(seeds) < recommendUserSeeds {
+					seeds = append(seeds, item.ProductID)
+				}
+			}
+		}
+	}
+
+	// 2. 汇总每个已购商品的共同购买推荐 多个已购商品都推荐的商品分数更高
+	var candidates []model.ScoredProduct
+	if len(seeds) > 0 {
+		lists, err := s.recommendRepo.GetCoPurchaseBatch(ctx, seeds, coPurchaseTopK)
+		if err != nil {
+			util.GlobalLogger.Warn(ctx, "共同购买推荐读取失败 降级为热销商品",
+				util.Field{Key: "user_id", Value: userID},
+				util.Field{Key: "error", Value: err.Error()},
+			)
+		}
+		candidates = model.MergeScoredProducts(lists, purchased, 2*limit)
+	}
+
+	return s.assemble(ctx, candidates, purchased, limit)
+}
+
+// assemble 按顺序加载候选商品详情 跳过已下架和exclude中的商品 不足limit时用热销商品补齐
+func (s *RecommendService) assemble(ctx context.Context, candidates []model.ScoredProduct, exclude map[int]bool, limit int) ([]Recommendation, error) {
+	result, err := s.loadRecommendations(ctx, candidates, RecommendReasonCoPurchase, exclude, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) >= limit {
+		return result, nil
+	}
+
+	// 已经推荐的商品不再重复出现
+	for _, rec := range result {
+		exclude[rec.Product.ID] = true
+	}
+	bestSellers, err := s.bestSellers(ctx, limit+len(exclude))
+	if err != nil {
+		return nil, err
+	}
+	fill, err := s.loadRecommendations(ctx, bestSellers, RecommendReasonBestSeller, exclude, limit-len(result))
+	if err != nil {
+		return nil, err
+	}
+	return append(result, fill...), nil
+}
+
+// bestSellers 热销商品榜单 还没有计算过或Redis不可用时按商品累计销量
+func (s *RecommendService) bestSellers(ctx context.Context, limit int) ([]model.ScoredProduct, error) {
+	list, err := s.recommendRepo.GetBestSellers(ctx, limit)
+	if err == nil && len(list) > 0 {
+		return list, nil
+	}
+	if err != nil {
+		util.GlobalLogger.Warn(ctx, "热销榜单读取失败 改为查询数据库",
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+
+	products, err := s.productRepo.GetBestSellers(ctx, limit)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询热销商品失败", err)
+	}
+	list = make([]model.ScoredProduct, len(products))
+	for i, p := range products {
+		list[i] = model.ScoredProduct{ProductID: p.ID, Score: float64(p.Sales)}
+	}
+	return list, nil
+}
+
+// loadRecommendations 批量查询候选商品详情 保持候选顺序 只保留可以购买的商品
+func (s *RecommendService) loadRecommendations(ctx context.Context, candidates []model.ScoredProduct, reason string, exclude map[int]bool, limit int) ([]Recommendation, error) {
+	ids := make([]int, 0, len(candidates))
+	for _, c := range candidates {
+		if !exclude[c.ProductID] {
+			ids = append(ids, c.ProductID)
+		}
+	}
+	products, err := s.productRepo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询商品失败", err)
+	}
+	productMap := make(map[int]*model.Product, len(products))
+	for i := range products {
+		productMap[products[i].ID] = &products[i]
+	}
+
+	result := make([]Recommendation, 0, limit)
+	for _, c := range candidates {
+		if len(result) >= limit {
+			break
+		}
+		product, ok := productMap[c.ProductID]
+		if !ok || exclude[c.ProductID] || !product.IsPurchasable() {
+			continue
+		}
+		result = append(result, Recommendation{Product: product, Score: c.Score, Reason: reason})
+	}
+	return result, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefundService 订单退款 支持全额退款和按商品行部分退款 退款成功后退回库存
+type RefundService struct {
+	refundRepo    *repository.RefundRepo
+	paymentRepo   *repository.PaymentRepo
+	orderRepo     *repository.OrderRepo
+	skuRepo       *repository.SKURepo
+	inventoryRepo *repository.InventoryRepo
+	orderService  *OrderService
+	provider      PaymentProvider
+}
+
+// NewRefundService 创建退款服务实例
+func NewRefundService(refundRepo *repository.RefundRepo, paymentRepo *repository.PaymentRepo, orderRepo *repository.OrderRepo, skuRepo *repository.SKURepo, inventoryRepo *repository.InventoryRepo, orderService *OrderService, provider PaymentProvider) *RefundService {
+	return &RefundService{
+		refundRepo:    refundRepo,
+		paymentRepo:   paymentRepo,
+		orderRepo:     orderRepo,
+		skuRepo:       skuRepo,
+		inventoryRepo: inventoryRepo,
+		orderService:  orderService,
+		provider:      provider,
+	}
+}
+
+// CreateRefund 为订单发起退款 lines为空时退还剩余的全部金额
+// 退款单先以处理中状态落库占用可退金额 渠道退款成功后再退回库存并更新订单
+func (s *RefundService) CreateRefund(ctx context.Context, orderID string, operatorID int, lines []model.RefundLineRequest, reason string) (*model.Refund, error) {
+	// 1. 参数验证
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > 255 {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "退款原因不能超过255个字符", util.ErrInvalidInput)
+	}
+
+	// 2. 校验订单状态
+	order, err := s.orderService.loadForUpdate(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !model.IsRefundableOrderStatus(order.Status) {
+		return nil, util.NewBusinessError("ORDER_NOT_REFUNDABLE", "订单当前状态不能退款: "+order.Status, util.ErrInvalidInput)
+	}
+	var items []model.OrderItem
+	if err := json.Unmarshal([]byte(order.Items), &items); err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "订单商品解析失败", err)
+	}
+
+	// 3. 锁定支付单 计算可退金额并创建处理中的退款单
+	var refund *model.Refund
+	var payment *model.Payment
+	err = s.refundRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		payment, err = s.paymentRepo.GetSucceededByOrderForUpdateWithTx(tx, orderID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return util.NewBusinessError("PAYMENT_NOT_FOUND", "订单没有支付成功的支付单", util.ErrInvalidInput)
+		}
+		if err != nil {
+			return util.NewBusinessError("QUERY_FAILED", "查询支付单失败", err)
+		}
+		previous, err := s.refundRepo.ListActiveByOrderWithTx(tx, orderID)
+		if err != nil {
+			return util.NewBusinessError("QUERY_FAILED", "查询退款记录失败", err)
+		}
+		refundLines, amount, err := model.PlanRefund(items, previous, lines, payment.Amount)
+		if err != nil {
+			return util.NewBusinessError("INVALID_REFUND", err.Error(), util.ErrInvalidInput)
+		}
+
+		refund = &model.Refund{
+			ID:         newRefundID(),
+			OrderID:    orderID,
+			PaymentID:  payment.ID,
+			Amount:     amount,
+			Lines:      refundLines,
+			Reason:     reason,
+			Status:     model.RefundStatusPending,
+			OperatorID: operatorID,
+		}
+		if err := s.refundRepo.CreateWithTx(tx, refund); err != nil {
+			return util.NewBusinessError("REFUND_CREATE_FAILED", "创建退款单失败", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 通过支付渠道原路退款 失败时退款单标记为失败 不再占用可退金额
+	result, err := s.provider.Refund(ctx, RefundRequest{
+		RefundID:   refund.ID,
+		PaymentRef: payment.ProviderRef,
+		Amount:     refund.Amount,
+	})
+	if err != nil {
+		util.GlobalLogger.Error(ctx, "支付渠道退款失败", err,
+			util.Field{Key: "order_id", Value: orderID},
+			util.Field{Key: "refund_id", Value: refund.ID},
+		)
+		if markErr := s.refundRepo.MarkFailed(ctx, refund.ID); markErr != nil {
+			util.GlobalLogger.Error(ctx, "退款单状态更新失败", markErr,
+				util.Field{Key: "refund_id", Value: refund.ID},
+			)
+		}
+		return nil, util.NewBusinessError("REFUND_FAILED", "支付渠道退款失败", err)
+	}
+
+	// 5. 退款成功 在同一事务中更新退款单、退回库存、累加订单已退金额
+	refundedAt := time.Now()
+	err = s.refundRepo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updated, err := s.refundRepo.MarkSucceededWithTx(tx, refund.ID, result.ProviderRef, refundedAt)
+		if err != nil || !updated {
+			return err
+		}
+		if err := s.restockWithTx(ctx, tx, refund.Lines); err != nil {
+			return err
+		}
+
+		// 退款成功的合计等于支付金额时订单变为已退款
+		if _, err := s.paymentRepo.GetSucceededByOrderForUpdateWithTx(tx, orderID); err != nil {
+			return err
+		}
+		refundedTotal, err := s.refundRepo.SumSucceededWithTx(tx, orderID)
+		if err != nil {
+			return err
+		}
+		fullyRefunded := model.RoundAmount(refundedTotal) >= model.RoundAmount(payment.Amount)
+		return s.orderRepo.AddRefundedAmountWithTx(tx, orderID, refund.Amount, fullyRefunded)
+	})
+	if err != nil {
+		// 渠道已经退款成功 本地状态需要人工核对
+		util.GlobalLogger.Error(ctx, "退款成功但本地状态更新失败 需要人工处理", err,
+			util.Field{Key: "order_id", Value: orderID},
+			util.Field{Key: "refund_id", Value: refund.ID},
+		)
+		return nil, util.NewBusinessError("REFUND_UPDATE_FAILED", "更新退款状态失败", err)
+	}
+
+	// 6. 订单和库存已变更 删除缓存
+	s.orderService.evictOrder(ctx, orderID)
+	for _, line := range refund.Lines {
+		if line.Quantity > 0 {
+			s.orderService.invalidateProduct(ctx, line.ProductID)
+		}
+	}
+
+	util.GlobalLogger.Info(ctx, "订单退款成功",
+		util.Field{Key: "order_id", Value: orderID},
+		util.Field{Key: "refund_id", Value: refund.ID},
+		util.Field{Key: "amount", Value: refund.Amount},
+	)
+	refund.Status = model.RefundStatusSucceeded
+	refund.ProviderRef = result.ProviderRef
+	refund.RefundedAt = &refundedAt
+	return refund, nil
+}
+
+// ListRefunds 查询订单的退款记录
+func (s *RefundService) ListRefunds(ctx context.Context, orderID string) ([]model.Refund, error) {
+	refunds, err := s.refundRepo.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询退款记录失败", err)
+	}
+	return refunds, nil
+}
+
+// restockWithTx 退回退款商品行的库存 多规格商品退回SKU库存
+// 商品或SKU已经删除时跳过 不影响退款
+func (s *RefundService) restockWithTx(ctx context.Context, tx *gorm.DB, lines []model.RefundLine) error {
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			continue
+		}
+		var err error
+		if line.SKUID != 0 {
+			err = s.skuRepo.IncreaseStockWithTx(tx, line.SKUID, line.Quantity)
+		} else {
+			err = s.inventoryRepo.IncreaseStockWithTx(tx, line.ProductID, line.Quantity)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			util.GlobalLogger.Warn(ctx, "退款商品库存记录不存在 跳过退回库存",
+				util.Field{Key: "product_id", Value: line.ProductID},
+				util.Field{Key: "sku_id", Value: line.SKUID},
+				util.Field{Key: "quantity", Value: line.Quantity},
+			)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRefundID 生成退款单ID 时间戳加随机数
+func newRefundID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return "r" + time.Now().Format("060102150405") + hex.EncodeToString(b)
+}
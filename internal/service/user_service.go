@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 用户不存在时也做一次哈希比较 避免通过响应时间判断用户名是否已注册
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// UserService 用户注册、登录和资料维护
+type UserService struct {
+	userRepo *repository.UserRepo
+}
+
+// NewUserService 创建用户服务实例
+func NewUserService(userRepo *repository.UserRepo) *UserService {
+	return &UserService{userRepo: userRepo}
+}
+
+// UserRegistration 注册信息
+type UserRegistration struct {
+	Username string
+	Password string
+	Nickname string
+	Email    string
+	Phone    string
+}
+
+// Register 注册普通用户
+func (s *UserService) Register(ctx context.Context, reg UserRegistration) (*model.User, error) {
+	// 1. 参数验证
+	reg.Username = strings.TrimSpace(reg.Username)
+	reg.Email = strings.TrimSpace(reg.Email)
+	if err := model.ValidateUsername(reg.Username); err != nil {
+		return nil, util.NewBusinessError("INVALID_PARAMS", err.Error(), util.ErrInvalidInput)
+	}
+	if err := model.ValidatePassword(reg.Password); err != nil {
+		return nil, util.NewBusinessError("INVALID_PARAMS", err.Error(), util.ErrInvalidInput)
+	}
+	if err := model.ValidateEmail(reg.Email); err != nil {
+		return nil, util.NewBusinessError("INVALID_PARAMS", err.Error(), util.ErrInvalidInput)
+	}
+
+	// 2. 密码哈希后写入数据库
+	hash, err := bcrypt.GenerateFromPassword([]byte(reg.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, util.NewBusinessError("USER_CREATE_FAILED", "注册失败", err)
+	}
+	nickname := strings.TrimSpace(reg.Nickname)
+	if nickname == "" {
+		nickname = reg.Username
+	}
+	user := &model.User{
+		Username:     reg.Username,
+		PasswordHash: string(hash),
+		Nickname:     nickname,
+		Email:        reg.Email,
+		Phone:        strings.TrimSpace(reg.Phone),
+		Role:         model.UserRoleCustomer,
+		Status:       model.UserStatusActive,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			return nil, util.NewBusinessError("USERNAME_TAKEN", err.Error(), util.ErrInvalidInput)
+		}
+		return nil, util.NewBusinessError("USER_CREATE_FAILED", "注册失败", err)
+	}
+	return user, nil
+}
+
+// Login 校验用户名和密码 用户名不存在和密码错误返回相同的错误
+func (s *UserService) Login(ctx context.Context, username, password string) (*model.User, error) {
+	user, err := s.userRepo.GetByUsername(ctx, strings.TrimSpace(username))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询用户失败", err)
+	}
+
+	hash := dummyPasswordHash
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || user == nil {
+		return nil, util.NewBusinessError("LOGIN_FAILED", "用户名或密码错误", util.ErrUnauthorized)
+	}
+	if !user.IsActive() {
+		return nil, util.NewBusinessError("USER_DISABLED", "账号已被禁用", util.ErrUnauthorized)
+	}
+
+	now := time.Now()
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID, now); err != nil {
+		util.GlobalLogger.Warn(ctx, "更新最后登录时间失败",
+			util.Field{Key: "user_id", Value: user.ID},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	user.LastLoginAt = &now
+	return user, nil
+}
+
+// GetUser 查询用户资料
+func (s *UserService) GetUser(ctx context.Context, id int) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewBusinessError("USER_NOT_FOUND", "用户不存在", util.ErrNotFound)
+	}
+	if err != nil {
+		return nil, util.NewBusinessError("QUERY_FAILED", "查询用户失败", err)
+	}
+	return user, nil
+}
+
+// GetActiveUser 根据订单中的用户ID查询用户 并校验用户可以下单
+func (s *UserService) GetActiveUser(ctx context.Context, userID string) (*model.User, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, util.NewBusinessError("USER_NOT_FOUND", "用户不存在", util.ErrNotFound)
+	}
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive() {
+		return nil, util.NewBusinessError("USER_DISABLED", "账号已被禁用", util.ErrInvalidInput)
+	}
+	return user, nil
+}
+
+// UserUpdate 用户资料更新字段 nil表示不修改
+type UserUpdate struct {
+	Nickname *string
+	Email    *string
+	Phone    *string
+}
+
+// UpdateProfile 修改用户资料
+func (s *UserService) UpdateProfile(ctx context.Context, id int, update UserUpdate) (*model.User, error) {
+	// 1. 参数验证 组装需要修改的字段
+	updates := make(map[string]interface{})
+	if update.Nickname != nil {
+		nickname := strings.TrimSpace(*update.Nickname)
+		if nickname == "" {
+			return nil, util.NewBusinessError("INVALID_PARAMS", "昵称不能为空", util.ErrInvalidInput)
+		}
+		updates["nickname"] = nickname
+	}
+	if update.Email != nil {
+		email := strings.TrimSpace(*update.Email)
+		if err := model.ValidateEmail(email); err != nil {
+			return nil, util.NewBusinessError("INVALID_PARAMS", err.Error(), util.ErrInvalidInput)
+		}
+		updates["email"] = email
+	}
+	if update.Phone != nil {
+		updates["phone"] = strings.TrimSpace(*update.Phone)
+	}
+	if len(updates) == 0 {
+		return nil, util.NewBusinessError("INVALID_PARAMS", "没有需要修改的字段", util.ErrInvalidInput)
+	}
+
+	// 2. 写入数据库并返回最新资料
+	if err := s.userRepo.Update(ctx, id, updates); err != nil {
+		return nil, userWriteError(err)
+	}
+	return s.GetUser(ctx, id)
+}
+
+// ChangePassword 修改密码 需要校验原密码
+func (s *UserService) ChangePassword(ctx context.Context, id int, oldPassword, newPassword string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)) != nil {
+		return util.NewBusinessError("WRONG_PASSWORD", "原密码错误", util.ErrInvalidInput)
+	}
+	if err := model.ValidatePassword(newPassword); err != nil {
+		return util.NewBusinessError("INVALID_PARAMS", err.Error(), util.ErrInvalidInput)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return util.NewBusinessError("USER_UPDATE_FAILED", "修改密码失败", err)
+	}
+	if err := s.userRepo.Update(ctx, id, map[string]interface{}{"password_hash": string(hash)}); err != nil {
+		return userWriteError(err)
+	}
+	return nil
+}
+
+// ListUsers 管理员按条件分页查询用户
+func (s *UserService) ListUsers(ctx context.Context, filter model.UserFilter) ([]model.User, int64, error) {
+	if filter.Status != "" && !model.IsValidUserStatus(filter.Status) {
+		return nil, 0, util.NewBusinessError("INVALID_STATUS", "无效的用户状态: "+filter.Status, util.ErrInvalidInput)
+	}
+	if filter.Role != "" && !model.IsValidUserRole(filter.Role) {
+		return nil, 0, util.NewBusinessError("INVALID_ROLE", "无效的用户角色: "+filter.Role, util.ErrInvalidInput)
+	}
+	users, total, err := s.userRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, util.NewBusinessError("QUERY_FAILED", "查询用户列表失败", err)
+	}
+	return users, total, nil
+}
+
+// ChangeStatus 管理员启用或禁用用户 禁用后不能登录和下单
+func (s *UserService) ChangeStatus(ctx context.Context, id int, status string) (*model.User, error) {
+	if !model.IsValidUserStatus(status) {
+		return nil, util.NewBusinessError("INVALID_STATUS", "无效的用户状态: "+status, util.ErrInvalidInput)
+	}
+	if err := s.userRepo.Update(ctx, id, map[string]interface{}{"status": status}); err != nil {
+		return nil, userWriteError(err)
+	}
+	return s.GetUser(ctx, id)
+}
+
+// EnsureAdmin 启动时创建初始管理员 用户名已存在时不做任何修改
+func (s *UserService) EnsureAdmin(ctx context.Context, username, password string) error {
+	if _, err := s.userRepo.GetByUsername(ctx, username); err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err := model.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	err = s.userRepo.Create(ctx, &model.User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Nickname:     username,
+		Role:         model.UserRoleAdmin,
+		Status:       model.UserStatusActive,
+	})
+	if errors.Is(err, repository.ErrUsernameTaken) {
+		// 其他实例同时创建了管理员
+		return nil
+	}
+	return err
+}
+
+// userWriteError 将repo层的错误转换为业务错误
+func userWriteError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.NewBusinessError("USER_NOT_FOUND", "用户不存在", util.ErrNotFound)
+	}
+	return util.NewBusinessError("USER_UPDATE_FAILED", "用户更新失败", err)
+}
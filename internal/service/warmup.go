@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/util"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// WarmupOptions 缓存预热参数
+type WarmupOptions struct {
+	Products int           // 预热的热门商品数量
+	Orders   int           // 预热的最近订单数量
+	Timeout  time.Duration // 时间预算 超时后停止预热
+}
+
+// WarmUp 启动时预热缓存
+// 发布后本地缓存是空的 第一波流量会全部打到MySQL 所以在服务对外提供之前先加载最近的订单和热门商品
+// 热门商品根据最近订单中的购买数量统计 预热失败不影响启动
+func WarmUp(ctx context.Context, orderService *OrderService, productService *ProductService, opts WarmupOptions) {
+	startTime := time.Now()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	// 1. 预热最近订单
+	orders, err := orderService.WarmUp(ctx, opts.Orders)
+	if err != nil {
+		util.GlobalLogger.Warn(ctx, "订单缓存预热未完成",
+			util.Field{Key: "warmed", Value: len(orders)},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+
+	// 2. 预热热门商品
+	productCount, err := productService.WarmUp(ctx, hotProductIDs(orders), opts.Products)
+	if err != nil {
+		util.GlobalLogger.Warn(ctx, "商品缓存预热未完成",
+			util.Field{Key: "warmed", Value: productCount},
+			util.Field{Key: "error", Value: err.Error()},
+		)
+	}
+
+	util.GlobalLogger.Info(ctx, "缓存预热完成",
+		util.Field{Key: "orders", Value: len(orders)},
+		util.Field{Key: "products", Value: productCount},
+		util.Field{Key: "duration_ms", Value: time.Since(startTime).Milliseconds()},
+	)
+}
+
+// ProductWarmUpSource 商品预热的数据来源
+type ProductWarmUpSource struct {
+	ActiveIDs func(ctx context.Context, limit int) ([]int, error)           // 上架中的商品ID 用于补齐
+	Load      func(ctx context.Context, ids []int) ([]model.Product, error) // 批量加载商品详情（包含SKU）
+	Store     func(ctx context.Context, product *model.Product) error       // 写入本地缓存和Redis缓存
+}
+
+// WarmUpProducts 按热度顺序预热商品 返回预热的商品数量
+// hotIDs去重后最多取limit个 查不到的跳过 不足limit时用上架中的商品补齐
+func WarmUpProducts(ctx context.Context, hotIDs []int, limit int, source ProductWarmUpSource) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	hotIDs = uniqueIDs(hotIDs)
+	if len(hotIDs) > limit {
+		hotIDs = hotIDs[:limit]
+	}
+
+	// 1. 加载热门商品 按热度排序
+	products, err := loadProductsInOrder(ctx, hotIDs, source.Load)
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. 不足limit时用上架中的商品补齐
+	if len(products) < limit {
+		activeIDs, err := source.ActiveIDs(ctx, limit)
+		if err != nil {
+			return 0, err
+		}
+		seen := make(map[int]bool, len(products))
+		for _, p := range products {
+			seen[p.ID] = true
+		}
+		fillIDs := make([]int, 0, limit-len(products))
+		for _, id := range activeIDs {
+			if len(products)+len(fillIDs) >= limit {
+				break
+			}
+			if !seen[id] {
+				seen[id] = true
+				fillIDs = append(fillIDs, id)
+			}
+		}
+		more, err := loadProductsInOrder(ctx, fillIDs, source.Load)
+		if err != nil {
+			return 0, err
+		}
+		products = append(products, more...)
+	}
+
+	// 3. 按顺序写入缓存 超出时间预算时越热门的商品越先写入
+	return WarmUpEach(ctx, products, source.Store)
+}
+
+// loadProductsInOrder 批量加载商品 按ids的顺序返回 查不到的跳过
+func loadProductsInOrder(ctx context.Context, ids []int, load func(ctx context.Context, ids []int) ([]model.Product, error)) ([]model.Product, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	products, err := load(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]model.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	ordered := make([]model.Product, 0, len(products))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered, nil
+}
+
+// WarmUpEach 按顺序逐个写入缓存 返回写入成功的数量
+// 超出时间预算或写入失败时停止 已写入的部分保留
+func WarmUpEach[T any](ctx context.Context, items []T, store func(ctx context.Context, item *T) error) (int, error) {
+	for i := range items {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		if err := store(ctx, &items[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
+
+// hotProductIDs 统计订单中各商品的购买数量 按数量从高到低返回商品ID
+func hotProductIDs(orders []model.Order) []int {
+	sales := make(map[int]int)
+	for _, order := range orders {
+		var items []model.OrderItem
+		if err := json.Unmarshal([]byte(order.Items), &items); err != nil {
+			continue
+		}
+		for _, item := range items {
+			sales[item.ProductID] += item.Quantity
+		}
+	}
+
+	ids := make([]int, 0, len(sales))
+	for id := range sales {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if sales[ids[i]] != sales[ids[j]] {
+			return sales[ids[i]] > sales[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
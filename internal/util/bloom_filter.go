@@ -0,0 +1,107 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 布隆过滤器默认参数
+// 按100万条数据、1%误判率估算 需要约960万位和7个哈希函数 这里取2^24位（2MB）
+const (
+	DefaultBloomBits   = 1 << 24
+	DefaultBloomHashes = 7
+)
+
+// BloomFilter 基于Redis bitmap的布隆过滤器
+// 用于拦截不存在的ID（缓存穿透） 判断为不存在时一定不存在 判断为存在时可能误判
+type BloomFilter struct {
+	client *redis.Client
+	key    string
+	bits   uint64
+	hashes int
+	ready  atomic.Bool // 从数据库重建完成前 不拦截任何请求
+}
+
+// NewBloomFilter 创建布隆过滤器
+func NewBloomFilter(client *redis.Client, key string, bits uint64, hashes int) *BloomFilter {
+	return &BloomFilter{
+		client: client,
+		key:    key,
+		bits:   bits,
+		hashes: hashes,
+	}
+}
+
+// offsets 计算元素对应的位偏移 使用双重哈希 h1 + i*h2 模拟k个哈希函数
+func (b *BloomFilter) offsets(item string) []int64 {
+	h := fnv.New64a()
+	h.Write([]byte(item))
+	h1 := h.Sum64()
+	h2 := (h1 >> 33) | (h1 << 31) | 1 // 保证为奇数 避免和bits有公因子时分布退化
+
+	offsets := make([]int64, b.hashes)
+	for i := 0; i < b.hashes; i++ {
+		offsets[i] = int64((h1 + uint64(i)*h2) % b.bits)
+	}
+	return offsets
+}
+
+// Add 添加元素 使用pipeline批量SETBIT
+func (b *BloomFilter) Add(ctx context.Context, items ...string) error {
+	if b == nil || b.client == nil || len(items) == 0 {
+		return nil
+	}
+
+	pipe := b.client.Pipeline()
+	for _, item := range items {
+		for _, offset := range b.offsets(item) {
+			pipe.SetBit(ctx, b.key, offset, 1)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("布隆过滤器写入失败: %w", err)
+	}
+	return nil
+}
+
+// MightContain 判断元素是否可能存在
+// 未重建完成或redis出错时返回true（放行） 宁可多查一次数据库也不能误拦截真实数据
+func (b *BloomFilter) MightContain(ctx context.Context, item string) bool {
+	if b == nil || b.client == nil || !b.ready.Load() {
+		return true
+	}
+
+	pipe := b.client.Pipeline()
+	offsets := b.offsets(item)
+	cmds := make([]*redis.IntCmd, len(offsets))
+	for i, offset := range offsets {
+		cmds[i] = pipe.GetBit(ctx, b.key, offset)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		GlobalLogger.Warn(ctx, "布隆过滤器查询失败，放行请求",
+			Field{Key: "key", Value: b.key},
+			Field{Key: "error", Value: err.Error()},
+		)
+		return true
+	}
+
+	for _, cmd := range cmds {
+		if cmd.Val() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MarkReady 标记重建完成 开始拦截不存在的元素
+// 重建采用在原key上补写的方式而不是新建后替换 这样重建期间新增的元素不会丢失
+// 代价是已删除元素的位不会被清除 只会增加误判率
+func (b *BloomFilter) MarkReady() {
+	if b != nil {
+		b.ready.Store(true)
+	}
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheInvalidationChannel 缓存失效广播频道
+const CacheInvalidationChannel = "cache:invalidate"
+
+// 缓存实体类型
+const (
+	CacheEntityOrder   = "order"
+	CacheEntityProduct = "product"
+)
+
+// InvalidationMessage 缓存失效消息
+type InvalidationMessage struct {
+	Entity string `json:"entity"` // order / product
+	ID     string `json:"id"`     // 为空表示清空该实体的全部本地缓存
+	Source string `json:"source"` // 发布消息的实例标识 便于排查
+}
+
+// InvalidationHandler 收到失效消息后的处理函数 id为空表示清空全部
+type InvalidationHandler func(id string)
+
+// CacheInvalidator 基于Redis pub/sub的跨实例本地缓存失效
+// 多副本部署时 某个实例修改了订单/商品 其他实例本地缓存里的数据就过期了
+// 修改方发布失效消息 所有实例（包括自己）订阅后删除对应的本地缓存
+type CacheInvalidator struct {
+	client     *redis.Client
+	instanceID string
+	subscribed atomic.Bool // 是否已经订阅成功过 再次订阅成功说明是重连
+
+	mu       sync.RWMutex
+	handlers map[string][]InvalidationHandler
+}
+
+// NewCacheInvalidator 创建缓存失效广播实例
+func NewCacheInvalidator(client *redis.Client) *CacheInvalidator {
+	hostname, _ := os.Hostname()
+	return &CacheInvalidator{
+		client:     client,
+		instanceID: fmt.Sprintf("%s_%d", hostname, os.Getpid()),
+		handlers:   make(map[string][]InvalidationHandler),
+	}
+}
+
+// Register 注册某个实体的失效处理函数
+func (c *CacheInvalidator) Register(entity string, handler InvalidationHandler) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[entity] = append(c.handlers[entity], handler)
+}
+
+// Publish 发布缓存失效消息
+// 允许为nil 方便单实例或测试时不启用广播
+func (c *CacheInvalidator) Publish(ctx context.Context, entity, id string) error {
+	if c == nil || c.client == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(InvalidationMessage{Entity: entity, ID: id, Source: c.instanceID})
+	if err != nil {
+		return err
+	}
+	if err := c.client.Publish(ctx, CacheInvalidationChannel, payload).Err(); err != nil {
+		return fmt.Errorf("发布缓存失效消息失败: %w", err)
+	}
+	return nil
+}
+
+// Start 订阅缓存失效频道 阻塞直到ctx取消
+// 连接断开后使用指数退避重连 重连成功后清空全部本地缓存 因为断线期间的失效消息已经丢失
+func (c *CacheInvalidator) Start(ctx context.Context) {
+	const (
+		baseDelay = 100 * time.Millisecond
+		maxDelay  = 10 * time.Second
+	)
+
+	delay := baseDelay
+	for {
+		err := c.subscribe(ctx, func() {
+			c.MarkSubscribed(ctx)
+			delay = baseDelay
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		GlobalLogger.Error(ctx, "缓存失效订阅中断", err,
+			Field{Key: "retry_in_ms", Value: delay.Milliseconds()},
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// subscribe 建立一次订阅并持续处理消息 直到出错
+func (c *CacheInvalidator) subscribe(ctx context.Context, onSubscribed func()) error {
+	pubsub := c.client.Subscribe(ctx, CacheInvalidationChannel)
+	defer pubsub.Close()
+
+	// 等待订阅确认 确保连接可用
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	onSubscribed()
+
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := c.HandleMessage(msg.Payload); err != nil {
+			GlobalLogger.Warn(ctx, "缓存失效消息格式错误",
+				Field{Key: "payload", Value: msg.Payload},
+			)
+		}
+	}
+}
+
+// MarkSubscribed 订阅成功后调用 重连成功时清空全部本地缓存 因为断线期间的失效消息已经丢失
+func (c *CacheInvalidator) MarkSubscribed(ctx context.Context) {
+	if c.subscribed.Swap(true) {
+		GlobalLogger.Info(ctx, "缓存失效订阅已重连，清空本地缓存")
+		c.DispatchAll()
+	}
+}
+
+// HandleMessage 处理一条失效消息 调用实体对应的处理函数
+func (c *CacheInvalidator) HandleMessage(payload string) error {
+	var m InvalidationMessage
+	if err := json.Unmarshal([]byte(payload), &m); err != nil {
+		return err
+	}
+	c.dispatch(m.Entity, m.ID)
+	return nil
+}
+
+// dispatch 调用实体对应的处理函数
+func (c *CacheInvalidator) dispatch(entity, id string) {
+	c.mu.RLock()
+	handlers := c.handlers[entity]
+	c.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(id)
+	}
+}
+
+// DispatchAll 清空所有实体的本地缓存
+func (c *CacheInvalidator) DispatchAll() {
+	c.mu.RLock()
+	entities := make([]string, 0, len(c.handlers))
+	for entity := range c.handlers {
+		entities = append(entities, entity)
+	}
+	c.mu.RUnlock()
+
+	for _, entity := range entities {
+		c.dispatch(entity, "")
+	}
+}
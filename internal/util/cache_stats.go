@@ -0,0 +1,31 @@
+package util
+
+import "sync/atomic"
+
+// CacheCounter 缓存命中计数器 用于没有内置统计的缓存层（如Redis）
+type CacheCounter struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Hit 记录一次命中
+func (c *CacheCounter) Hit() {
+	c.hits.Add(1)
+}
+
+// Miss 记录一次未命中
+func (c *CacheCounter) Miss() {
+	c.misses.Add(1)
+}
+
+// Stats 获取统计信息 Size由调用方按需填充
+func (c *CacheCounter) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
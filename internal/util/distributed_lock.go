@@ -14,6 +14,7 @@ type DistributedLock struct {
 	key        string
 	value      string
 	expiration time.Duration
+	acquiredAt time.Time // 加锁成功的时间 用于统计持有时长
 }
 
 // LockKeyGenerator 锁key生成器
@@ -52,6 +53,20 @@ func (g *LockKeyGenerator) GenerateProductLockKey(productID int) string {
 	return fmt.Sprintf("lock:product:%d", productID)
 }
 
+// GenerateCacheLockKey 生成缓存加载互斥锁的key
+// 格式: lock:cache:{cache_key}
+// 示例: lock:cache:product:123
+func (g *LockKeyGenerator) GenerateCacheLockKey(cacheKey string) string {
+	return fmt.Sprintf("lock:cache:%s", cacheKey)
+}
+
+// GenerateJobLockKey 生成后台任务锁的key 保证多实例部署时同一任务只有一个实例执行
+// 格式: lock:job:{job_name}
+// 示例: lock:job:recommend_rebuild
+func (g *LockKeyGenerator) GenerateJobLockKey(job string) string {
+	return fmt.Sprintf("lock:job:%s", job)
+}
+
 // NewDistributedLock 创建分布式锁实例
 func NewDistributedLock(client *redis.Client, key string, expiration time.Duration) *DistributedLock {
 	return &DistributedLock{
@@ -77,15 +92,30 @@ func (dl *DistributedLock) TryLock(ctx context.Context) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("获取分布式锁失败: %w", err)
 	}
+	if result {
+		dl.acquiredAt = time.Now()
+	}
 	return result, nil
 }
 
 // TryLockWithRetry 带重试的锁获取（优化版，使用指数退避）
-func (dl *DistributedLock) TryLockWithRetry(ctx context.Context, maxRetries int, baseDelay time.Duration) (bool, error) {
+func (dl *DistributedLock) TryLockWithRetry(ctx context.Context, maxRetries int, baseDelay time.Duration) (locked bool, err error) {
 	maxDelay := 500 * time.Millisecond // 最大重试间隔
 
+	// 记录获取耗时、重试次数和最终结果
+	start := time.Now()
+	attempts := 0
+	defer func() {
+		retries := attempts - 1
+		if retries < 0 {
+			retries = 0
+		}
+		GlobalLockMetrics.ObserveAcquire(dl.key, locked, retries, time.Since(start))
+	}()
+
 	for i := 0; i < maxRetries; i++ {
-		locked, err := dl.TryLock(ctx)
+		attempts++
+		locked, err = dl.TryLock(ctx)
 		if err != nil {
 			return false, err
 		}
@@ -139,9 +169,14 @@ func (dl *DistributedLock) Unlock(ctx context.Context) error {
 
 	// 检查删除结果
 	if result.(int64) == 0 {
+		// 锁在业务执行期间已过期或被他人持有
+		GlobalLockMetrics.ObserveLost(dl.key)
 		return fmt.Errorf("锁不存在或已被其他进程持有")
 	}
 
+	if !dl.acquiredAt.IsZero() {
+		GlobalLockMetrics.ObserveRelease(dl.key, time.Since(dl.acquiredAt))
+	}
 	return nil
 }
 
@@ -161,6 +196,7 @@ func (dl *DistributedLock) ExtendLock(ctx context.Context, newExpiration time.Du
 	}
 
 	if result.(int64) == 0 {
+		GlobalLockMetrics.ObserveLost(dl.key)
 		return fmt.Errorf("锁不存在或已被其他进程持有")
 	}
 
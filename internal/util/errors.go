@@ -13,6 +13,8 @@ var (
 	ErrOrderCreateFailed = errors.New("订单创建失败")
 	ErrDatabaseError     = errors.New("数据库操作失败")
 	ErrTimeout           = errors.New("操作超时")
+	ErrUnauthorized      = errors.New("未登录或登录凭证无效")
+	ErrForbidden         = errors.New("没有权限")
 )
 
 // BusinessError 业务错误
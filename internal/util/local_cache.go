@@ -0,0 +1,158 @@
+package util
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LocalCache 进程内本地缓存
+// 支持最大条目数、按条目过期（TTL）和LRU淘汰 并统计命中/未命中次数
+// sync.Map没有容量限制也没有过期机制 读过的数据会一直留在内存里 这里用 map + 双向链表 实现
+type LocalCache[K comparable, V any] struct {
+	mu         sync.Mutex
+	maxEntries int           // 最大条目数 <=0 表示不限制
+	ttl        time.Duration // 默认过期时间 <=0 表示不过期
+	ll         *list.List    // 链表头部为最近使用
+	items      map[K]*list.Element
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// cacheEntry 缓存条目
+type cacheEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time // 零值表示不过期
+}
+
+// CacheStats 本地缓存统计信息
+type CacheStats struct {
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+	Evictions  int64   `json:"evictions"` // LRU淘汰和过期清理的次数
+	Size       int     `json:"size"`
+	MaxEntries int     `json:"max_entries"`
+}
+
+// NewLocalCache 创建本地缓存实例
+func NewLocalCache[K comparable, V any](maxEntries int, ttl time.Duration) *LocalCache[K, V] {
+	return &LocalCache[K, V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		items:      make(map[K]*list.Element),
+	}
+}
+
+// Get 查询缓存 命中时将条目移动到链表头部 过期条目会被顺带删除
+func (c *LocalCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return zero, false
+	}
+
+	entry := elem.Value.(*cacheEntry[K, V])
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		c.evictions++
+		c.misses++
+		return zero, false
+	}
+
+	c.ll.MoveToFront(elem)
+	c.hits++
+	return entry.value, true
+}
+
+// Set 写入缓存 使用默认过期时间
+func (c *LocalCache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL 写入缓存并指定过期时间 超出容量时淘汰最久未使用的条目
+func (c *LocalCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cacheEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	elem := c.ll.PushFront(&cacheEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	c.items[key] = elem
+
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		if oldest := c.ll.Back(); oldest != nil {
+			c.removeElement(oldest)
+			c.evictions++
+		}
+	}
+}
+
+// Delete 删除缓存条目
+func (c *LocalCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// Purge 清空缓存
+func (c *LocalCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[K]*list.Element)
+}
+
+// Len 当前缓存条目数（包含尚未被清理的过期条目）
+func (c *LocalCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Stats 获取缓存统计信息
+func (c *LocalCache[K, V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+		Size:       c.ll.Len(),
+		MaxEntries: c.maxEntries,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// removeElement 从链表和map中移除条目（调用方需持有锁）
+func (c *LocalCache[K, V]) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*cacheEntry[K, V]).key)
+}
@@ -0,0 +1,196 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LockMetrics 分布式锁指标统计
+// 按锁类型聚合（lock:inventory:123 -> inventory），用于观察锁竞争情况
+type LockMetrics struct {
+	stats sync.Map // map[string]*lockStats
+}
+
+// lockStats 单个锁类型的计数器 全部使用atomic 避免在加锁路径上再引入互斥锁
+type lockStats struct {
+	acquired        int64 // 成功获取次数
+	failed          int64 // 获取失败次数（重试耗尽、redis错误、上下文取消）
+	retries         int64 // 重试次数（第一次之后的每次尝试）
+	acquireNanos    int64 // 累计获取耗时
+	maxAcquireNanos int64 // 最大获取耗时
+	released        int64 // 释放次数
+	holdNanos       int64 // 累计持有时间
+	maxHoldNanos    int64 // 最大持有时间
+	lost            int64 // 锁丢失次数（释放/续期时发现锁已过期或被他人持有）
+}
+
+// LockStatsSnapshot 锁指标快照
+type LockStatsSnapshot struct {
+	Type            string  `json:"type"`
+	Acquired        int64   `json:"acquired"`
+	Failed          int64   `json:"failed"`
+	Retries         int64   `json:"retries"`
+	AvgAcquireMs    float64 `json:"avg_acquire_ms"`
+	MaxAcquireMs    float64 `json:"max_acquire_ms"`
+	Released        int64   `json:"released"`
+	AvgHoldMs       float64 `json:"avg_hold_ms"`
+	MaxHoldMs       float64 `json:"max_hold_ms"`
+	Lost            int64   `json:"lost"`
+	ContentionRatio float64 `json:"contention_ratio"` // 每次获取平均重试次数
+}
+
+// NewLockMetrics 创建锁指标实例
+func NewLockMetrics() *LockMetrics {
+	return &LockMetrics{}
+}
+
+// 全局锁指标实例
+var GlobalLockMetrics = NewLockMetrics()
+
+// lockType 从锁key中解析锁类型
+// 示例: lock:inventory:123 -> inventory
+func lockType(key string) string {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) >= 2 && parts[0] == "lock" {
+		return parts[1]
+	}
+	return key
+}
+
+// get 获取（或创建）指定key对应类型的计数器
+func (m *LockMetrics) get(key string) *lockStats {
+	t := lockType(key)
+	if v, ok := m.stats.Load(t); ok {
+		return v.(*lockStats)
+	}
+	v, _ := m.stats.LoadOrStore(t, &lockStats{})
+	return v.(*lockStats)
+}
+
+// ObserveAcquire 记录一次加锁结果
+func (m *LockMetrics) ObserveAcquire(key string, acquired bool, retries int, latency time.Duration) {
+	s := m.get(key)
+	if acquired {
+		atomic.AddInt64(&s.acquired, 1)
+	} else {
+		atomic.AddInt64(&s.failed, 1)
+	}
+	atomic.AddInt64(&s.retries, int64(retries))
+	atomic.AddInt64(&s.acquireNanos, int64(latency))
+	storeMax(&s.maxAcquireNanos, int64(latency))
+}
+
+// ObserveRelease 记录一次释放锁及持有时间
+func (m *LockMetrics) ObserveRelease(key string, hold time.Duration) {
+	s := m.get(key)
+	atomic.AddInt64(&s.released, 1)
+	atomic.AddInt64(&s.holdNanos, int64(hold))
+	storeMax(&s.maxHoldNanos, int64(hold))
+}
+
+// ObserveLost 记录一次锁丢失
+func (m *LockMetrics) ObserveLost(key string) {
+	atomic.AddInt64(&m.get(key).lost, 1)
+}
+
+// Snapshot 获取所有锁类型的指标快照（按类型名排序）
+func (m *LockMetrics) Snapshot() []LockStatsSnapshot {
+	var result []LockStatsSnapshot
+	m.stats.Range(func(k, v interface{}) bool {
+		s := v.(*lockStats)
+		acquired := atomic.LoadInt64(&s.acquired)
+		failed := atomic.LoadInt64(&s.failed)
+		released := atomic.LoadInt64(&s.released)
+		retries := atomic.LoadInt64(&s.retries)
+
+		snap := LockStatsSnapshot{
+			Type:         k.(string),
+			Acquired:     acquired,
+			Failed:       failed,
+			Retries:      retries,
+			MaxAcquireMs: nanosToMs(atomic.LoadInt64(&s.maxAcquireNanos)),
+			Released:     released,
+			MaxHoldMs:    nanosToMs(atomic.LoadInt64(&s.maxHoldNanos)),
+			Lost:         atomic.LoadInt64(&s.lost),
+		}
+		if attempts := acquired + failed; attempts > 0 {
+			snap.AvgAcquireMs = nanosToMs(atomic.LoadInt64(&s.acquireNanos) / attempts)
+			snap.ContentionRatio = float64(retries) / float64(attempts)
+		}
+		if released > 0 {
+			snap.AvgHoldMs = nanosToMs(atomic.LoadInt64(&s.holdNanos) / released)
+		}
+		result = append(result, snap)
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// storeMax CAS方式更新最大值
+func storeMax(addr *int64, val int64) {
+	for {
+		old := atomic.LoadInt64(addr)
+		if val <= old || atomic.CompareAndSwapInt64(addr, old, val) {
+			return
+		}
+	}
+}
+
+func nanosToMs(n int64) float64 {
+	return float64(n) / float64(time.Millisecond)
+}
+
+// LockInfo 当前持有中的锁信息
+type LockInfo struct {
+	Key   string `json:"key"`
+	Owner string `json:"owner"`  // 锁的持有者标识（加锁时写入的value）
+	TTLMs int64  `json:"ttl_ms"` // 剩余过期时间 -1表示未设置过期
+}
+
+// ListLocks 扫描redis中匹配pattern的锁 返回key、持有者和剩余TTL
+// 使用SCAN而不是KEYS 避免阻塞redis
+func ListLocks(ctx context.Context, client *redis.Client, pattern string, limit int) ([]LockInfo, error) {
+	locks := make([]LockInfo, 0)
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		owner, err := client.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue // 扫描期间已释放
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取锁持有者失败: %w", err)
+		}
+
+		lock := &DistributedLock{client: client, key: key}
+		ttl, err := lock.GetLockTTL(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ttl == -2 {
+			continue // key不存在
+		}
+
+		ttlMs := int64(-1)
+		if ttl >= 0 {
+			ttlMs = ttl.Milliseconds()
+		}
+		locks = append(locks, LockInfo{Key: key, Owner: owner, TTLMs: ttlMs})
+
+		if limit > 0 && len(locks) >= limit {
+			break
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("扫描锁失败: %w", err)
+	}
+	return locks, nil
+}
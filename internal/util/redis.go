@@ -3,6 +3,8 @@ package util
 
 import (
 	"context"
+	"math/rand"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -32,35 +34,95 @@ func PingRedis(ctx context.Context) error {
 	return err
 }
 
+// OrderCacheKey 生成订单缓存key
+// 格式: order:{order_id}
+func OrderCacheKey(orderID string) string {
+	return "order:" + orderID
+}
+
 // GetOrderCache 从Redis查询订单缓存
 // context 上下文 用于传递请求的上下文信息
 func GetOrderCache(ctx context.Context, orderID string) (string, error) {
-	return RedisClient.Get(ctx, "order:"+orderID).Result()
+	return RedisClient.Get(ctx, OrderCacheKey(orderID)).Result()
 }
 
 // SetOrderCache 设置订单缓存
 func SetOrderCache(ctx context.Context, orderID string, data string, expiration time.Duration) error {
-	return RedisClient.Set(ctx, "order:"+orderID, data, expiration).Err()
+	return RedisClient.Set(ctx, OrderCacheKey(orderID), data, expiration).Err()
 }
 
 // DelOrderCache 删除订单缓存
 func DelOrderCache(ctx context.Context, orderID string) error {
-	return RedisClient.Del(ctx, "order:"+orderID).Err()
+	return RedisClient.Del(ctx, OrderCacheKey(orderID)).Err()
+}
+
+// ProductCacheKey 生成商品缓存key
+// 格式: product:{product_id}  示例: product:123
+func ProductCacheKey(productID int) string {
+	return "product:" + strconv.Itoa(productID)
 }
 
 // GetProductCache 从Redis查询商品缓存
 func GetProductCache(ctx context.Context, productID int) (string, error) {
-	return RedisClient.Get(ctx, "product:"+string(rune(productID))).Result()
+	return RedisClient.Get(ctx, ProductCacheKey(productID)).Result()
 }
 
 // SetProductCache 设置商品缓存
 func SetProductCache(ctx context.Context, productID int, data string, expiration time.Duration) error {
-	return RedisClient.Set(ctx, "product:"+string(rune(productID)), data, expiration).Err()
+	return RedisClient.Set(ctx, ProductCacheKey(productID), data, expiration).Err()
 }
 
 // DelProductCache 删除商品缓存
 func DelProductCache(ctx context.Context, productID int) error {
-	return RedisClient.Del(ctx, "product:"+string(rune(productID))).Err()
+	return RedisClient.Del(ctx, ProductCacheKey(productID)).Err()
+}
+
+// TTLWithJitter 在基础过期时间上增加随机抖动
+// 避免同一批写入的缓存在同一时刻集中过期（缓存雪崩）
+func TTLWithJitter(base, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return base
+	}
+	return base + time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// ScanCount 统计匹配pattern的key数量（使用SCAN 不阻塞redis）
+func ScanCount(ctx context.Context, client *redis.Client, pattern string) (int, error) {
+	count := 0
+	iter := client.Scan(ctx, 0, pattern, 500).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	return count, iter.Err()
+}
+
+// ScanDelete 删除匹配pattern的所有key 每批最多500个 返回删除数量
+func ScanDelete(ctx context.Context, client *redis.Client, pattern string) (int64, error) {
+	var deleted int64
+	batch := make([]string, 0, 500)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := client.Del(ctx, batch...).Result()
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+
+	iter := client.Scan(ctx, 0, pattern, 500).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
 }
 
 // CloseRedis 关闭Redis连接
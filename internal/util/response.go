@@ -1,6 +1,7 @@
 package util
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -20,6 +21,10 @@ const (
 	CodeError    = 500 // 服务器错误
 	CodeInvalid  = 400 // 参数错误
 	CodeNotFound = 404 // 资源不存在
+	CodeConflict = 409 // 数据冲突（如乐观锁版本不一致）
+
+	CodeUnauthorized = 401 // 未登录或登录凭证无效
+	CodeForbidden    = 403 // 没有权限
 )
 
 // ResponseHelper 响应助手，提供统一的响应方法
@@ -63,6 +68,46 @@ func (h *ResponseHelper) NotFound(c *gin.Context, message string) {
 	h.Error(c, CodeNotFound, message)
 }
 
+// Unauthorized 未登录或登录凭证无效响应
+func (h *ResponseHelper) Unauthorized(c *gin.Context, message string) {
+	h.Error(c, CodeUnauthorized, message)
+}
+
+// Forbidden 没有权限响应
+func (h *ResponseHelper) Forbidden(c *gin.Context, message string) {
+	h.Error(c, CodeForbidden, message)
+}
+
+// Conflict 数据冲突响应
+func (h *ResponseHelper) Conflict(c *gin.Context, message string) {
+	h.Error(c, CodeConflict, message)
+}
+
+// BusinessError 根据业务错误码返回对应的响应
+func (h *ResponseHelper) BusinessError(c *gin.Context, prefix string, err error) {
+	businessErr := GetBusinessError(err)
+	if businessErr == nil {
+		h.ServerError(c, prefix+": "+err.Error())
+		return
+	}
+
+	message := prefix + ": " + businessErr.Message
+	switch {
+	case errors.Is(err, ErrNotFound):
+		h.NotFound(c, message)
+	case errors.Is(err, ErrInvalidInput):
+		h.InvalidParams(c, message)
+	case errors.Is(err, ErrUnauthorized):
+		h.Unauthorized(c, message)
+	case errors.Is(err, ErrForbidden):
+		h.Forbidden(c, message)
+	case businessErr.Code == "VERSION_CONFLICT":
+		h.Conflict(c, message)
+	default:
+		h.ServerError(c, message)
+	}
+}
+
 // 全局响应助手实例
 var ResponseUtil = NewResponseHelper()
 
@@ -0,0 +1,272 @@
+package util
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// SearchIndex 进程内倒排索引 使用BM25计算相关度
+// 中文没有空格分词 索引时对连续的汉字同时切分单字和二元组（bigram） 英文和数字按单词切分并转小写
+// 查询时连续汉字只用二元组匹配 单个汉字才用单字匹配 不依赖词典
+type SearchIndex struct {
+	mu       sync.RWMutex
+	weights  map[string]float64         // 字段权重 如名称命中比描述命中更重要
+	postings map[string]map[int]float64 // term -> 文档ID -> 加权词频
+	docTerms map[int][]string           // 文档包含的term 删除文档时使用
+	docLen   map[int]float64            // 文档加权长度
+	totalLen float64
+}
+
+// SearchHit 搜索结果
+type SearchHit struct {
+	ID    int     `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// NewSearchIndex 创建倒排索引 weights为字段权重 未配置的字段权重为1
+func NewSearchIndex(weights map[string]float64) *SearchIndex {
+	return &SearchIndex{
+		weights:  weights,
+		postings: make(map[string]map[int]float64),
+		docTerms: make(map[int][]string),
+		docLen:   make(map[int]float64),
+	}
+}
+
+// Upsert 新增或更新文档
+func (idx *SearchIndex) Upsert(id int, fields map[string]string) {
+	// 分词在锁外完成
+	tf := make(map[string]float64)
+	length := 0.0
+	for field, text := range fields {
+		weight, ok := idx.weights[field]
+		if !ok {
+			weight = 1
+		}
+		for _, term := range Tokenize(text) {
+			tf[term] += weight
+			length += weight
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(id)
+	if len(tf) == 0 {
+		return
+	}
+	terms := make([]string, 0, len(tf))
+	for term, freq := range tf {
+		docs, ok := idx.postings[term]
+		if !ok {
+			docs = make(map[int]float64)
+			idx.postings[term] = docs
+		}
+		docs[id] = freq
+		terms = append(terms, term)
+	}
+	idx.docTerms[id] = terms
+	idx.docLen[id] = length
+	idx.totalLen += length
+}
+
+// Remove 删除文档
+func (idx *SearchIndex) Remove(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+// removeLocked 删除文档（调用方需持有写锁）
+func (idx *SearchIndex) removeLocked(id int) {
+	for _, term := range idx.docTerms[id] {
+		docs := idx.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLen -= idx.docLen[id]
+	delete(idx.docTerms, id)
+	delete(idx.docLen, id)
+}
+
+// Len 索引中的文档数量
+func (idx *SearchIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docLen)
+}
+
+// Search 按相关度从高到低返回匹配的文档
+func (idx *SearchIndex) Search(query string) []SearchHit {
+	terms := uniqueStrings(TokenizeQuery(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.docLen))
+	if n == 0 {
+		return nil
+	}
+	avgLen := idx.totalLen / n
+
+	scores := make(map[int]float64)
+	for _, term := range terms {
+		docs := idx.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range docs {
+			norm := tf + bm25K1*(1-bm25B+bm25B*idx.docLen[id]/avgLen)
+			scores[id] += idf * tf * (bm25K1 + 1) / norm
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, SearchHit{ID: id, Score: math.Round(score*1000) / 1000})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// Tokenize 索引分词 汉字输出单字和二元组 英文数字按单词输出（小写）
+// 示例: "iPhone 15 苹果手机" -> iphone, 15, 苹, 果, 手, 机, 苹果, 果手, 手机
+func Tokenize(text string) []string {
+	return tokenize(text, false)
+}
+
+// TokenizeQuery 查询分词 连续多个汉字只输出二元组 单个汉字才输出单字
+// 避免查询"手机"时因为单字"机"命中"耳机"
+func TokenizeQuery(text string) []string {
+	return tokenize(text, true)
+}
+
+// tokenize 分词实现 query为true时按查询规则切分汉字
+func tokenize(text string, query bool) []string {
+	var tokens []string
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushHan := func() {
+		if !query || len(han) == 1 {
+			for i := range han {
+				tokens = append(tokens, string(han[i]))
+			}
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens = append(tokens, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// Highlight 将文本中命中查询词的部分用<em></em>包裹 英文不区分大小写
+// 返回值是HTML片段 原文中的每一段都先转义 商品名称和描述中的标签不会被当作HTML执行
+func Highlight(text, query string) string {
+	patterns := highlightPatterns(query)
+	if len(patterns) == 0 || text == "" {
+		return html.EscapeString(text)
+	}
+
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = runes // 个别字符转小写后长度变化 退化为区分大小写匹配
+	}
+
+	marked := make([]bool, len(runes))
+	for _, p := range patterns {
+		for i := 0; i+len(p) <= len(lower); i++ {
+			if string(lower[i:i+len(p)]) == string(p) {
+				for j := i; j < i+len(p); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	// 按是否命中把文本切成若干段 每段转义后再写入 命中的段用<em>包裹
+	var sb strings.Builder
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && marked[i] == marked[start] {
+			continue
+		}
+		segment := html.EscapeString(string(runes[start:i]))
+		if marked[start] {
+			sb.WriteString("<em>" + segment + "</em>")
+		} else {
+			sb.WriteString(segment)
+		}
+		start = i
+	}
+	return sb.String()
+}
+
+// highlightPatterns 生成高亮匹配模式 与查询分词规则一致
+func highlightPatterns(query string) [][]rune {
+	var patterns [][]rune
+	for _, token := range TokenizeQuery(query) {
+		patterns = append(patterns, []rune(token))
+	}
+	return patterns
+}
+
+// uniqueStrings 去重并保持顺序
+func uniqueStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
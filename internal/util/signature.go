@@ -0,0 +1,42 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrSignatureInvalid = errors.New("签名无效")
+	ErrSignatureExpired = errors.New("签名已过期")
+)
+
+// SignPayload 对回调内容签名 签名内容为 "{timestamp}.{body}" 结果为十六进制的HMAC-SHA256
+// 把时间戳纳入签名 防止截获的回调在很久之后被重放
+func SignPayload(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPayload 校验回调签名 timestamp为Unix秒 与now相差超过tolerance时视为过期
+func VerifyPayload(secret []byte, timestamp string, body []byte, signature string, tolerance time.Duration, now time.Time) error {
+	expected := SignPayload(secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrSignatureInvalid
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	diff := now.Sub(time.Unix(seconds, 0))
+	if diff > tolerance || diff < -tolerance {
+		return ErrSignatureExpired
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SingleFlight 合并同一个key的并发调用 只有第一个调用者真正执行加载函数
+// 其余调用者等待并共享结果 用于防止热点缓存过期时大量请求同时打到数据库（缓存击穿）
+type SingleFlight[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[V]
+}
+
+// flightCall 一次正在进行中的加载
+type flightCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// Do 执行加载函数 shared表示结果是否与其他调用者共享
+// 加载函数在独立的goroutine中执行 调用者的ctx取消只会让自己提前返回 不会影响其他等待者
+func (g *SingleFlight[V]) Do(ctx context.Context, key string, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[V])
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall[V]{done: make(chan struct{})}
+		g.calls[key] = c
+		go func() {
+			c.val, c.err = fn()
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err, ok
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err(), ok
+	}
+}
+
+// 跨实例缓存互斥锁客户端 为nil时只做进程内合并
+var cacheMutexClient *redis.Client
+
+// EnableCacheMutex 启用跨实例缓存加载互斥
+// 开启后同一个key在所有实例中同一时刻只有一个去查数据库 其他实例短暂等待redis缓存被回填
+func EnableCacheMutex(client *redis.Client) {
+	cacheMutexClient = client
+}
+
+// 跨实例互斥参数
+const (
+	cacheMutexTTL      = 3 * time.Second       // 互斥锁过期时间 防止加载方宕机后死锁
+	cacheMutexWait     = 50 * time.Millisecond // 未抢到锁时的轮询间隔
+	cacheMutexMaxPolls = 10                    // 最多轮询次数 超过后直接查数据库
+)
+
+// CacheLoader 缓存未命中时的加载器
+// 进程内使用singleflight合并 可选在redis上加短时互斥锁实现跨实例合并
+type CacheLoader[V any] struct {
+	group SingleFlight[V]
+}
+
+// NewCacheLoader 创建缓存加载器
+func NewCacheLoader[V any]() *CacheLoader[V] {
+	return &CacheLoader[V]{}
+}
+
+// Load 加载缓存key对应的数据
+// recheck 用于在等待其他实例加载期间重新检查redis缓存
+// load 查询数据库并负责回填redis缓存
+func (l *CacheLoader[V]) Load(ctx context.Context, cacheKey string, recheck func(ctx context.Context) (V, bool), load func(ctx context.Context) (V, error)) (V, error) {
+	// 加载过程不跟随单个请求取消 避免一个请求断开导致所有等待者失败
+	loadCtx := context.WithoutCancel(ctx)
+
+	v, err, _ := l.group.Do(ctx, cacheKey, func() (V, error) {
+		client := cacheMutexClient
+		if client == nil {
+			return load(loadCtx)
+		}
+		return l.loadWithMutex(loadCtx, client, cacheKey, recheck, load)
+	})
+	return v, err
+}
+
+// loadWithMutex 抢到redis互斥锁的实例负责查数据库 其他实例轮询redis缓存
+func (l *CacheLoader[V]) loadWithMutex(ctx context.Context, client *redis.Client, cacheKey string, recheck func(ctx context.Context) (V, bool), load func(ctx context.Context) (V, error)) (V, error) {
+	lockKey := NewLockKeyGenerator().GenerateCacheLockKey(cacheKey)
+	lock := NewDistributedLock(client, lockKey, cacheMutexTTL)
+
+	locked, err := lock.TryLock(ctx)
+	if err != nil {
+		// redis不可用时退化为直接查数据库
+		GlobalLogger.Warn(ctx, "获取缓存互斥锁失败，直接加载",
+			Field{Key: "key", Value: cacheKey},
+			Field{Key: "error", Value: err.Error()},
+		)
+		return load(ctx)
+	}
+	if locked {
+		defer func() {
+			if err := lock.Unlock(ctx); err != nil {
+				GlobalLogger.Warn(ctx, "释放缓存互斥锁失败",
+					Field{Key: "key", Value: cacheKey},
+					Field{Key: "error", Value: err.Error()},
+				)
+			}
+		}()
+		return load(ctx)
+	}
+
+	// 其他实例正在加载 等待其回填redis缓存
+	for i := 0; i < cacheMutexMaxPolls; i++ {
+		time.Sleep(cacheMutexWait)
+		if v, ok := recheck(ctx); ok {
+			return v, nil
+		}
+	}
+	return load(ctx)
+}
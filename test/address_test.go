@@ -0,0 +1,63 @@
+package test
+
+import (
+	"demo01/internal/model"
+	"testing"
+)
+
+// TestAddressValidate 测试收货地址校验和快照
+func TestAddressValidate(t *testing.T) {
+	address := model.Address{
+		Receiver: " 张三 ",
+		Phone:    "13800138000",
+		Province: "广东省",
+		City:     "深圳市",
+		District: "南山区",
+		Detail:   "科技园1号",
+	}
+	address.Normalize()
+	if err := address.Validate(); err != nil {
+		t.Fatalf("合法地址校验失败: %v", err)
+	}
+	snapshot := address.Snapshot()
+	if snapshot.Receiver != "张三" || snapshot.Detail != "科技园1号" {
+		t.Fatalf("地址快照内容不一致: %+v", snapshot)
+	}
+	// 修改地址簿不影响已生成的快照
+	address.Detail = "其他地址"
+	if snapshot.Detail != "科技园1号" {
+		t.Fatal("快照应该与地址簿相互独立")
+	}
+
+	invalid := []model.Address{
+		{Receiver: "", Phone: "13800138000", Province: "广东省", City: "深圳市", Detail: "1号"},
+		{Receiver: "张三", Phone: "12345", Province: "广东省", City: "深圳市", Detail: "1号"},
+		{Receiver: "张三", Phone: "13800138000", Province: "", City: "深圳市", Detail: "1号"},
+		{Receiver: "张三", Phone: "13800138000", Province: "广东省", City: "深圳市", Detail: ""},
+	}
+	for i, a := range invalid {
+		if err := a.Validate(); err == nil {
+			t.Errorf("第%d个非法地址应该校验失败", i)
+		}
+	}
+}
+
+// TestOrderStatusTransition 测试订单状态流转规则
+func TestOrderStatusTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     bool
+	}{
+		{model.OrderStatusPending, model.OrderStatusPaid, true},
+		{model.OrderStatusPaid, model.OrderStatusShipped, true},
+		{model.OrderStatusShipped, model.OrderStatusCompleted, true},
+		{model.OrderStatusPending, model.OrderStatusShipped, false},
+		{model.OrderStatusShipped, model.OrderStatusCancelled, false},
+		{model.OrderStatusCancelled, model.OrderStatusPaid, false},
+	}
+	for _, tc := range cases {
+		if got := model.CanTransitionOrderStatus(tc.from, tc.to); got != tc.want {
+			t.Errorf("%s -> %s: got %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
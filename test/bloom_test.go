@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"demo01/config"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestBloomFilterPassThrough 测试没有Redis或未重建完成时布隆过滤器一律放行
+func TestBloomFilterPassThrough(t *testing.T) {
+	ctx := context.Background()
+	var nilFilter *util.BloomFilter
+	if !nilFilter.MightContain(ctx, "1") || nilFilter.Add(ctx, "1") != nil {
+		t.Fatal("未初始化的布隆过滤器应该放行")
+	}
+	noRedis := util.NewBloomFilter(nil, "bloom:test", 1<<10, 3)
+	noRedis.MarkReady()
+	if !noRedis.MightContain(ctx, "1") {
+		t.Fatal("没有Redis时应该放行")
+	}
+}
+
+// TestBloomFilter 测试布隆过滤器的添加、查询和重建完成前的放行
+func TestBloomFilter(t *testing.T) {
+	// 加载全局配置并初始化Redis连接
+	cfg := config.Load()
+	util.InitRedis(cfg.RedisAddr, cfg.RedisPwd)
+
+	ctx := context.Background()
+	key := fmt.Sprintf("bloom:test:%d", time.Now().UnixNano())
+	t.Cleanup(func() { util.RedisClient.Del(ctx, key) })
+	bloom := util.NewBloomFilter(util.RedisClient, key, 1<<16, 7)
+
+	// 重建完成前 还没有写入的ID也要放行 否则重建期间真实数据会被误拦截
+	if !bloom.MightContain(ctx, "100") {
+		t.Fatal("重建完成前应该放行所有ID")
+	}
+	if err := bloom.Add(ctx, "1", "2", "3"); err != nil {
+		t.Fatalf("写入布隆过滤器失败: %v", err)
+	}
+	bloom.MarkReady()
+
+	for _, id := range []string{"1", "2", "3"} {
+		if !bloom.MightContain(ctx, id) {
+			t.Fatalf("已写入的ID不能判断为不存在: %s", id)
+		}
+	}
+	if bloom.MightContain(ctx, "100") {
+		t.Fatal("重建完成后未写入的ID应该被拦截")
+	}
+
+	// 新创建的ID在第一次读取前写入 之后立即可以查到
+	if err := bloom.Add(ctx, "100"); err != nil {
+		t.Fatalf("写入布隆过滤器失败: %v", err)
+	}
+	if !bloom.MightContain(ctx, "100") {
+		t.Fatal("新写入的ID应该立即可以查到")
+	}
+}
+
+// TestNotFoundPlaceholder 测试空值缓存的写入、读取和被真实数据覆盖
+func TestNotFoundPlaceholder(t *testing.T) {
+	// 加载全局配置并初始化Redis连接
+	cfg := config.Load()
+	util.InitRedis(cfg.RedisAddr, cfg.RedisPwd)
+
+	ctx := context.Background()
+	id := 900000000 + int(time.Now().UnixNano()%100000000)
+
+	// 商品
+	productRepo := repository.NewProductRepo(nil, util.RedisClient)
+	t.Cleanup(func() { productRepo.DelFromCache(ctx, id) })
+	if _, err := productRepo.GetFromCache(ctx, id); !errors.Is(err, redis.Nil) {
+		t.Fatalf("没有缓存时应该返回redis.Nil: %v", err)
+	}
+	if err := productRepo.SetNotFoundToCache(ctx, id); err != nil {
+		t.Fatalf("写入空值缓存失败: %v", err)
+	}
+	if _, err := productRepo.GetFromCache(ctx, id); !errors.Is(err, repository.ErrCachedNotFound) {
+		t.Fatalf("空值缓存应该返回ErrCachedNotFound: %v", err)
+	}
+	if err := productRepo.SetToCache(ctx, id, &model.Product{ID: id, Name: "新商品"}); err != nil {
+		t.Fatalf("写入商品缓存失败: %v", err)
+	}
+	if product, err := productRepo.GetFromCache(ctx, id); err != nil || product.Name != "新商品" {
+		t.Fatalf("真实数据应该覆盖空值缓存: %+v %v", product, err)
+	}
+	if err := productRepo.DelFromCache(ctx, id); err != nil {
+		t.Fatalf("删除商品缓存失败: %v", err)
+	}
+	if _, err := productRepo.GetFromCache(ctx, id); !errors.Is(err, redis.Nil) {
+		t.Fatalf("删除后应该返回redis.Nil: %v", err)
+	}
+
+	// 订单
+	orderRepo := repository.NewOrderRepo(nil, util.RedisClient)
+	orderID := "test" + strconv.Itoa(id)
+	t.Cleanup(func() { orderRepo.DelFromCache(ctx, orderID) })
+	if err := orderRepo.SetNotFoundToCache(ctx, orderID); err != nil {
+		t.Fatalf("写入空值缓存失败: %v", err)
+	}
+	if _, err := orderRepo.GetFromCache(ctx, orderID); !errors.Is(err, repository.ErrCachedNotFound) {
+		t.Fatalf("空值缓存应该返回ErrCachedNotFound: %v", err)
+	}
+	if err := orderRepo.SetToCache(ctx, orderID, &model.Order{ID: orderID}); err != nil {
+		t.Fatalf("写入订单缓存失败: %v", err)
+	}
+	if order, err := orderRepo.GetFromCache(ctx, orderID); err != nil || order.ID != orderID {
+		t.Fatalf("真实数据应该覆盖空值缓存: %+v %v", order, err)
+	}
+}
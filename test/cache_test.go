@@ -0,0 +1,163 @@
+package test
+
+import (
+	"context"
+	"demo01/internal/util"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestProductCacheKey 测试商品缓存key使用十进制编码
+func TestProductCacheKey(t *testing.T) {
+	cases := map[int]string{
+		1:      "product:1",
+		123:    "product:123",
+		100000: "product:100000",
+	}
+	for id, expected := range cases {
+		if key := util.ProductCacheKey(id); key != expected {
+			t.Fatalf("商品缓存key生成错误，期望: %s, 实际: %s", expected, key)
+		}
+	}
+}
+
+// TestTTLWithJitter 测试缓存过期时间抖动范围
+func TestTTLWithJitter(t *testing.T) {
+	base, jitter := 30*time.Minute, 5*time.Minute
+	for i := 0; i < 100; i++ {
+		ttl := util.TTLWithJitter(base, jitter)
+		if ttl < base || ttl >= base+jitter {
+			t.Fatalf("过期时间超出范围: %v", ttl)
+		}
+	}
+
+	if ttl := util.TTLWithJitter(base, 0); ttl != base {
+		t.Fatalf("无抖动时应返回基础过期时间，实际: %v", ttl)
+	}
+}
+
+// TestLocalCacheLRU 测试本地缓存超出容量时淘汰最久未使用的条目
+func TestLocalCacheLRU(t *testing.T) {
+	cache := util.NewLocalCache[int, string](2, 0)
+
+	cache.Set(1, "a")
+	cache.Set(2, "b")
+	cache.Get(1) // 访问1 使2成为最久未使用
+	cache.Set(3, "c")
+
+	if _, ok := cache.Get(2); ok {
+		t.Fatal("条目2应该已被淘汰")
+	}
+	if v, ok := cache.Get(1); !ok || v != "a" {
+		t.Fatalf("条目1应该仍在缓存中，实际: %v %v", v, ok)
+	}
+	if v, ok := cache.Get(3); !ok || v != "c" {
+		t.Fatalf("条目3应该在缓存中，实际: %v %v", v, ok)
+	}
+
+	stats := cache.Stats()
+	if stats.Size != 2 || stats.Evictions != 1 || stats.Hits != 3 || stats.Misses != 1 {
+		t.Fatalf("缓存统计错误: %+v", stats)
+	}
+}
+
+// TestLocalCacheTTL 测试本地缓存条目过期
+func TestLocalCacheTTL(t *testing.T) {
+	cache := util.NewLocalCache[string, int](10, 50*time.Millisecond)
+
+	cache.Set("short", 1)
+	cache.SetWithTTL("long", 2, time.Minute)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok := cache.Get("short"); ok {
+		t.Fatal("条目应该已经过期")
+	}
+	if v, ok := cache.Get("long"); !ok || v != 2 {
+		t.Fatalf("自定义过期时间的条目不应过期，实际: %v %v", v, ok)
+	}
+
+	cache.Delete("long")
+	if cache.Len() != 0 {
+		t.Fatalf("删除后缓存应为空，实际: %d", cache.Len())
+	}
+}
+
+// TestCacheLoaderCoalesce 测试并发未命中同一个key时只加载一次
+func TestCacheLoaderCoalesce(t *testing.T) {
+	loader := util.NewCacheLoader[int]()
+	ctx := context.Background()
+
+	var loads int32
+	recheck := func(ctx context.Context) (int, bool) { return 0, false }
+	load := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(50 * time.Millisecond) // 模拟慢查询
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.Load(ctx, "product:1", recheck, load)
+			if err != nil || v != 42 {
+				t.Errorf("加载结果错误: %v %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("期望只加载1次，实际: %d", n)
+	}
+}
+
+// TestCacheInvalidatorDispatch 测试失效消息按实体分发 以及重连后清空全部本地缓存
+func TestCacheInvalidatorDispatch(t *testing.T) {
+	ctx := context.Background()
+	invalidator := util.NewCacheInvalidator(nil)
+	var orders, products []string
+	invalidator.Register(util.CacheEntityOrder, func(id string) { orders = append(orders, id) })
+	invalidator.Register(util.CacheEntityProduct, func(id string) { products = append(products, "a:"+id) })
+	invalidator.Register(util.CacheEntityProduct, func(id string) { products = append(products, "b:"+id) })
+
+	publish := func(entity, id string) {
+		payload, _ := json.Marshal(util.InvalidationMessage{Entity: entity, ID: id, Source: "test"})
+		if err := invalidator.HandleMessage(string(payload)); err != nil {
+			t.Fatalf("处理失效消息失败: %v", err)
+		}
+	}
+
+	// 只调用对应实体的处理函数 同一实体的多个处理函数都会调用
+	publish(util.CacheEntityOrder, "o1")
+	publish(util.CacheEntityProduct, "12")
+	publish("unknown", "1")
+	if !reflect.DeepEqual(orders, []string{"o1"}) || !reflect.DeepEqual(products, []string{"a:12", "b:12"}) {
+		t.Fatalf("分发结果错误: %v %v", orders, products)
+	}
+	if err := invalidator.HandleMessage("not json"); err == nil {
+		t.Fatal("格式错误的消息应该返回错误")
+	}
+
+	// 首次订阅成功不清空 重连成功后所有实体都收到空ID 清空全部本地缓存
+	orders, products = nil, nil
+	invalidator.MarkSubscribed(ctx)
+	if len(orders) != 0 || len(products) != 0 {
+		t.Fatalf("首次订阅不应该清空本地缓存: %v %v", orders, products)
+	}
+	invalidator.MarkSubscribed(ctx)
+	if !reflect.DeepEqual(orders, []string{""}) || !reflect.DeepEqual(products, []string{"a:", "b:"}) {
+		t.Fatalf("重连后应该清空全部本地缓存: %v %v", orders, products)
+	}
+
+	// 没有Redis时发布消息直接忽略
+	if err := invalidator.Publish(ctx, util.CacheEntityOrder, "o1"); err != nil {
+		t.Fatalf("没有Redis时发布应该忽略: %v", err)
+	}
+}
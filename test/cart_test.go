@@ -0,0 +1,178 @@
+package test
+
+import (
+	"context"
+	"demo01/config"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestCartItemID 测试购物车行ID的生成和解析
+func TestCartItemID(t *testing.T) {
+	item := model.CartItem{ProductID: 12, SKUID: 3}
+	productID, skuID, err := model.ParseCartItemID(item.ID())
+	if err != nil || productID != 12 || skuID != 3 {
+		t.Fatalf("解析购物车行ID失败: %d %d %v", productID, skuID, err)
+	}
+	if _, _, err := model.ParseCartItemID(model.CartItemID(5, 0)); err != nil {
+		t.Fatalf("无规格商品的行ID应该合法: %v", err)
+	}
+
+	for _, id := range []string{"", "12", "a-1", "0-1", "12--1", "12-x"} {
+		if _, _, err := model.ParseCartItemID(id); err == nil {
+			t.Errorf("非法的行ID应该解析失败: %q", id)
+		}
+	}
+
+	if model.ValidateCartQuantity(0) == nil || model.ValidateCartQuantity(model.MaxCartItemQuantity+1) == nil {
+		t.Error("超出范围的数量应该校验失败")
+	}
+	if err := model.ValidateCartQuantity(model.MaxCartItemQuantity); err != nil {
+		t.Errorf("数量上限应该合法: %v", err)
+	}
+}
+
+// TestSelectCartItems 测试结算时选择购物车商品
+func TestSelectCartItems(t *testing.T) {
+	items := []model.CartItem{
+		{ProductID: 1, Quantity: 1, Selected: true},
+		{ProductID: 2, SKUID: 5, Quantity: 2, Selected: false},
+		{ProductID: 3, Quantity: 3, Selected: true},
+	}
+	ids := func(selected []model.CartItem) []string {
+		result := make([]string, 0, len(selected))
+		for _, item := range selected {
+			result = append(result, item.ID())
+		}
+		return result
+	}
+
+	cases := []struct {
+		name     string
+		items    []model.CartItem
+		itemIDs  []string
+		expected []string
+		err      error
+	}{
+		{"未指定时选出勾选的商品", items, nil, []string{"1-0", "3-0"}, nil},
+		{"指定时按指定顺序选出 不管是否勾选", items, []string{"3-0", "2-5"}, []string{"3-0", "2-5"}, nil},
+		{"重复的行ID只选一次", items, []string{"1-0", "1-0"}, []string{"1-0"}, nil},
+		{"不在购物车中的行", items, []string{"1-0", "9-0"}, nil, util.ErrNotFound},
+		{"没有勾选的商品", items[1:2], nil, nil, util.ErrInvalidInput},
+		{"购物车为空", nil, nil, nil, util.ErrInvalidInput},
+	}
+	for _, c := range cases {
+		selected, err := service.SelectCartItems(c.items, c.itemIDs)
+		if c.err != nil {
+			if !errors.Is(err, c.err) {
+				t.Fatalf("%s: 期望错误 %v, 实际 %v", c.name, c.err, err)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(ids(selected)) != fmt.Sprint(c.expected) {
+			t.Fatalf("%s: 期望 %v, 实际 %v %v", c.name, c.expected, ids(selected), err)
+		}
+	}
+}
+
+// TestBuildCartLine 测试用实时商品数据组装购物车行 以及各种不可购买的原因
+func TestBuildCartLine(t *testing.T) {
+	simple := &model.Product{ID: 1, Name: "数据线", Price: 20, Status: model.ProductStatusActive}
+	variant := &model.Product{ID: 2, Name: "手机", Price: 5000, Status: model.ProductStatusActive, SKUs: []model.SKU{
+		{ID: 5, ProductID: 2, Price: 5999, Stock: 1, Attributes: []model.SKUAttribute{{Name: "颜色", Value: "黑色"}}},
+	}}
+	offShelf := &model.Product{ID: 3, Name: "旧款", Price: 10, Status: model.ProductStatusInactive}
+	stocks := map[int]int{1: 10, 3: 10}
+
+	cases := []struct {
+		name      string
+		item      model.CartItem
+		product   *model.Product
+		price     float64
+		subtotal  float64
+		available bool
+		reason    string
+	}{
+		{"无规格商品使用库存表的库存", model.CartItem{ProductID: 1, Quantity: 2}, simple, 20, 40, true, ""},
+		{"多规格商品使用SKU的价格和库存", model.CartItem{ProductID: 2, SKUID: 5, Quantity: 1}, variant, 5999, 5999, true, ""},
+		{"库存不足", model.CartItem{ProductID: 2, SKUID: 5, Quantity: 2}, variant, 5999, 11998, false, service.CartUnavailableInsufficient},
+		{"SKU已删除", model.CartItem{ProductID: 2, SKUID: 6, Quantity: 1}, variant, 5000, 0, false, service.CartUnavailableSKUNotFound},
+		{"多规格商品未选规格", model.CartItem{ProductID: 2, Quantity: 1}, variant, 5000, 0, false, service.CartUnavailableSKUNotFound},
+		{"商品已下架", model.CartItem{ProductID: 3, Quantity: 1}, offShelf, 10, 10, false, service.CartUnavailableOffShelf},
+		{"商品已删除", model.CartItem{ProductID: 4, Quantity: 1}, nil, 0, 0, false, service.CartUnavailableNotFound},
+	}
+	for _, c := range cases {
+		line := service.BuildCartLine(c.item, c.product, stocks)
+		if line.Price != c.price || line.Subtotal != c.subtotal || line.Available != c.available || line.Reason != c.reason {
+			t.Fatalf("%s: 组装结果错误 %+v", c.name, line)
+		}
+	}
+	if line := service.BuildCartLine(model.CartItem{ProductID: 2, SKUID: 5, Quantity: 1}, variant, stocks); line.SKUName != "黑色" || line.Stock != 1 {
+		t.Fatalf("多规格商品应该带上规格名称和SKU库存: %+v", line)
+	}
+}
+
+// TestCartRepoAddItem 测试Redis加购脚本的数量累加、数量上限、行数上限和损坏数据的处理
+func TestCartRepoAddItem(t *testing.T) {
+	// 加载全局配置并初始化Redis连接
+	cfg := config.Load()
+	util.InitRedis(cfg.RedisAddr, cfg.RedisPwd)
+
+	ctx := context.Background()
+	repo := repository.NewCartRepo(util.RedisClient)
+	userID := 900000000 + int(time.Now().UnixNano()%100000000)
+	t.Cleanup(func() { repo.Clear(ctx, userID) })
+
+	add := func(productID, quantity int) (*model.CartItem, error) {
+		return repo.AddItem(ctx, userID, &model.CartItem{
+			ProductID: productID, Quantity: quantity, Selected: true, AddedAt: time.Now(),
+		}, 2, 5)
+	}
+
+	// 已在购物车中的累加数量并重新勾选
+	if _, err := add(1, 2); err != nil {
+		t.Fatalf("加购失败: %v", err)
+	}
+	// 取消勾选后再次加购
+	if err := repo.Save(ctx, userID, &model.CartItem{ProductID: 1, Quantity: 2, Selected: false, AddedAt: time.Now()}); err != nil {
+		t.Fatalf("写入购物车失败: %v", err)
+	}
+	item, err := add(1, 3)
+	if err != nil || item.Quantity != 5 || !item.Selected {
+		t.Fatalf("累加数量错误: %+v %v", item, err)
+	}
+
+	// 累加后超过数量上限 原数量不变
+	if _, err := add(1, 1); !errors.Is(err, model.ErrCartQuantityExceeded) {
+		t.Fatalf("超过数量上限应该失败: %v", err)
+	}
+	if item, _ := repo.Get(ctx, userID, model.CartItemID(1, 0)); item == nil || item.Quantity != 5 {
+		t.Fatalf("超过数量上限时不应该修改原数量: %+v", item)
+	}
+
+	// 行数达到上限时不能加入新商品 已有商品仍然可以累加
+	if _, err := add(2, 1); err != nil {
+		t.Fatalf("加购失败: %v", err)
+	}
+	if _, err := add(3, 1); !errors.Is(err, model.ErrCartFull) {
+		t.Fatalf("超过行数上限应该失败: %v", err)
+	}
+	if item, err := add(2, 1); err != nil || item.Quantity != 2 {
+		t.Fatalf("行数达到上限时已有商品应该可以累加: %+v %v", item, err)
+	}
+
+	// 损坏的数据按新商品处理 不占用新的行数
+	if err := util.RedisClient.HSet(ctx, fmt.Sprintf("cart:%d", userID), model.CartItemID(2, 0), "not json").Err(); err != nil {
+		t.Fatalf("写入损坏数据失败: %v", err)
+	}
+	item, err = add(2, 3)
+	if err != nil || item.Quantity != 3 || item.ProductID != 2 {
+		t.Fatalf("损坏的数据应该被新商品覆盖: %+v %v", item, err)
+	}
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"demo01/config"
+	"demo01/internal/model"
+	"demo01/internal/repository"
+	"demo01/internal/util"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCouponCalculate 测试三种优惠券的优惠计算、门槛和分摊
+func TestCouponCalculate(t *testing.T) {
+	newItems := func() []model.OrderItem {
+		return []model.OrderItem{
+			{ProductID: 1, Quantity: 2, Price: 30}, // 60
+			{ProductID: 2, Quantity: 1, Price: 40}, // 40
+			{ProductID: 3, Quantity: 1, Price: 50}, // 50 不在范围内
+		}
+	}
+	eligible := []bool{true, true, false}
+
+	// 立减 按金额比例分摊 60:40
+	items := newItems()
+	fixed := &model.Coupon{Type: model.CouponTypeFixed, Amount: 10}
+	discount, err := fixed.Calculate(items, eligible)
+	if err != nil {
+		t.Fatalf("立减券计算失败: %v", err)
+	}
+	if discount.Amount != 10 || discount.OriginalAmount != 150 || discount.EligibleAmount != 100 {
+		t.Fatalf("立减券金额错误: %+v", discount)
+	}
+	if items[0].Discount != 6 || items[1].Discount != 4 || items[2].Discount != 0 {
+		t.Fatalf("优惠分摊错误: %v %v %v", items[0].Discount, items[1].Discount, items[2].Discount)
+	}
+
+	// 折扣 减免15% 最高12元
+	items = newItems()
+	percent := &model.Coupon{Type: model.CouponTypePercent, Percent: 15, MaxDiscount: 12}
+	if discount, _ := percent.Calculate(items, eligible); discount.Amount != 12 {
+		t.Fatalf("折扣券应该按最高优惠封顶: %v", discount.Amount)
+	}
+
+	// 满减 范围内金额不足门槛
+	items = newItems()
+	threshold := &model.Coupon{Type: model.CouponTypeThreshold, Amount: 20, MinSpend: 120}
+	if _, err := threshold.Calculate(items, eligible); !errors.Is(err, model.ErrCouponThresholdNotMet) {
+		t.Fatalf("未达到门槛应该返回ErrCouponThresholdNotMet: %v", err)
+	}
+	if _, err := fixed.Calculate(newItems(), []bool{false, false, false}); !errors.Is(err, model.ErrCouponNotApplicable) {
+		t.Fatalf("没有适用商品应该返回ErrCouponNotApplicable: %v", err)
+	}
+
+	// 分摊的尾差计入最后一项 合计等于优惠金额
+	items = []model.OrderItem{
+		{ProductID: 1, Quantity: 1, Price: 10},
+		{ProductID: 2, Quantity: 1, Price: 10},
+		{ProductID: 3, Quantity: 1, Price: 10},
+	}
+	discount, _ = (&model.Coupon{Type: model.CouponTypeFixed, Amount: 10}).Calculate(items, []bool{true, true, true})
+	sum := 0.0
+	for _, line := range discount.Lines {
+		sum += line.Amount
+	}
+	if model.RoundAmount(sum) != 10 {
+		t.Fatalf("分摊合计应该等于优惠金额: %v", sum)
+	}
+}
+
+// TestCouponScopeAndValidity 测试优惠券的适用范围和有效期
+func TestCouponScopeAndValidity(t *testing.T) {
+	coupon := &model.Coupon{ProductIDs: []int{7}, CategoryIDs: []int{3}}
+	if !coupon.InScope(7, "/1/") {
+		t.Error("指定商品应该在范围内")
+	}
+	if !coupon.InScope(8, "/1/3/12/") {
+		t.Error("子分类的商品应该在范围内")
+	}
+	if coupon.InScope(8, "/1/13/") {
+		t.Error("其他分类的商品不应该在范围内")
+	}
+	if !(&model.Coupon{}).InScope(8, "") {
+		t.Error("未指定范围时全场可用")
+	}
+
+	now := time.Now()
+	valid := &model.Coupon{Status: model.CouponStatusActive, StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour)}
+	if err := valid.CheckUsable(now); err != nil {
+		t.Errorf("有效期内应该可以使用: %v", err)
+	}
+	if err := valid.CheckUsable(now.Add(2 * time.Hour)); !errors.Is(err, model.ErrCouponExpired) {
+		t.Errorf("过期后应该返回ErrCouponExpired: %v", err)
+	}
+	valid.Status = model.CouponStatusDisabled
+	if err := valid.CheckUsable(now); !errors.Is(err, model.ErrCouponDisabled) {
+		t.Errorf("停用后应该返回ErrCouponDisabled: %v", err)
+	}
+}
+
+// TestCouponReserveLimits 测试Redis预占脚本的总量限制和每人限制
+func TestCouponReserveLimits(t *testing.T) {
+	// 加载全局配置并初始化Redis连接
+	cfg := config.Load()
+	util.InitRedis(cfg.RedisAddr, cfg.RedisPwd)
+
+	ctx := context.Background()
+	// 计数已存在时不会从数据库恢复 测试不需要数据库
+	repo := repository.NewCouponRepo(nil, util.RedisClient)
+	newCoupon := func() int {
+		couponID := 900000000 + int(time.Now().UnixNano()%100000000)
+		key := fmt.Sprintf("coupon:redemptions:%d", couponID)
+		if err := util.RedisClient.HSet(ctx, key, "total", 0).Err(); err != nil {
+			t.Fatalf("初始化计数失败: %v", err)
+		}
+		t.Cleanup(func() { util.RedisClient.Del(ctx, key) })
+		return couponID
+	}
+
+	// 总量3次 每人2次
+	couponID := newCoupon()
+	for i := 0; i < 2; i++ {
+		if err := repo.Reserve(ctx, couponID, 1, 3, 2); err != nil {
+			t.Fatalf("用户1第%d次预占失败: %v", i+1, err)
+		}
+	}
+	if err := repo.Reserve(ctx, couponID, 1, 3, 2); !errors.Is(err, model.ErrCouponUserLimitReached) {
+		t.Fatalf("超过每人限制应该失败: %v", err)
+	}
+	if err := repo.Reserve(ctx, couponID, 2, 3, 2); err != nil {
+		t.Fatalf("用户2预占失败: %v", err)
+	}
+	if err := repo.Reserve(ctx, couponID, 3, 3, 2); !errors.Is(err, model.ErrCouponSoldOut) {
+		t.Fatalf("超过总量应该失败: %v", err)
+	}
+
+	// 归还后其他用户可以继续使用
+	if err := repo.Release(ctx, couponID, 1); err != nil {
+		t.Fatalf("归还失败: %v", err)
+	}
+	if err := repo.Reserve(ctx, couponID, 3, 3, 2); err != nil {
+		t.Fatalf("归还后预占失败: %v", err)
+	}
+	if err := repo.Reserve(ctx, couponID, 4, 3, 2); !errors.Is(err, model.ErrCouponSoldOut) {
+		t.Fatalf("再次用完总量后应该失败: %v", err)
+	}
+
+	// 并发预占不超过总量
+	couponID = newCoupon()
+	var wg sync.WaitGroup
+	var success int32
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			if err := repo.Reserve(ctx, couponID, userID, 5, 1); err == nil {
+				atomic.AddInt32(&success, 1)
+			} else if !errors.Is(err, model.ErrCouponSoldOut) {
+				t.Errorf("用户%d预占出错: %v", userID, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if success != 5 {
+		t.Fatalf("并发预占成功次数应该为5 实际为%d", success)
+	}
+}
@@ -235,6 +235,36 @@ func TestDistributedLockExpiration(t *testing.T) {
 	t.Log("分布式锁过期机制测试通过")
 }
 
+// TestLockMetrics 测试锁指标按类型聚合
+func TestLockMetrics(t *testing.T) {
+	metrics := util.NewLockMetrics()
+
+	metrics.ObserveAcquire("lock:inventory:1", true, 2, 30*time.Millisecond)
+	metrics.ObserveAcquire("lock:inventory:2", false, 4, 10*time.Millisecond)
+	metrics.ObserveRelease("lock:inventory:1", 20*time.Millisecond)
+	metrics.ObserveLost("lock:order:o1")
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("期望2种锁类型，实际: %d", len(snapshot))
+	}
+
+	inventory := snapshot[0]
+	if inventory.Type != "inventory" || inventory.Acquired != 1 || inventory.Failed != 1 || inventory.Retries != 6 {
+		t.Fatalf("库存锁指标错误: %+v", inventory)
+	}
+	if inventory.AvgAcquireMs != 20 || inventory.MaxAcquireMs != 30 || inventory.ContentionRatio != 3 {
+		t.Fatalf("库存锁耗时统计错误: %+v", inventory)
+	}
+	if inventory.Released != 1 || inventory.AvgHoldMs != 20 {
+		t.Fatalf("库存锁持有时间统计错误: %+v", inventory)
+	}
+
+	if order := snapshot[1]; order.Type != "order" || order.Lost != 1 {
+		t.Fatalf("订单锁指标错误: %+v", order)
+	}
+}
+
 // BenchmarkDistributedLock 分布式锁性能基准测试
 func BenchmarkDistributedLock(b *testing.B) {
 	// 加载全局配置并初始化Redis连接
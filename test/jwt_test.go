@@ -0,0 +1,46 @@
+package test
+
+import (
+	"demo01/internal/util"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestJWTSignAndParse 测试令牌签发、篡改检测和过期校验
+func TestJWTSignAndParse(t *testing.T) {
+	secret := []byte("test-secret")
+	claims := util.NewTokenClaims(12, "tom", "customer", util.TokenTypeAccess, time.Minute)
+
+	token, err := util.SignToken(secret, claims)
+	if err != nil {
+		t.Fatalf("签发令牌失败: %v", err)
+	}
+	parsed, err := util.ParseToken(secret, token)
+	if err != nil {
+		t.Fatalf("解析令牌失败: %v", err)
+	}
+	if parsed.UserID != 12 || parsed.Username != "tom" || parsed.Type != util.TokenTypeAccess || parsed.ID != claims.ID {
+		t.Fatalf("令牌内容不一致: %+v", parsed)
+	}
+
+	// 密钥不同或内容被篡改时签名校验失败
+	if _, err := util.ParseToken([]byte("other-secret"), token); !errors.Is(err, util.ErrTokenInvalid) {
+		t.Fatalf("错误密钥应该校验失败: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	forged, _ := util.SignToken(secret, util.NewTokenClaims(1, "admin", "admin", util.TokenTypeAccess, time.Minute))
+	tampered := parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+	if _, err := util.ParseToken(secret, tampered); !errors.Is(err, util.ErrTokenInvalid) {
+		t.Fatalf("篡改后的令牌应该校验失败: %v", err)
+	}
+	if _, err := util.ParseToken(secret, "not-a-token"); !errors.Is(err, util.ErrTokenInvalid) {
+		t.Fatalf("格式错误的令牌应该校验失败: %v", err)
+	}
+
+	expired, _ := util.SignToken(secret, util.NewTokenClaims(12, "tom", "customer", util.TokenTypeAccess, -time.Second))
+	if _, err := util.ParseToken(secret, expired); !errors.Is(err, util.ErrTokenExpired) {
+		t.Fatalf("过期令牌应该返回ErrTokenExpired: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"demo01/internal/model"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestOrderCursor 测试订单分页游标的编码和解析
+func TestOrderCursor(t *testing.T) {
+	order := &model.Order{ID: "o0101120000123", CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 123000000, time.Local)}
+	cursor, err := model.DecodeOrderCursor(model.EncodeOrderCursor(order))
+	if err != nil {
+		t.Fatalf("解析游标失败: %v", err)
+	}
+	if cursor.ID != order.ID || !cursor.CreatedAt.Equal(order.CreatedAt) {
+		t.Fatalf("游标内容不一致: %+v", cursor)
+	}
+
+	for _, bad := range []string{"", "!!!", "e30"} { // e30 为 {} 的编码
+		if _, err := model.DecodeOrderCursor(bad); err == nil {
+			t.Fatalf("游标 %q 应该无效", bad)
+		}
+	}
+}
+
+// TestOrderItemQuantity 测试订单项数量校验 0和负数会变成增加库存 必须拒绝
+func TestOrderItemQuantity(t *testing.T) {
+	cases := []struct {
+		quantity int
+		valid    bool
+	}{
+		{1, true},
+		{model.MaxCartItemQuantity, true},
+		{0, false},
+		{-1, false},
+		{model.MaxCartItemQuantity + 1, false},
+	}
+	for _, c := range cases {
+		item := model.OrderItem{ProductID: 1, Quantity: c.quantity}
+		err := item.ValidateQuantity()
+		if c.valid && err != nil {
+			t.Fatalf("数量%d应该合法: %v", c.quantity, err)
+		}
+		if !c.valid && !errors.Is(err, model.ErrCartQuantityExceeded) {
+			t.Fatalf("数量%d应该被拒绝: %v", c.quantity, err)
+		}
+	}
+}
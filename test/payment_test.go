@@ -0,0 +1,74 @@
+package test
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestPaymentSignature 测试回调签名的校验、篡改检测和时间戳过期
+func TestPaymentSignature(t *testing.T) {
+	secret := []byte("test-secret")
+	body := []byte(`{"provider_ref":"mock_p1","status":"succeeded","amount":99.5}`)
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := util.SignPayload(secret, ts, body)
+
+	if err := util.VerifyPayload(secret, ts, body, sig, 5*time.Minute, now); err != nil {
+		t.Fatalf("正确的签名应该通过校验: %v", err)
+	}
+
+	// 篡改请求体、时间戳或使用其他密钥都应该失败
+	tampered := []byte(`{"provider_ref":"mock_p1","status":"succeeded","amount":0.01}`)
+	if err := util.VerifyPayload(secret, ts, tampered, sig, 5*time.Minute, now); !errors.Is(err, util.ErrSignatureInvalid) {
+		t.Fatalf("篡改请求体应该校验失败: %v", err)
+	}
+	if err := util.VerifyPayload(secret, strconv.FormatInt(now.Unix()+1, 10), body, sig, 5*time.Minute, now); !errors.Is(err, util.ErrSignatureInvalid) {
+		t.Fatalf("篡改时间戳应该校验失败: %v", err)
+	}
+	if err := util.VerifyPayload([]byte("other"), ts, body, sig, 5*time.Minute, now); !errors.Is(err, util.ErrSignatureInvalid) {
+		t.Fatalf("其他密钥应该校验失败: %v", err)
+	}
+
+	// 超出允许误差的回调视为重放
+	if err := util.VerifyPayload(secret, ts, body, sig, 5*time.Minute, now.Add(10*time.Minute)); !errors.Is(err, util.ErrSignatureExpired) {
+		t.Fatalf("过期的回调应该校验失败: %v", err)
+	}
+}
+
+// TestMockPaymentProvider 测试模拟渠道生成的回调可以被自身校验并还原
+func TestMockPaymentProvider(t *testing.T) {
+	provider := service.NewMockPaymentProvider([]byte("test-secret"))
+
+	intent, err := provider.CreateIntent(context.Background(), service.PaymentRequest{PaymentID: "p1", Amount: 99.5})
+	if err != nil || intent.ProviderRef != "mock_p1" || intent.PayURL == "" {
+		t.Fatalf("创建支付意图错误: %+v %v", intent, err)
+	}
+
+	body, sig, ts, err := provider.SignCallback(model.PaymentEvent{
+		ProviderRef: intent.ProviderRef,
+		Status:      model.PaymentStatusSucceeded,
+		Amount:      99.5,
+	})
+	if err != nil {
+		t.Fatalf("生成回调失败: %v", err)
+	}
+	event, err := provider.VerifyCallback(sig, ts, body)
+	if err != nil {
+		t.Fatalf("回调校验失败: %v", err)
+	}
+	if event.ProviderRef != "mock_p1" || event.Status != model.PaymentStatusSucceeded || event.Amount != 99.5 {
+		t.Fatalf("回调内容错误: %+v", event)
+	}
+
+	// 其他密钥签出的回调不能通过校验
+	other := service.NewMockPaymentProvider([]byte("other-secret"))
+	if _, err := other.VerifyCallback(sig, ts, body); err == nil {
+		t.Fatal("其他密钥签出的回调不应该通过校验")
+	}
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"demo01/internal/model"
+	"testing"
+	"time"
+)
+
+// TestPriceAt 测试根据价格变更记录计算某一时刻的价格
+func TestPriceAt(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	histories := []model.PriceHistory{
+		{OldPrice: 100, NewPrice: 90, ChangedAt: base.Add(24 * time.Hour)},
+		{OldPrice: 90, NewPrice: 120, ChangedAt: base.Add(48 * time.Hour)},
+	}
+
+	cases := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"第一次变更之前", base, 100},
+		{"恰好在变更时刻", base.Add(24 * time.Hour), 90},
+		{"两次变更之间", base.Add(36 * time.Hour), 90},
+		{"最后一次变更之后", base.Add(72 * time.Hour), 120},
+	}
+	for _, c := range cases {
+		if got := model.PriceAt(histories, c.at, 120); got != c.want {
+			t.Fatalf("%s: 期望价格 %.2f, 实际 %.2f", c.name, c.want, got)
+		}
+	}
+
+	if got := model.PriceAt(nil, base, 88); got != 88 {
+		t.Fatalf("没有变更记录时应返回当前价格, 实际 %.2f", got)
+	}
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"bytes"
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestValidateImportRow 测试导入行的字段校验
+func TestValidateImportRow(t *testing.T) {
+	valid := func() service.ProductImportRow {
+		return service.ProductImportRow{ExternalSKU: " SKU-1 ", Name: " iPhone 15 ", Price: 5999, Stock: 10}
+	}
+
+	row := valid()
+	if msg := service.ValidateImportRow(&row); msg != "" {
+		t.Fatalf("合法的行校验失败: %s", msg)
+	}
+	if row.ExternalSKU != "SKU-1" || row.Name != "iPhone 15" || row.Status != "" {
+		t.Fatalf("校验时应该去掉首尾空白且不填默认状态: %+v", row)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(r *service.ProductImportRow)
+	}{
+		{"外部编码为空", func(r *service.ProductImportRow) { r.ExternalSKU = " " }},
+		{"外部编码过长", func(r *service.ProductImportRow) { r.ExternalSKU = strings.Repeat("a", 65) }},
+		{"名称为空", func(r *service.ProductImportRow) { r.Name = "" }},
+		{"名称过长", func(r *service.ProductImportRow) { r.Name = strings.Repeat("手", 101) }},
+		{"价格为0", func(r *service.ProductImportRow) { r.Price = 0 }},
+		{"库存为负", func(r *service.ProductImportRow) { r.Stock = -1 }},
+		{"状态无效", func(r *service.ProductImportRow) { r.Status = "deleted" }},
+	}
+	for _, c := range cases {
+		row := valid()
+		c.modify(&row)
+		if msg := service.ValidateImportRow(&row); msg == "" {
+			t.Fatalf("%s 应该校验失败", c.name)
+		}
+	}
+}
+
+// TestResolveImportStatus 测试导入时的商品状态 已有商品的状态变更需要符合流转规则
+func TestResolveImportStatus(t *testing.T) {
+	cases := []struct {
+		current, requested, expected string
+		ok                           bool
+	}{
+		{"", "", model.ProductStatusActive, true},                            // 新商品默认上架
+		{"", model.ProductStatusDraft, model.ProductStatusDraft, true},       // 新商品可以是任意状态
+		{model.ProductStatusInactive, "", model.ProductStatusInactive, true}, // 未指定时保持原状态
+		{model.ProductStatusActive, model.ProductStatusActive, model.ProductStatusActive, true},
+		{model.ProductStatusActive, model.ProductStatusInactive, model.ProductStatusInactive, true},
+		{model.ProductStatusDiscontinued, model.ProductStatusActive, model.ProductStatusActive, false}, // 停售是终态
+		{model.ProductStatusActive, model.ProductStatusDraft, model.ProductStatusDraft, false},
+	}
+	for _, c := range cases {
+		status, ok := model.ResolveImportStatus(c.current, c.requested)
+		if ok != c.ok || (ok && status != c.expected) {
+			t.Fatalf("%q -> %q 期望 %q/%v, 实际 %q/%v", c.current, c.requested, c.expected, c.ok, status, ok)
+		}
+	}
+}
+
+// TestProductFileRoundTrip 测试导出的文件可以原样重新导入
+func TestProductFileRoundTrip(t *testing.T) {
+	sku1, sku2 := "SKU-1", "SKU-2"
+	products := []model.Product{
+		{ID: 1, ExternalSKU: &sku1, Name: "iPhone 15", Description: `含有逗号, "引号"和
+换行的描述`, Price: 5999, Stock: 10, CategoryID: 3, Category: "手机", Status: model.ProductStatusActive},
+		{ID: 2, ExternalSKU: &sku2, Name: "AirPods", Price: 1299.5, Stock: 0, Status: model.ProductStatusInactive},
+	}
+
+	for _, format := range []string{service.ProductFileCSV, service.ProductFileJSONL} {
+		var buf bytes.Buffer
+		write, err := service.NewProductFileWriter(&buf, format)
+		if err != nil {
+			t.Fatalf("%s 创建写出器失败: %v", format, err)
+		}
+		if err := write(products); err != nil {
+			t.Fatalf("%s 导出失败: %v", format, err)
+		}
+
+		next, err := service.NewProductRowReader(&buf, format)
+		if err != nil {
+			t.Fatalf("%s 读取导出文件失败: %v", format, err)
+		}
+		for i, p := range products {
+			_, row, err := next()
+			if err != nil {
+				t.Fatalf("%s 第%d个商品读取失败: %v", format, i+1, err)
+			}
+			if msg := service.ValidateImportRow(row); msg != "" {
+				t.Fatalf("%s 第%d个商品校验失败: %s", format, i+1, msg)
+			}
+			if row.ExternalSKU != *p.ExternalSKU || row.Name != p.Name || row.Description != p.Description ||
+				row.Price != p.Price || row.Stock != p.Stock || row.CategoryID != p.CategoryID ||
+				row.Category != p.Category || row.Status != p.Status {
+				t.Fatalf("%s 第%d个商品往返后不一致: %+v", format, i+1, row)
+			}
+		}
+		if _, _, err := next(); !errors.Is(err, io.EOF) {
+			t.Fatalf("%s 读取完所有商品后应该返回EOF: %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"demo01/internal/model"
+	"errors"
+	"testing"
+)
+
+// TestProductStatusTransition 测试商品状态流转规则
+func TestProductStatusTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{model.ProductStatusDraft, model.ProductStatusActive, true},
+		{model.ProductStatusActive, model.ProductStatusInactive, true},
+		{model.ProductStatusInactive, model.ProductStatusActive, true},
+		{model.ProductStatusActive, model.ProductStatusDiscontinued, true},
+		{model.ProductStatusInactive, model.ProductStatusDraft, false},
+		{model.ProductStatusDiscontinued, model.ProductStatusActive, false},
+		{model.ProductStatusActive, model.ProductStatusActive, false},
+	}
+	for _, c := range cases {
+		if got := model.CanTransitionProductStatus(c.from, c.to); got != c.allowed {
+			t.Fatalf("状态流转 %s -> %s 期望: %v, 实际: %v", c.from, c.to, c.allowed, got)
+		}
+	}
+
+	if model.IsValidProductStatus("deleted") {
+		t.Fatal("deleted不是合法的商品状态")
+	}
+	if (&model.Product{Status: model.ProductStatusInactive}).IsPurchasable() {
+		t.Fatal("下架商品不能下单")
+	}
+}
+
+// TestVariantMatrix 测试SKU规格校验和规格矩阵生成
+func TestVariantMatrix(t *testing.T) {
+	sku := func(id int, color, storage string, stock int) model.SKU {
+		return model.SKU{
+			ID:    id,
+			Price: 5999,
+			Stock: stock,
+			Attributes: []model.SKUAttribute{
+				{Name: "颜色", Value: color},
+				{Name: "容量", Value: storage},
+			},
+		}
+	}
+	skus := []model.SKU{
+		sku(1, "黑色", "128GB", 10),
+		sku(2, "黑色", "256GB", 0),
+		sku(3, "白色", "128GB", 5),
+	}
+	if err := model.ValidateSKUs(skus); err != nil {
+		t.Fatalf("合法的SKU校验失败: %v", err)
+	}
+	if err := model.ValidateSKUs(append(skus, sku(4, "黑色", "128GB", 1))); err == nil {
+		t.Fatal("重复的规格组合应该校验失败")
+	}
+	mismatch := model.SKU{Price: 1, Attributes: []model.SKUAttribute{{Name: "颜色", Value: "蓝色"}}}
+	if err := model.ValidateSKUs(append(skus, mismatch)); err == nil {
+		t.Fatal("规格维度不一致应该校验失败")
+	}
+
+	matrix := model.BuildVariantMatrix(skus)
+	if len(matrix.Dimensions) != 2 || matrix.Dimensions[0].Name != "颜色" || matrix.Dimensions[1].Name != "容量" {
+		t.Fatalf("规格维度顺序错误: %+v", matrix.Dimensions)
+	}
+	if got := matrix.Dimensions[0].Values; len(got) != 2 || got[0] != "黑色" || got[1] != "白色" {
+		t.Fatalf("颜色规格值错误: %v", got)
+	}
+	cell, ok := matrix.Variants["黑色/256GB"]
+	if !ok || cell.SKUID != 2 || cell.Available {
+		t.Fatalf("黑色/256GB 应该对应无库存的SKU 2, 实际: %+v", cell)
+	}
+	if _, ok := matrix.Variants["白色/256GB"]; ok {
+		t.Fatal("不存在的规格组合不应该出现在矩阵中")
+	}
+}
+
+// TestPriceOrderItem 测试订单项以商品或SKU的当前价格定价 忽略请求中的价格
+func TestPriceOrderItem(t *testing.T) {
+	plain := &model.Product{ID: 1, Price: 99}
+	item := model.OrderItem{ProductID: 1, Quantity: 1, Price: 0.01}
+	if err := plain.PriceOrderItem(&item); err != nil || item.Price != 99 {
+		t.Fatalf("单规格商品应该使用商品价格: %v %v", item.Price, err)
+	}
+	item.SKUID = 3
+	if err := plain.PriceOrderItem(&item); !errors.Is(err, model.ErrProductHasNoSKUs) {
+		t.Fatalf("单规格商品不能指定SKU: %v", err)
+	}
+
+	multi := &model.Product{ID: 2, Price: 1, SKUs: []model.SKU{
+		{ID: 5, Price: 5999, Attributes: []model.SKUAttribute{{Name: "颜色", Value: "黑色"}}},
+	}}
+	item = model.OrderItem{ProductID: 2, SKUID: 5, Quantity: 1, Price: 0.01}
+	if err := multi.PriceOrderItem(&item); err != nil || item.Price != 5999 || item.SKUName != "黑色" {
+		t.Fatalf("多规格商品应该使用SKU价格和规格: %+v %v", item, err)
+	}
+	item.SKUID = 0
+	if err := multi.PriceOrderItem(&item); !errors.Is(err, model.ErrSKURequired) {
+		t.Fatalf("多规格商品必须指定SKU: %v", err)
+	}
+	item.SKUID = 6
+	if err := multi.PriceOrderItem(&item); !errors.Is(err, model.ErrSKUNotInProduct) {
+		t.Fatalf("不属于该商品的SKU应该失败: %v", err)
+	}
+}
+
+// TestBuildCategoryTree 测试物化路径和分类树组装
+func TestBuildCategoryTree(t *testing.T) {
+	if got := model.CategoryPath("", 1); got != "/1/" {
+		t.Fatalf("一级分类路径错误: %s", got)
+	}
+	if got := model.CategoryPath("/1/", 3); got != "/1/3/" {
+		t.Fatalf("二级分类路径错误: %s", got)
+	}
+
+	categories := []model.Category{
+		{ID: 1, Name: "数码", Level: 1, Path: "/1/"},
+		{ID: 2, Name: "家电", Level: 1, Path: "/2/"},
+		{ID: 3, Name: "手机", ParentID: 1, Level: 2, Path: "/1/3/"},
+		{ID: 4, Name: "电脑", ParentID: 1, Level: 2, Path: "/1/4/"},
+		{ID: 5, Name: "游戏本", ParentID: 4, Level: 3, Path: "/1/4/5/"},
+	}
+	tree := model.BuildCategoryTree(categories)
+	if len(tree) != 2 || tree[0].ID != 1 || tree[1].ID != 2 {
+		t.Fatalf("根分类错误: %+v", tree)
+	}
+	if len(tree[0].Children) != 2 || tree[0].Children[1].ID != 4 {
+		t.Fatalf("数码的子分类错误: %+v", tree[0].Children)
+	}
+	if len(tree[0].Children[1].Children) != 1 || tree[0].Children[1].Children[0].Name != "游戏本" {
+		t.Fatal("电脑下应该有游戏本")
+	}
+
+	// 只传入子树时 子树的根作为根节点返回
+	subtree := model.BuildCategoryTree(categories[3:])
+	if len(subtree) != 1 || subtree[0].ID != 4 || len(subtree[0].Children) != 1 {
+		t.Fatalf("子树组装错误: %+v", subtree)
+	}
+}
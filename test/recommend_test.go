@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"demo01/internal/util"
+	"errors"
+	"testing"
+)
+
+// TestCoPurchaseCounter 测试共同购买统计和多列表合并
+func TestCoPurchaseCounter(t *testing.T) {
+	counter := model.NewCoPurchaseCounter()
+	counter.AddOrder([]model.OrderItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 2}})
+	counter.AddOrder([]model.OrderItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 1}, {ProductID: 3, Quantity: 1}})
+	// 同一商品的多个SKU只算一次同单购买
+	counter.AddOrder([]model.OrderItem{{ProductID: 1, SKUID: 10, Quantity: 1}, {ProductID: 1, SKUID: 11, Quantity: 1}, {ProductID: 3, Quantity: 1}})
+
+	top := counter.Top(1, 10)
+	if len(top) != 2 || top[0].ProductID != 2 || top[0].Score != 2 || top[1].ProductID != 3 || top[1].Score != 2 {
+		// 2和3都与1同单两次 分数相同时按ID升序
+		t.Fatalf("商品1的共同购买结果错误: %+v", top)
+	}
+	if top := counter.Top(2, 1); len(top) != 1 || top[0].ProductID != 1 {
+		t.Fatalf("limit截取错误: %+v", top)
+	}
+
+	best := counter.BestSellers(2)
+	if len(best) != 2 || best[0].ProductID != 1 || best[0].Score != 4 || best[1].ProductID != 2 {
+		t.Fatalf("热销榜单错误: %+v", best)
+	}
+
+	merged := model.MergeScoredProducts([][]model.ScoredProduct{counter.Top(2, 10), counter.Top(3, 10)},
+		map[int]bool{2: true}, 10)
+	if len(merged) != 2 || merged[0].ProductID != 1 || merged[0].Score != 4 || merged[1].ProductID != 3 {
+		t.Fatalf("合并结果错误: %+v", merged)
+	}
+}
+
+// TestFetchProductsInOrder 测试批量查询商品保持请求顺序、限制ID数量并返回查不到的ID
+func TestFetchProductsInOrder(t *testing.T) {
+	catalog := map[int]*model.Product{
+		1: {ID: 1, Status: model.ProductStatusActive},
+		2: {ID: 2, Status: model.ProductStatusActive},
+		3: {ID: 3, Status: model.ProductStatusDraft}, // 不可购买
+		5: {ID: 5, Status: model.ProductStatusActive},
+	}
+	fetch := func(ctx context.Context, id int) (*model.Product, error) {
+		if product, ok := catalog[id]; ok {
+			return product, nil
+		}
+		return nil, util.NewBusinessError("PRODUCT_NOT_FOUND", "商品不存在", util.ErrNotFound)
+	}
+
+	result, err := service.FetchProductsInOrder(context.Background(), []int{5, 4, 1, 3, 5, 2}, fetch)
+	if err != nil {
+		t.Fatalf("批量查询失败: %v", err)
+	}
+	if len(result.Products) != 3 || result.Products[0].ID != 5 || result.Products[1].ID != 1 || result.Products[2].ID != 2 {
+		t.Fatalf("应该按请求顺序返回去重后的可购买商品: %+v", result.Products)
+	}
+	if len(result.Missing) != 2 || result.Missing[0] != 4 || result.Missing[1] != 3 {
+		t.Fatalf("查不到和不可购买的ID应该按顺序记录: %v", result.Missing)
+	}
+
+	// 最多100个ID 重复的ID去重后计数
+	ids := make([]int, 0, 101)
+	for i := 1; i <= 100; i++ {
+		ids = append(ids, i, i)
+	}
+	if _, err := service.FetchProductsInOrder(context.Background(), ids, fetch); err != nil {
+		t.Fatalf("去重后100个ID应该允许: %v", err)
+	}
+	if _, err := service.FetchProductsInOrder(context.Background(), append(ids, 101), fetch); !errors.Is(err, util.ErrInvalidInput) {
+		t.Fatalf("超过100个ID应该失败: %v", err)
+	}
+
+	// 查询出错时返回错误 不当作查不到
+	failing := func(ctx context.Context, id int) (*model.Product, error) {
+		return nil, errors.New("db down")
+	}
+	if _, err := service.FetchProductsInOrder(context.Background(), []int{1}, failing); err == nil || errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("查询出错应该返回错误: %v", err)
+	}
+
+	// 请求取消后返回取消错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.FetchProductsInOrder(ctx, []int{1, 2}, fetch); !errors.Is(err, context.Canceled) {
+		t.Fatalf("请求取消后应该返回取消错误: %v", err)
+	}
+}
+
+// TestCoPurchaseCountOrder 测试只有已支付的订单计入共同购买 未支付和已退款的订单不能影响推荐
+func TestCoPurchaseCountOrder(t *testing.T) {
+	counter := model.NewCoPurchaseCounter()
+	items := []model.OrderItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 1}}
+	for _, status := range []string{model.OrderStatusPending, model.OrderStatusCancelled, model.OrderStatusRefunded} {
+		if counter.CountOrder(status, items) {
+			t.Fatalf("%s状态的订单不应该计入", status)
+		}
+	}
+	if top := counter.Top(1, 10); len(top) != 0 {
+		t.Fatalf("未支付的订单不应该产生共同购买: %+v", top)
+	}
+
+	for _, status := range model.PurchasedOrderStatuses {
+		if !counter.CountOrder(status, items) {
+			t.Fatalf("%s状态的订单应该计入", status)
+		}
+	}
+	if top := counter.Top(1, 10); len(top) != 1 || top[0].ProductID != 2 || top[0].Score != 3 {
+		t.Fatalf("已支付的订单应该计入共同购买: %+v", top)
+	}
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"demo01/internal/model"
+	"errors"
+	"testing"
+)
+
+// TestPlanRefund 测试全额退款、按数量部分退款、退差价和超额退款的校验
+func TestPlanRefund(t *testing.T) {
+	items := []model.OrderItem{
+		{ProductID: 1, Quantity: 3, Price: 10, Discount: 1}, // 实付29
+		{ProductID: 2, SKUID: 5, Quantity: 1, Price: 20},    // 实付20
+	}
+	paid := 49.0
+
+	// 全额退款 退还所有行的数量和金额
+	lines, total, err := model.PlanRefund(items, nil, nil, paid)
+	if err != nil || total != 49 || len(lines) != 2 || lines[0].Quantity != 3 || lines[1].SKUID != 5 {
+		t.Fatalf("全额退款错误: %+v %v %v", lines, total, err)
+	}
+
+	// 按数量折算 29*1/3 = 9.67
+	lines, total, err = model.PlanRefund(items, nil, []model.RefundLineRequest{{Line: 0, Quantity: 1}}, paid)
+	if err != nil || total != 9.67 || lines[0].Quantity != 1 {
+		t.Fatalf("部分退款错误: %+v %v %v", lines, total, err)
+	}
+
+	// 退完剩余数量时退还剩余金额 不留尾差
+	previous := []model.Refund{{Amount: 9.67, Lines: []model.RefundLine{{Line: 0, Quantity: 1, Amount: 9.67}}}}
+	_, total, err = model.PlanRefund(items, previous, []model.RefundLineRequest{{Line: 0, Quantity: 2}}, paid)
+	if err != nil || total != 19.33 {
+		t.Fatalf("退完剩余数量应该退还剩余金额: %v %v", total, err)
+	}
+
+	// 之后的全额退款只退剩余部分
+	_, total, err = model.PlanRefund(items, previous, nil, paid)
+	if err != nil || total != 39.33 {
+		t.Fatalf("剩余部分全额退款错误: %v %v", total, err)
+	}
+
+	// 只退差价 不退库存
+	lines, total, err = model.PlanRefund(items, nil, []model.RefundLineRequest{{Line: 1, Amount: 5}}, paid)
+	if err != nil || total != 5 || lines[0].Quantity != 0 {
+		t.Fatalf("退差价错误: %+v %v %v", lines, total, err)
+	}
+
+	// 超出可退数量、可退金额和不存在的商品行
+	if _, _, err := model.PlanRefund(items, previous, []model.RefundLineRequest{{Line: 0, Quantity: 3}}, paid); !errors.Is(err, model.ErrRefundQuantity) {
+		t.Fatalf("超出可退数量应该失败: %v", err)
+	}
+	if _, _, err := model.PlanRefund(items, nil, []model.RefundLineRequest{{Line: 1, Amount: 20.01}}, paid); !errors.Is(err, model.ErrRefundAmount) {
+		t.Fatalf("超出可退金额应该失败: %v", err)
+	}
+	if _, _, err := model.PlanRefund(items, nil, []model.RefundLineRequest{{Line: 2, Quantity: 1}}, paid); !errors.Is(err, model.ErrRefundLineInvalid) {
+		t.Fatalf("不存在的商品行应该失败: %v", err)
+	}
+	if _, _, err := model.PlanRefund(items, nil, []model.RefundLineRequest{{Line: 0, Quantity: 1}, {Line: 0, Quantity: 1}}, paid); !errors.Is(err, model.ErrRefundLineDuplicated) {
+		t.Fatalf("重复的商品行应该失败: %v", err)
+	}
+
+	// 已全额退款后不能再退
+	full := []model.Refund{{Amount: 49, Lines: []model.RefundLine{{Line: 0, Quantity: 3, Amount: 29}, {Line: 1, Quantity: 1, Amount: 20}}}}
+	if _, _, err := model.PlanRefund(items, full, nil, paid); !errors.Is(err, model.ErrRefundNothing) {
+		t.Fatalf("已全额退款后不能再退: %v", err)
+	}
+
+	// 退款总额不能超过实际支付金额
+	if _, _, err := model.PlanRefund(items, nil, nil, 40); !errors.Is(err, model.ErrRefundExceedsPaid) {
+		t.Fatalf("超过支付金额应该失败: %v", err)
+	}
+}
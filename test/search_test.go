@@ -0,0 +1,62 @@
+package test
+
+import (
+	"demo01/internal/util"
+	"reflect"
+	"testing"
+)
+
+// TestTokenize 测试中英文混合分词
+func TestTokenize(t *testing.T) {
+	tokens := util.Tokenize("iPhone 15 苹果手机")
+	expected := []string{"iphone", "15", "苹", "果", "手", "机", "苹果", "果手", "手机"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatalf("分词结果错误，期望: %v, 实际: %v", expected, tokens)
+	}
+}
+
+// TestSearchIndexRanking 测试检索结果按相关度排序 名称命中优先于描述命中
+func TestSearchIndexRanking(t *testing.T) {
+	index := util.NewSearchIndex(map[string]float64{"name": 3, "description": 1})
+	index.Upsert(1, map[string]string{"name": "iPhone 15", "description": "苹果最新手机"})
+	index.Upsert(2, map[string]string{"name": "华为手机", "description": "国产旗舰"})
+	index.Upsert(3, map[string]string{"name": "AirPods Pro", "description": "无线降噪耳机"})
+
+	hits := index.Search("手机")
+	if len(hits) != 2 || hits[0].ID != 2 || hits[1].ID != 1 {
+		t.Fatalf("检索排序错误: %+v", hits)
+	}
+
+	// 更新后旧内容不再命中
+	index.Upsert(2, map[string]string{"name": "华为平板", "description": "国产旗舰"})
+	if hits := index.Search("手机"); len(hits) != 1 || hits[0].ID != 1 {
+		t.Fatalf("更新后检索结果错误: %+v", hits)
+	}
+
+	index.Remove(1)
+	if hits := index.Search("手机"); len(hits) != 0 {
+		t.Fatalf("删除后不应再命中: %+v", hits)
+	}
+	if index.Len() != 2 {
+		t.Fatalf("索引文档数量错误: %d", index.Len())
+	}
+}
+
+// TestHighlight 测试命中高亮
+func TestHighlight(t *testing.T) {
+	cases := []struct{ text, query, expected string }{
+		{"苹果最新手机，搭载A17芯片", "手机", "苹果最新<em>手机</em>，搭载A17芯片"},
+		{"iPhone 15 Pro", "iphone pro", "<em>iPhone</em> 15 <em>Pro</em>"},
+		{"无线降噪耳机", "耳", "无线降噪<em>耳</em>机"},
+		{"专业级笔记本电脑", "手机", "专业级笔记本电脑"},
+		// 原文中的标签必须转义 不能被当作HTML执行
+		{"<script>alert(1)</script>手机", "手机", "&lt;script&gt;alert(1)&lt;/script&gt;<em>手机</em>"},
+		{"<b>script</b>", "script", "&lt;b&gt;<em>script</em>&lt;/b&gt;"},
+		{"<img src=x onerror=alert(1)>", "耳机", "&lt;img src=x onerror=alert(1)&gt;"},
+	}
+	for _, c := range cases {
+		if got := util.Highlight(c.text, c.query); got != c.expected {
+			t.Fatalf("高亮结果错误，期望: %s, 实际: %s", c.expected, got)
+		}
+	}
+}
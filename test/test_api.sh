@@ -1,10 +1,39 @@
 #!/bin/bash
 
 # API测试脚本
+# 下单需要登录 创建商品需要管理员账号 管理员密码与服务启动时的ADMIN_PASSWORD一致
 BASE_URL="http://localhost:8080"
+TEST_USERNAME="${TEST_USERNAME:-api_test_user}"
+TEST_PASSWORD="${TEST_PASSWORD:-Test123456}"
+ADMIN_USERNAME="${ADMIN_USERNAME:-admin}"
+ADMIN_PASSWORD="${ADMIN_PASSWORD:?请设置ADMIN_PASSWORD}"
 
 echo "🚀 开始API测试..."
 
+# 登录 输出登录响应
+login() {
+  curl -s -X POST "$BASE_URL/users/login" \
+    -H "Content-Type: application/json" \
+    -d "{\"username\": \"$1\", \"password\": \"$2\"}"
+}
+
+# 测试用户相关API
+echo "👤 测试用户API..."
+
+# 0. 注册并登录 用户已存在时注册失败不影响后续步骤
+echo "0. 注册并登录测试用户"
+curl -s -X POST "$BASE_URL/users/register" \
+  -H "Content-Type: application/json" \
+  -d "{\"username\": \"$TEST_USERNAME\", \"password\": \"$TEST_PASSWORD\"}" | jq '.message'
+USER_LOGIN=$(login "$TEST_USERNAME" "$TEST_PASSWORD")
+USER_TOKEN=$(echo "$USER_LOGIN" | jq -r '.data.access_token')
+USER_ID=$(echo "$USER_LOGIN" | jq -r '.data.user.id')
+ADMIN_TOKEN=$(login "$ADMIN_USERNAME" "$ADMIN_PASSWORD" | jq -r '.data.access_token')
+if [ "$USER_TOKEN" == "null" ] || [ "$ADMIN_TOKEN" == "null" ]; then
+    echo "登录失败"
+    exit 1
+fi
+
 # 测试商品相关API
 echo "📦 测试商品API..."
 
@@ -24,6 +53,7 @@ curl -s -X GET "$BASE_URL/products/1/stock" | jq '.'
 echo -e "\n4. 创建新商品"
 curl -s -X POST "$BASE_URL/products" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" \
   -d '{
     "name": "测试商品",
     "description": "这是一个测试商品",
@@ -36,37 +66,53 @@ curl -s -X POST "$BASE_URL/products" \
 # 测试订单相关API
 echo -e "\n📋 测试订单API..."
 
-# 5. 创建订单
-echo "5. 创建订单"
+# 5. 创建默认收货地址 下单时没有指定地址则使用默认地址
+echo "5. 创建默认收货地址"
+curl -s -X POST "$BASE_URL/users/$USER_ID/addresses" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $USER_TOKEN" \
+  -d '{
+    "receiver": "张三",
+    "phone": "13800138000",
+    "province": "广东省",
+    "city": "深圳市",
+    "district": "南山区",
+    "detail": "科技园1号",
+    "is_default": true
+  }' | jq '.'
+
+# 6. 创建订单 下单用户取自登录令牌 价格以商品当前价格为准
+echo -e "\n6. 创建订单"
 curl -s -X POST "$BASE_URL/orders" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $USER_TOKEN" \
   -d '{
-    "user_id": "user_001",
     "items": [
       {
         "product_id": 1,
-        "quantity": 2,
-        "price": 5999.00
+        "quantity": 2
       },
       {
         "product_id": 3,
-        "quantity": 1,
-        "price": 1999.00
+        "quantity": 1
       }
     ]
   }' | jq '.'
 
-# 6. 获取所有订单
-echo -e "\n6. 获取所有订单"
-curl -s -X GET "$BASE_URL/orders" | jq '.'
+# 7. 获取当前用户的订单
+echo -e "\n7. 获取当前用户的订单"
+curl -s -X GET "$BASE_URL/users/$USER_ID/orders" \
+  -H "Authorization: Bearer $USER_TOKEN" | jq '.'
 
-# 7. 获取特定订单
-echo -e "\n7. 获取最新订单"
-ORDER_ID=$(curl -s -X GET "$BASE_URL/orders" | jq -r '.data[0].id')
+# 8. 获取特定订单
+echo -e "\n8. 获取最新订单"
+ORDER_ID=$(curl -s -X GET "$BASE_URL/users/$USER_ID/orders" \
+  -H "Authorization: Bearer $USER_TOKEN" | jq -r '.data.orders[0].id')
 if [ "$ORDER_ID" != "null" ]; then
-    curl -s -X GET "$BASE_URL/orders/$ORDER_ID" | jq '.'
+    curl -s -X GET "$BASE_URL/orders/$ORDER_ID" \
+      -H "Authorization: Bearer $USER_TOKEN" | jq '.'
 else
     echo "没有找到订单"
 fi
 
-echo -e "\n✅ API测试完成！" 
\ No newline at end of file
+echo -e "\n✅ API测试完成！"
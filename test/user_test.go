@@ -0,0 +1,40 @@
+package test
+
+import (
+	"demo01/internal/model"
+	"strings"
+	"testing"
+)
+
+// TestValidateUserInput 测试注册时的用户名、密码和邮箱校验
+func TestValidateUserInput(t *testing.T) {
+	for _, username := range []string{"tom", "user_01", strings.Repeat("a", 32)} {
+		if err := model.ValidateUsername(username); err != nil {
+			t.Fatalf("用户名 %q 应该合法: %v", username, err)
+		}
+	}
+	for _, username := range []string{"", "ab", "张三", "a b", strings.Repeat("a", 33)} {
+		if model.ValidateUsername(username) == nil {
+			t.Fatalf("用户名 %q 应该不合法", username)
+		}
+	}
+
+	if model.ValidatePassword("12345") == nil || model.ValidatePassword(strings.Repeat("x", 73)) == nil {
+		t.Fatal("密码长度校验错误")
+	}
+	if err := model.ValidatePassword("123456"); err != nil {
+		t.Fatalf("6位密码应该合法: %v", err)
+	}
+
+	if err := model.ValidateEmail(""); err != nil {
+		t.Fatalf("空邮箱表示不填: %v", err)
+	}
+	if err := model.ValidateEmail("tom@example.com"); err != nil {
+		t.Fatalf("邮箱应该合法: %v", err)
+	}
+	for _, email := range []string{"tom", "Tom <tom@example.com>"} {
+		if model.ValidateEmail(email) == nil {
+			t.Fatalf("邮箱 %q 应该不合法", email)
+		}
+	}
+}
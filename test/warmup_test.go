@@ -0,0 +1,109 @@
+package test
+
+import (
+	"context"
+	"demo01/internal/model"
+	"demo01/internal/service"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestWarmUpProducts 测试商品预热的数量上限、热度顺序、去重、补齐和时间预算
+func TestWarmUpProducts(t *testing.T) {
+	catalog := map[int]model.Product{
+		1: {ID: 1, Name: "数据线", Status: model.ProductStatusActive},
+		2: {ID: 2, Name: "手机", Status: model.ProductStatusActive, SKUs: []model.SKU{{ID: 5, ProductID: 2, Price: 5999, Stock: 1}}},
+		3: {ID: 3, Name: "耳机", Status: model.ProductStatusActive},
+		4: {ID: 4, Name: "平板", Status: model.ProductStatusActive, SKUs: []model.SKU{{ID: 7, ProductID: 4, Price: 3999, Stock: 2}}},
+	}
+	// 模拟GetProductsByIDs 返回顺序与请求顺序无关
+	load := func(ctx context.Context, ids []int) ([]model.Product, error) {
+		var products []model.Product
+		for id := 4; id >= 1; id-- {
+			for _, want := range ids {
+				if want == id {
+					products = append(products, catalog[id])
+				}
+			}
+		}
+		return products, nil
+	}
+	newSource := func(stored *[]model.Product) service.ProductWarmUpSource {
+		return service.ProductWarmUpSource{
+			ActiveIDs: func(ctx context.Context, limit int) ([]int, error) { return []int{4, 2, 1, 3}[:limit], nil },
+			Load:      load,
+			Store: func(ctx context.Context, product *model.Product) error {
+				*stored = append(*stored, *product)
+				return nil
+			},
+		}
+	}
+	storedIDs := func(stored []model.Product) []int {
+		ids := make([]int, 0, len(stored))
+		for _, p := range stored {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	// 按热度顺序 去重后取limit个
+	var stored []model.Product
+	n, err := service.WarmUpProducts(context.Background(), []int{3, 1, 3, 2}, 2, newSource(&stored))
+	if err != nil || n != 2 || !reflect.DeepEqual(storedIDs(stored), []int{3, 1}) {
+		t.Fatalf("应该按热度顺序预热前2个商品: %d %v %v", n, storedIDs(stored), err)
+	}
+
+	// 查不到的热门商品跳过 不足时用上架商品补齐 补齐的商品同样带上SKU
+	stored = nil
+	n, err = service.WarmUpProducts(context.Background(), []int{9, 2}, 3, newSource(&stored))
+	if err != nil || n != 3 || !reflect.DeepEqual(storedIDs(stored), []int{2, 4, 1}) {
+		t.Fatalf("补齐结果错误: %d %v %v", n, storedIDs(stored), err)
+	}
+	for _, p := range stored {
+		if !reflect.DeepEqual(p, catalog[p.ID]) {
+			t.Fatalf("预热的商品应该与批量查询的结果一致: %+v", p)
+		}
+	}
+
+	// limit为0时不预热
+	stored = nil
+	if n, err := service.WarmUpProducts(context.Background(), []int{1}, 0, newSource(&stored)); n != 0 || err != nil || len(stored) != 0 {
+		t.Fatalf("limit为0时不应该预热: %d %v", n, err)
+	}
+
+	// 超出时间预算时停止 已预热的部分保留
+	stored = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	source := newSource(&stored)
+	store := source.Store
+	source.Store = func(ctx context.Context, product *model.Product) error {
+		if err := store(ctx, product); err != nil {
+			return err
+		}
+		if len(stored) == 2 {
+			cancel()
+		}
+		return nil
+	}
+	n, err = service.WarmUpProducts(ctx, []int{1, 2, 3, 4}, 4, source)
+	if !errors.Is(err, context.Canceled) || n != 2 || len(stored) != 2 {
+		t.Fatalf("超出时间预算时应该停止: %d %v", n, err)
+	}
+}
+
+// TestWarmUpEach 测试逐个写入缓存时遇到错误停止
+func TestWarmUpEach(t *testing.T) {
+	storeErr := errors.New("redis down")
+	var stored []int
+	n, err := service.WarmUpEach(context.Background(), []int{1, 2, 3}, func(ctx context.Context, item *int) error {
+		if *item == 2 {
+			return storeErr
+		}
+		stored = append(stored, *item)
+		return nil
+	})
+	if !errors.Is(err, storeErr) || n != 1 || !reflect.DeepEqual(stored, []int{1}) {
+		t.Fatalf("写入失败时应该停止: %d %v %v", n, stored, err)
+	}
+}
@@ -10,21 +10,90 @@ import (
 	"time"
 )
 
+const baseURL = "http://localhost:8080"
+
+// OrderRequest 下单请求 下单用户取自登录令牌 价格以商品当前价格为准
 type OrderRequest struct {
-	UserID string      `json:"user_id"`
-	Items  []OrderItem `json:"items"`
+	Items []OrderItem `json:"items"`
 }
 
 type OrderItem struct {
-	ProductID int     `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
 }
 
 type OrderResponse struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// postJSON 发送POST请求并解析统一响应 token非空时携带登录令牌
+func postJSON(url, token string, payload interface{}) (*OrderResponse, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result OrderResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// prepareUser 注册并登录测试用户 创建默认收货地址 返回访问令牌
+// 用户已存在时注册失败 直接登录即可
+func prepareUser(username, password string) (string, error) {
+	if _, err := postJSON(baseURL+"/users/register", "", map[string]string{"username": username, "password": password}); err != nil {
+		return "", err
+	}
+	resp, err := postJSON(baseURL+"/users/login", "", map[string]string{"username": username, "password": password})
+	if err != nil {
+		return "", err
+	}
+	if resp.Code != 200 {
+		return "", fmt.Errorf("登录失败: %s", resp.Message)
+	}
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+		User        struct {
+			ID int `json:"id"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(resp.Data, &tokens); err != nil {
+		return "", err
+	}
+
+	address := map[string]interface{}{
+		"receiver": username, "phone": "13800138000", "province": "广东省",
+		"city": "深圳市", "detail": "科技园1号", "is_default": true,
+	}
+	resp, err = postJSON(fmt.Sprintf("%s/users/%d/addresses", baseURL, tokens.User.ID), tokens.AccessToken, address)
+	if err != nil {
+		return "", err
+	}
+	if resp.Code != 200 {
+		return "", fmt.Errorf("创建收货地址失败: %s", resp.Message)
+	}
+	return tokens.AccessToken, nil
 }
 
 func main() {
@@ -40,6 +109,17 @@ func main() {
 	fmt.Printf("每单数量: %d\n", quantityPerOrder)
 	fmt.Printf("总需求量: %d\n", concurrentUsers*quantityPerOrder)
 
+	// 下单需要登录 先为每个并发用户准备好登录令牌和收货地址 不计入测试耗时
+	tokens := make([]string, concurrentUsers)
+	for i := range tokens {
+		token, err := prepareUser(fmt.Sprintf("concurrent_user_%d", i), "Test123456")
+		if err != nil {
+			fmt.Printf("用户 %d 准备失败: %v\n", i, err)
+			return
+		}
+		tokens[i] = token
+	}
+
 	var wg sync.WaitGroup
 	successCount := 0
 	failCount := 0
@@ -55,25 +135,16 @@ func main() {
 
 			// 构造订单请求
 			orderReq := OrderRequest{
-				UserID: fmt.Sprintf("concurrent_user_%d", userID),
 				Items: []OrderItem{
 					{
 						ProductID: productID,
 						Quantity:  quantityPerOrder,
-						Price:     5999.0,
 					},
 				},
 			}
 
-			// 序列化请求
-			jsonData, err := json.Marshal(orderReq)
-			if err != nil {
-				fmt.Printf("用户 %d 序列化请求失败: %v\n", userID, err)
-				return
-			}
-
-			// 发送请求
-			resp, err := http.Post("http://localhost:8080/orders", "application/json", bytes.NewBuffer(jsonData))
+			// 携带登录令牌发送请求
+			orderResp, err := postJSON(baseURL+"/orders", tokens[userID], orderReq)
 			if err != nil {
 				fmt.Printf("用户 %d 请求失败: %v\n", userID, err)
 				mu.Lock()
@@ -81,27 +152,6 @@ func main() {
 				mu.Unlock()
 				return
 			}
-			defer resp.Body.Close()
-
-			// 读取响应
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				fmt.Printf("用户 %d 读取响应失败: %v\n", userID, err)
-				mu.Lock()
-				failCount++
-				mu.Unlock()
-				return
-			}
-
-			// 解析响应
-			var orderResp OrderResponse
-			if err := json.Unmarshal(body, &orderResp); err != nil {
-				fmt.Printf("用户 %d 解析响应失败: %v\n", userID, err)
-				mu.Lock()
-				failCount++
-				mu.Unlock()
-				return
-			}
 
 			// 统计结果
 			mu.Lock()